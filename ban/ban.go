@@ -0,0 +1,462 @@
+// Package ban 不当行为节点的惩罚和禁闭。
+// 节点的不当行为（格式错误、签名无效、跳数违规、流量超限、虚假回复）累计惩罚分，
+// 惩罚分随时间衰减，达到阈值时该节点的IP被禁闭，期限为 config.BanExpired。
+// IPv6节点通常拥有整个 /64 网段，其惩罚分和禁闭按 /64 聚合，IPv4按单个IP。
+// 禁闭可针对单个IP或整个网段（CIDR），用户配置的禁闭（bans.json）遵循同样的时效期。
+// 运行时产生的禁闭记录保存在缓存目录（banned.json），有变更时定期保存，重启后继续生效。
+package ban
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/base"
+	"github.com/cxio/depots/config"
+)
+
+// Offense 不当行为类别。
+type Offense int
+
+// 不当行为类别值
+const (
+	Malformed Offense = iota // 格式错误的数据包
+	Signature                // 签名验证失败
+	Hops                     // 跳数违规
+	Flood                    // 流量超限
+	Fake                     // 虚假回复
+)
+
+// 各类别的惩罚分
+var penalties = [...]float64{
+	Malformed: config.PenaltyMalformed,
+	Signature: config.PenaltySignature,
+	Hops:      config.PenaltyHops,
+	Flood:     config.PenaltyFlood,
+	Fake:      config.PenaltyFake,
+}
+
+func (o Offense) String() string {
+	switch o {
+	case Malformed:
+		return "malformed"
+	case Signature:
+		return "signature"
+	case Hops:
+		return "hops"
+	case Flood:
+		return "flood"
+	case Fake:
+		return "fake"
+	}
+	return fmt.Sprintf("offense(%d)", int(o))
+}
+
+// Penalty 返回该类别的惩罚分。
+// 未知类别返回0。
+func (o Offense) Penalty() float64 {
+	if o < 0 || int(o) >= len(penalties) {
+		return 0
+	}
+	return penalties[o]
+}
+
+// 惩罚分
+type score struct {
+	value float64   // 最近一次更新时的分值
+	at    time.Time // 最近一次更新时间
+}
+
+// 当前分值（已衰减）。
+func (s *score) now(now time.Time) float64 {
+	return s.value * math.Exp2(-float64(now.Sub(s.at))/float64(config.BanHalfLife))
+}
+
+// 禁闭条目
+type entry struct {
+	since  time.Time
+	reason string
+	manual bool // 用户配置，不写入运行时记录
+}
+
+// Manager 禁闭管理器。
+// 并发安全，可被各连接的处理过程共享。
+// 单个IP和IPv6的 /64 网段直接索引，其它网段逐一比对。
+type Manager struct {
+	mu     sync.Mutex
+	scores map[netip.Prefix]*score
+	bans   map[netip.Prefix]*entry // 可直接索引的条目
+	nets   map[netip.Prefix]*entry // 其它网段
+	notify func(netip.Prefix)
+	dirty  bool // 运行时记录有变更，待保存
+}
+
+// New 创建一个空的禁闭管理器。
+func New() *Manager {
+	return &Manager{
+		scores: make(map[netip.Prefix]*score),
+		bans:   make(map[netip.Prefix]*entry),
+		nets:   make(map[netip.Prefix]*entry),
+	}
+}
+
+// Load 从用户配置和运行时记录创建禁闭管理器。
+// - 用户配置（bans.json）的条目以载入时间起算时效期，格式错误的条目被忽略。
+// - 运行时记录（banned.json）中已过期的条目被忽略。
+func Load() (*Manager, error) {
+	manual, err := config.Bans()
+	if err != nil {
+		return nil, err
+	}
+	list, err := config.Banned()
+	if err != nil {
+		return nil, err
+	}
+	m := New()
+	now := time.Now()
+
+	for k, t := range manual {
+		p, ok := Parse(k)
+		if !ok {
+			logf("invalid ban entry %q", k)
+			continue
+		}
+		m.table(p)[p] = &entry{since: t, reason: "config", manual: true}
+	}
+	for _, b := range list {
+		if !b.Net.IsValid() || now.Sub(b.Since) >= config.BanExpired {
+			continue
+		}
+		p := prefix(b.Net.Addr(), b.Net.Bits())
+		t := m.table(p)
+
+		if _, ok := t[p]; !ok {
+			t[p] = &entry{since: b.Since, reason: b.Reason}
+		}
+	}
+	return m, nil
+}
+
+// Parse 解析禁闭条目。
+// 条目可为网段（CIDR）、IP或IP:Port，单个IP视为全长前缀，端口被忽略。
+// @s 条目文本
+func Parse(s string) (netip.Prefix, bool) {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return prefix(p.Addr(), p.Bits()), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return host(ap.Addr()), true
+	}
+	if ip, err := netip.ParseAddr(s); err == nil {
+		return host(ip), true
+	}
+	return netip.Prefix{}, false
+}
+
+// Group 返回节点IP的聚合网段。
+// IPv4为单个IP，IPv6为其所在的 /64 网段（通常整个分配给一个节点）。
+// @ip 节点IP
+func Group(ip netip.Addr) netip.Prefix {
+	ip = ip.Unmap()
+
+	if ip.Is4() {
+		return host(ip)
+	}
+	return prefix(ip, ipv6Group)
+}
+
+// Notify 设置禁闭通知处理器。
+// 新的禁闭生效时调用，可用于断开该网段内的已有连接，应当尽快返回。
+// @fn 处理函数
+func (m *Manager) Notify(fn func(netip.Prefix)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify = fn
+}
+
+// Penalize 记录节点的一次不当行为。
+// 惩罚分累计达到阈值（config.BanThreshold）时禁闭该IP，IPv6为其所在的 /64 网段。
+// 记分的节点数达到上限（config.LimitSources）时，淘汰当前分值最低者。
+// @ip 节点IP
+// @o  不当行为类别
+// @return 是否因此被禁闭
+func (m *Manager) Penalize(ip netip.Addr, o Offense) bool {
+	g := Group(ip)
+	now := time.Now()
+
+	m.mu.Lock()
+	s := m.scores[g]
+	if s == nil {
+		if len(m.scores) >= config.LimitSources {
+			m.evict(now)
+		}
+		s = &score{}
+		m.scores[g] = s
+	}
+	s.value = s.now(now) + o.Penalty()
+	s.at = now
+
+	// 取整比较，短时间内的细微衰减不计
+	if math.Round(s.value) < config.BanThreshold {
+		m.mu.Unlock()
+		return false
+	}
+	delete(m.scores, g)
+	fn := m.ban(g, now, o.String())
+	m.mu.Unlock()
+
+	if fn != nil {
+		fn(g)
+	}
+	return true
+}
+
+// Score 返回节点当前的惩罚分（已衰减）。
+// IPv6为其所在 /64 网段的聚合分值。
+// @ip 节点IP
+func (m *Manager) Score(ip netip.Addr) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s := m.scores[Group(ip)]; s != nil {
+		return s.now(time.Now())
+	}
+	return 0
+}
+
+// Ban 禁闭一个网段。
+// 已禁闭的网段重新起算时效期。
+// @p      网段，单个IP用全长前缀
+// @reason 禁闭原因
+func (m *Manager) Ban(p netip.Prefix, reason string) {
+	p = prefix(p.Addr(), p.Bits())
+
+	m.mu.Lock()
+	fn := m.ban(p, time.Now(), reason)
+	m.mu.Unlock()
+
+	if fn != nil {
+		fn(p)
+	}
+}
+
+// Unban 解除一个网段的禁闭。
+// 仅移除与之完全相同的条目，不影响包含它的更大网段。
+// @p 网段
+func (m *Manager) Unban(p netip.Prefix) {
+	p = prefix(p.Addr(), p.Bits())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.table(p)
+
+	if e := t[p]; e != nil {
+		delete(t, p)
+		m.dirty = m.dirty || !e.manual
+	}
+}
+
+// Banned 检查IP是否处于禁闭中。
+// 先查单个IP和IPv6的 /64 网段的索引，再比对其它网段。
+// @ip 节点IP
+func (m *Manager) Banned(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []netip.Prefix{host(ip)}
+	if ip.Is6() {
+		keys = append(keys, Group(ip))
+	}
+	for _, p := range keys {
+		if e := m.bans[p]; e != nil {
+			if now.Sub(e.since) < config.BanExpired {
+				return true
+			}
+			delete(m.bans, p)
+		}
+	}
+	for p, e := range m.nets {
+		if now.Sub(e.since) >= config.BanExpired {
+			delete(m.nets, p)
+			continue
+		}
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// List 返回当前有效的禁闭网段。
+func (m *Manager) List() []netip.Prefix {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(time.Now())
+	list := make([]netip.Prefix, 0, len(m.bans)+len(m.nets))
+
+	for p := range m.bans {
+		list = append(list, p)
+	}
+	for p := range m.nets {
+		list = append(list, p)
+	}
+	return list
+}
+
+// Save 保存运行时禁闭记录到 banned.json。
+// 用户配置的条目不写入，它们在下次启动时从 bans.json 重新载入。
+func (m *Manager) Save() error {
+	m.mu.Lock()
+	m.prune(time.Now())
+	list := make([]*config.Ban, 0, len(m.bans)+len(m.nets))
+
+	for _, t := range []map[netip.Prefix]*entry{m.bans, m.nets} {
+		for p, e := range t {
+			if !e.manual {
+				list = append(list, &config.Ban{Net: p, Since: e.since, Reason: e.reason})
+			}
+		}
+	}
+	m.dirty = false
+	m.mu.Unlock()
+
+	if err := config.SaveBanned(list); err != nil {
+		m.mu.Lock()
+		m.dirty = true
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run 定期清理过期的禁闭和已衰减的惩罚分。
+// 运行时禁闭记录有变更时，每 config.BanSave 保存一次（Save），
+// 以免异常退出时丢失。
+// 阻塞直到上下文退出，退出时保存运行时禁闭记录。
+// @ctx 执行上下文
+func (m *Manager) Run(ctx context.Context) error {
+	tick := time.NewTicker(config.BanHalfLife)
+	defer tick.Stop()
+
+	save := time.NewTicker(config.BanSave)
+	defer save.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return m.Save()
+		case <-tick.C:
+			m.mu.Lock()
+			m.prune(time.Now())
+			m.mu.Unlock()
+		case <-save.C:
+			if !m.changed() {
+				continue
+			}
+			if err := m.Save(); err != nil {
+				logf("save banned: %v", err)
+			}
+		}
+	}
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 添加禁闭条目并记录。
+// 返回需通知的处理器（锁外调用）。
+// 调用者需持有锁。
+func (m *Manager) ban(p netip.Prefix, now time.Time, reason string) func(netip.Prefix) {
+	m.table(p)[p] = &entry{since: now, reason: reason}
+	m.dirty = true
+	logf("banned %s for %s: %s", p, config.BanExpired, reason)
+
+	return m.notify
+}
+
+// 清理过期的禁闭和衰减殆尽的惩罚分。
+// 调用者需持有锁。
+func (m *Manager) prune(now time.Time) {
+	for _, t := range []map[netip.Prefix]*entry{m.bans, m.nets} {
+		for p, e := range t {
+			if now.Sub(e.since) >= config.BanExpired {
+				delete(t, p)
+			}
+		}
+	}
+	for ip, s := range m.scores {
+		if s.now(now) < 1 {
+			delete(m.scores, ip)
+		}
+	}
+}
+
+// 淘汰当前分值最低的惩罚分记录。
+// 分值高者更接近禁闭，予以保留，以免以大量新来源冲掉它们的累计。
+// 调用者需持有锁。
+func (m *Manager) evict(now time.Time) {
+	var low netip.Prefix
+	min := math.Inf(1)
+
+	for g, s := range m.scores {
+		if v := s.now(now); v < min {
+			low, min = g, v
+		}
+	}
+	delete(m.scores, low)
+}
+
+// 运行时禁闭记录是否有未保存的变更。
+func (m *Manager) changed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dirty
+}
+
+// 条目所属的表。
+// 单个IP和IPv6的 /64 网段可直接索引，其它网段需逐一比对。
+// 调用者需持有锁。
+func (m *Manager) table(p netip.Prefix) map[netip.Prefix]*entry {
+	if p.IsSingleIP() || (p.Addr().Is6() && p.Bits() == ipv6Group) {
+		return m.bans
+	}
+	return m.nets
+}
+
+// IPv6节点聚合的前缀长度
+const ipv6Group = 64
+
+// 单个IP的全长前缀。
+func host(ip netip.Addr) netip.Prefix {
+	ip = ip.Unmap()
+	return netip.PrefixFrom(ip, ip.BitLen())
+}
+
+// 规范化的网段（IPv4映射地址还原，主机位清零）。
+func prefix(ip netip.Addr, bits int) netip.Prefix {
+	if ip.Is4In6() {
+		bits -= 96
+		ip = ip.Unmap()
+	}
+	p, err := ip.Prefix(max(bits, 0))
+	if err != nil {
+		return host(ip)
+	}
+	return p
+}
+
+// 记录节点历史。
+func logf(format string, v ...any) {
+	if base.LogPeer != nil {
+		base.LogPeer.Printf(format, v...)
+	}
+}
@@ -0,0 +1,157 @@
+package ban
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+)
+
+// 惩罚至禁闭所需的次数。
+func strikes(o Offense) int {
+	return int(config.BanThreshold/o.Penalty()) + 1
+}
+
+func TestPenalize(t *testing.T) {
+	m := New()
+	ip := netip.MustParseAddr("10.0.0.1")
+
+	var notified netip.Prefix
+	m.Notify(func(p netip.Prefix) { notified = p })
+
+	for i := 1; i < strikes(Fake); i++ {
+		if m.Penalize(ip, Fake) {
+			t.Fatalf("banned after %d offenses", i)
+		}
+	}
+	if m.Score(ip) < config.PenaltyFake {
+		t.Errorf("score: %f", m.Score(ip))
+	}
+	if !m.Penalize(netip.MustParseAddr("::ffff:10.0.0.1"), Fake) {
+		t.Fatal("mapped address scored apart")
+	}
+	if !m.Banned(ip) || m.Banned(netip.MustParseAddr("10.0.0.2")) {
+		t.Error("ban not limited to the host")
+	}
+	if notified != netip.MustParsePrefix("10.0.0.1/32") {
+		t.Errorf("notified: %s", notified)
+	}
+	if m.Score(ip) != 0 {
+		t.Error("score kept after ban")
+	}
+}
+
+func TestPenalizeIPv6(t *testing.T) {
+	m := New()
+
+	// 同一 /64 网段内轮换地址，惩罚分聚合
+	for i := range strikes(Fake) {
+		ip := netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 15: byte(i + 1)})
+		m.Penalize(ip, Fake)
+	}
+	if !m.Banned(netip.MustParseAddr("2001:db8::ffff")) {
+		t.Error("/64 not banned")
+	}
+	if m.Banned(netip.MustParseAddr("2001:db8:0:1::1")) {
+		t.Error("neighbouring /64 banned")
+	}
+	if _, ok := m.bans[netip.MustParsePrefix("2001:db8::/64")]; !ok {
+		t.Error("/64 ban not indexed")
+	}
+}
+
+func TestPenalizeCap(t *testing.T) {
+	m := New()
+	high := netip.MustParseAddr("20.0.0.1")
+
+	m.Penalize(high, Fake)
+	m.Penalize(high, Fake)
+
+	// 大量来源各犯一次轻微过错
+	for i := range config.LimitSources + 10 {
+		m.Penalize(netip.AddrFrom4([4]byte{30, byte(i >> 16), byte(i >> 8), byte(i)}), Malformed)
+
+		if len(m.scores) > config.LimitSources {
+			t.Fatalf("scores: %d", len(m.scores))
+		}
+	}
+	// 分值高者被保留
+	if m.Score(high) < 2*config.PenaltyFake-1 {
+		t.Errorf("high score evicted: %f", m.Score(high))
+	}
+}
+
+func TestBanned(t *testing.T) {
+	m := New()
+	m.Ban(netip.MustParsePrefix("192.168.0.0/16"), "test")
+	m.Ban(netip.MustParsePrefix("10.0.0.1/32"), "test")
+	m.Ban(netip.MustParsePrefix("::ffff:172.16.0.0/108"), "test")
+
+	if len(m.bans) != 1 || len(m.nets) != 2 {
+		t.Fatalf("index: %d hosts, %d nets", len(m.bans), len(m.nets))
+	}
+	for _, s := range []string{"192.168.3.4", "10.0.0.1", "172.16.0.9", "::ffff:10.0.0.1"} {
+		if !m.Banned(netip.MustParseAddr(s)) {
+			t.Errorf("%s not banned", s)
+		}
+	}
+	if m.Banned(netip.MustParseAddr("10.0.0.2")) {
+		t.Error("10.0.0.2 banned")
+	}
+	// 过期的条目被移除
+	m.mu.Lock()
+	m.bans[netip.MustParsePrefix("10.0.0.1/32")].since = time.Now().Add(-config.BanExpired)
+	m.nets[netip.MustParsePrefix("192.168.0.0/16")].since = time.Now().Add(-config.BanExpired)
+	m.mu.Unlock()
+
+	if m.Banned(netip.MustParseAddr("10.0.0.1")) || m.Banned(netip.MustParseAddr("192.168.3.4")) {
+		t.Error("expired ban in effect")
+	}
+	if len(m.List()) != 1 {
+		t.Errorf("list: %v", m.List())
+	}
+	m.Unban(netip.MustParsePrefix("172.16.0.0/12"))
+
+	if m.Banned(netip.MustParseAddr("172.16.0.9")) {
+		t.Error("unbanned net in effect")
+	}
+}
+
+func TestSave(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	m, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.changed() {
+		t.Error("fresh manager changed")
+	}
+	ip := netip.MustParseAddr("2001:db8::1")
+
+	for range strikes(Signature) {
+		m.Penalize(ip, Signature)
+	}
+	if !m.changed() {
+		t.Fatal("ban not marked for saving")
+	}
+	if err = m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if m.changed() {
+		t.Error("changed after save")
+	}
+	m, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Banned(netip.MustParseAddr("2001:db8::2")) {
+		t.Error("saved ban not restored")
+	}
+	m.Unban(netip.MustParsePrefix("2001:db8::/64"))
+
+	if !m.changed() || m.Banned(ip) {
+		t.Error("unban not marked for saving")
+	}
+}
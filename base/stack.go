@@ -0,0 +1,61 @@
+package base
+
+import (
+	"net"
+	"net/netip"
+)
+
+// Stack 本机可用的IP协议栈。
+// 用于启动组网时排除无法连接的地址族，使仅有IPv6的节点也可完成组网。
+type Stack struct {
+	V4 bool // 有非回环的IPv4地址（含内网地址，可经NAT连接外网）
+	V6 bool // 有全局单播的IPv6地址
+}
+
+// LocalStack 探测本机可用的IP协议栈。
+// 无法获取网络接口时视为双栈，不作限制。
+func LocalStack() Stack {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return Stack{V4: true, V6: true}
+	}
+	var s Stack
+
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(n.IP)
+		if !ok {
+			continue
+		}
+		ip = ip.Unmap()
+
+		switch {
+		case ip.Is4() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast():
+			s.V4 = true
+		case ip.Is6() && ip.IsGlobalUnicast():
+			s.V6 = true
+		}
+	}
+	if !s.V4 && !s.V6 {
+		return Stack{V4: true, V6: true}
+	}
+	return s
+}
+
+// Reach 是否可以连接目标IP。
+// 回环地址总是可达。
+// @ip 目标IP
+func (s Stack) Reach(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if ip.IsLoopback() {
+		return true
+	}
+	if ip.Is4() {
+		return s.V4
+	}
+	return s.V6
+}
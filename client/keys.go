@@ -0,0 +1,309 @@
+// Package client 客户端辅助。
+// 供发出询问的应用使用，管理询问的密钥和回复的解密。
+package client
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// Mode 询问密钥模式
+type Mode int
+
+// 两种密钥模式
+const (
+	KeyPerQuest   Mode = iota // 每个询问一个新密钥
+	KeyPerSession             // 会话内共用一个密钥，定期轮换
+)
+
+var (
+	// ErrTooMany 未决询问过多
+	ErrTooMany = errors.New("too many outstanding quests")
+
+	// ErrUnknown 询问未知或已过期
+	ErrUnknown = errors.New("unknown or expired quest")
+)
+
+// 未决询问
+type quest struct {
+	ver     int         // 询问包版本
+	dh      *msg.DHPack // 询问的密钥交换包
+	created time.Time   // 创建时间
+}
+
+// Keys 询问密钥管理器。
+// 为每个询问分配ID和密钥交换包，收到回复时按ID匹配并解密。
+// 询问完成或超时后，不再被引用的密钥会被擦除。
+// 解密和签名在锁内进行，以免密钥在使用中被并发的结束或轮换擦除。
+type Keys struct {
+	mu      sync.Mutex
+	tag     msg.DHTag
+	mode    Mode
+	limit   int
+	session *msg.DHPack // 当前会话密钥
+	rotated time.Time   // 会话密钥创建时间
+	quests  map[uint64]*quest
+}
+
+// NewKeys 创建询问密钥管理器。
+// @tag  密钥交换算法
+// @mode 密钥模式
+// @limit 未决询问上限，零值取默认值（config.QuestMax）
+func NewKeys(tag msg.DHTag, mode Mode, limit int) (*Keys, error) {
+	if msg.NewDHPack(tag, nil) == nil {
+		return nil, packet.ErrAlgor
+	}
+	if limit <= 0 {
+		limit = config.QuestMax
+	}
+	return &Keys{
+		tag:    tag,
+		mode:   mode,
+		limit:  limit,
+		quests: make(map[uint64]*quest),
+	}, nil
+}
+
+// New 创建一个询问。
+// 返回的ID和密钥交换包用于编码询问包（packet.EncodeQuest|EncodeBatch）。
+// 询问包的版本被记录，低于其加密模式的回复会被拒绝（防降级）。
+// 未决询问达到上限时返回 ErrTooMany，外部可先清理（Clean）或等待。
+// @ver 询问包版本
+// @return1 询问ID
+// @return2 密钥交换包
+func (k *Keys) New(ver int) (uint64, *msg.DHPack, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.quests) >= k.limit {
+		k.clean(time.Now())
+
+		if len(k.quests) >= k.limit {
+			return 0, nil, ErrTooMany
+		}
+	}
+	dh, err := k.pack()
+	if err != nil {
+		return 0, nil, err
+	}
+	id, err := k.newID()
+	if err != nil {
+		return 0, nil, err
+	}
+	k.quests[id] = &quest{ver: ver, dh: dh, created: time.Now()}
+
+	return id, dh, nil
+}
+
+// Chunks 登记切分批量的询问ID。
+// 各批共用原询问的版本、密钥交换包和创建时间，回复按各自的询问ID匹配。
+// 切分批量的ID由 relay.ChunkBatch 给出，首批即原询问ID，无需登记。
+// @id  原询问ID
+// @ids 其余各批的询问ID
+func (k *Keys) Chunks(id uint64, ids ...uint64) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q := k.quests[id]
+	if q == nil {
+		return ErrUnknown
+	}
+	if len(k.quests)+len(ids) > k.limit {
+		return ErrTooMany
+	}
+	for _, x := range ids {
+		if _, ok := k.quests[x]; !ok {
+			k.quests[x] = &quest{ver: q.ver, dh: q.dh, created: q.created}
+		}
+	}
+	return nil
+}
+
+// Lookup 获取询问的密钥交换包。
+// 可用于撤销询问（packet.EncodeCancel），询问结束（Done）后不应再使用。
+// 未知或已过期时返回nil。
+// @id 询问ID
+func (k *Keys) Lookup(id uint64) *msg.DHPack {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if q := k.quest(id); q != nil {
+		return q.dh
+	}
+	return nil
+}
+
+// Reply 解码一个回复包。
+// 按询问ID匹配密钥，解密连系信息。询问保持未决，可继续接收其它回复。
+// 回复版本低于询问的加密模式时返回 packet.ErrDowngrade。
+// @data 回复包数据
+// @return1 基础信息
+// @return2 连系信息
+func (k *Keys) Reply(data []byte) (*packet.Base, *packet.AidInfo, error) {
+	id, err := packet.ReplyID(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q := k.quest(id)
+	if q == nil {
+		return nil, nil, ErrUnknown
+	}
+	return packet.DecodeReply(data, q.ver, q.dh)
+}
+
+// Found 解密一个批量回复条目。
+// 批量回复包由 packet.DecodeBatchReply 解码，其基础信息提供版本和询问ID。
+// 回复版本低于询问的加密模式时返回 packet.ErrDowngrade。
+// @b 批量回复的基础信息
+// @f 回复条目
+// @return1 基础信息
+// @return2 连系信息
+func (k *Keys) Found(b *packet.Base, f *packet.Found) (*packet.Base, *packet.AidInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q := k.quest(b.ID)
+	if q == nil {
+		return nil, nil, ErrUnknown
+	}
+	return packet.OpenFound(f, b.Ver, q.ver, b.ID, q.dh)
+}
+
+// Report 编码一个核实报告包。
+// 询问者向数据源获取数据后，将结果报告给本地驿站，驿站据此评定转回回复的节点的信誉。
+// 报告以询问的密钥签名，应当在结束询问（Done）之前调用，密钥封装算法不支持报告。
+// @id 询问ID
+// @ok 数据源是否提供了所需数据
+func (k *Keys) Report(id uint64, ok bool) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	q := k.quest(id)
+	if q == nil {
+		return nil, ErrUnknown
+	}
+	return packet.EncodeReport(&packet.Base{Ver: q.ver, ID: id}, ok, q.dh)
+}
+
+// Done 结束一个询问。
+// 移除询问，其密钥不再被引用时擦除。
+// @id 询问ID
+func (k *Keys) Done(id uint64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if q := k.quests[id]; q != nil {
+		delete(k.quests, id)
+		k.erase(q.dh)
+	}
+}
+
+// Clean 清理超时的询问。
+// 应当由外部定时调用。
+func (k *Keys) Clean() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.clean(time.Now())
+}
+
+// Len 返回未决询问数。
+func (k *Keys) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.quests)
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 获取未决询问。
+// 未知或已过期时返回nil。
+// 调用者需持有锁。
+func (k *Keys) quest(id uint64) *quest {
+	q := k.quests[id]
+	if q == nil || time.Since(q.created) >= config.QuestExpired {
+		return nil
+	}
+	return q
+}
+
+// 获取询问使用的密钥交换包。
+// 会话模式下复用当前会话密钥，到期轮换。
+// 调用者需持有锁。
+func (k *Keys) pack() (*msg.DHPack, error) {
+	if k.mode == KeyPerSession &&
+		k.session != nil &&
+		time.Since(k.rotated) < config.QuestKeyRotate {
+		return k.session, nil
+	}
+	key, err := msg.GenerateKey(k.tag)
+	if err != nil {
+		return nil, err
+	}
+	dh := msg.NewDHPack(k.tag, key)
+
+	if k.mode == KeyPerSession {
+		old := k.session
+		k.session, k.rotated = dh, time.Now()
+
+		if old != nil {
+			k.erase(old)
+		}
+	}
+	return dh, nil
+}
+
+// 创建一个未被占用的随机询问ID。
+// 调用者需持有锁。
+func (k *Keys) newID() (uint64, error) {
+	var buf [8]byte
+
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		id := binary.BigEndian.Uint64(buf[:])
+
+		if _, ok := k.quests[id]; !ok {
+			return id, nil
+		}
+	}
+}
+
+// 清理超时的询问。
+// 调用者需持有锁。
+func (k *Keys) clean(now time.Time) {
+	for id, q := range k.quests {
+		if now.Sub(q.created) >= config.QuestExpired {
+			delete(k.quests, id)
+			k.erase(q.dh)
+		}
+	}
+}
+
+// 擦除不再被引用的密钥。
+// 当前会话密钥和仍被未决询问使用的密钥保留。
+// 调用者需持有锁。
+func (k *Keys) erase(dh *msg.DHPack) {
+	if dh == k.session {
+		return
+	}
+	for _, q := range k.quests {
+		if q.dh == dh {
+			return
+		}
+	}
+	dh.Erase()
+}
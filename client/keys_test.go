@@ -0,0 +1,327 @@
+package client
+
+import (
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 数据源对询问的回复。
+// @ver 回复包版本
+func testReply(t *testing.T, ver int, id uint64, quest *msg.DHPack) []byte {
+	t.Helper()
+
+	key, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &packet.AidInfo{Network: "udp", IP: netip.MustParseAddr("10.0.0.1"), Port: 7790}
+	data, err := packet.EncodeReply(packet.NewBase(ver, id, 1, packet.NAT_LEVEL_NULL), a, msg.NewDHPack(msg.DH_X25519, key), quest.PublicBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReplyDowngrade(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerQuest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, dh, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, a, err := k.Reply(testReply(t, packet.VersionBind, id, dh)); err != nil || a.Port != 7790 {
+		t.Fatalf("bound reply: %v", err)
+	}
+	if _, _, err := k.Reply(testReply(t, packet.VersionStamp, id, dh)); !errors.Is(err, packet.ErrDowngrade) {
+		t.Errorf("downgraded reply: %v", err)
+	}
+	// 旧版本的询问仍接受旧模式的回复
+	old, dh, err := k.New(packet.VersionStamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := k.Reply(testReply(t, packet.VersionStamp, old, dh)); err != nil {
+		t.Errorf("legacy reply: %v", err)
+	}
+}
+
+func TestFoundDowngrade(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerSession, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, dh, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := msg.NewDHPack(msg.DH_X25519, key)
+	a := &packet.AidInfo{Network: "udp", IP: netip.MustParseAddr("10.0.0.1"), Port: 7790}
+
+	for _, ver := range []int{packet.VersionBind, packet.VersionStamp} {
+		b := packet.NewBase(ver, id, 1, packet.NAT_LEVEL_NULL)
+
+		f, err := packet.EncodeFound(b, a, src, dh.PublicBytes(), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = k.Found(b, f)
+
+		if ver == packet.VersionBind && err != nil {
+			t.Errorf("bound found: %v", err)
+		}
+		if ver < packet.VersionBind && !errors.Is(err, packet.ErrDowngrade) {
+			t.Errorf("downgraded found: %v", err)
+		}
+	}
+	if _, _, err := k.Found(&packet.Base{Ver: packet.VersionBind, ID: id + 1}, &packet.Found{}); !errors.Is(err, ErrUnknown) {
+		t.Errorf("unknown quest: %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerQuest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, dh, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := k.Report(id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok, sig, err := packet.DecodeReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.ID != id || b.Ver != packet.VersionBind || ok {
+		t.Errorf("report: %+v, %v", b, ok)
+	}
+	if !packet.VerifyReport(b, ok, msg.DH_X25519, dh.PublicBytes(), sig) {
+		t.Error("report signature rejected")
+	}
+	k.Done(id)
+
+	if _, err = k.Report(id, true); !errors.Is(err, ErrUnknown) {
+		t.Errorf("finished quest: %v", err)
+	}
+}
+
+// 密钥是否已被擦除。
+func erased(dh *msg.DHPack) bool {
+	_, err := dh.PrivateBytes()
+	return errors.Is(err, msg.ErrNoKey)
+}
+
+func TestSessionRotate(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerSession, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id1, dh1, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, dh2, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dh1 != dh2 || id1 == id2 {
+		t.Fatal("session key not shared")
+	}
+	// 到期轮换，旧密钥仍被未决询问使用
+	k.mu.Lock()
+	k.rotated = time.Now().Add(-config.QuestKeyRotate)
+	k.mu.Unlock()
+
+	id3, dh3, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dh3 == dh1 {
+		t.Fatal("session key not rotated")
+	}
+	if erased(dh1) {
+		t.Fatal("key in use erased by rotation")
+	}
+	if _, _, err = k.Reply(testReply(t, packet.VersionBind, id1, dh1)); err != nil {
+		t.Errorf("reply to the old key: %v", err)
+	}
+	k.Done(id1)
+	if erased(dh1) {
+		t.Fatal("key erased while still referenced")
+	}
+	k.Done(id2)
+	if !erased(dh1) {
+		t.Error("unreferenced old key kept")
+	}
+	// 当前会话密钥不因询问结束而擦除
+	k.Done(id3)
+	if erased(dh3) {
+		t.Error("current session key erased")
+	}
+}
+
+func TestPerQuest(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerQuest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id1, dh1, _ := k.New(packet.VersionBind)
+	_, dh2, _ := k.New(packet.VersionBind)
+
+	if dh1 == dh2 {
+		t.Fatal("quest key shared")
+	}
+	k.Done(id1)
+	if !erased(dh1) || erased(dh2) {
+		t.Error("wrong key erased")
+	}
+	if k.Lookup(id1) != nil {
+		t.Error("finished quest found")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerSession, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for range 3 {
+		id, _, err := k.New(packet.VersionBind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if _, _, err = k.New(packet.VersionBind); !errors.Is(err, ErrTooMany) {
+		t.Errorf("over limit: %v", err)
+	}
+	if err = k.Chunks(ids[0], 1); !errors.Is(err, ErrTooMany) {
+		t.Errorf("chunks over limit: %v", err)
+	}
+	k.Done(ids[2])
+
+	if _, _, err = k.New(packet.VersionBind); err != nil {
+		t.Errorf("after done: %v", err)
+	}
+	// 超时的询问在达到上限时先被清理
+	k.mu.Lock()
+	for _, q := range k.quests {
+		q.created = time.Now().Add(-config.QuestExpired)
+	}
+	k.mu.Unlock()
+
+	if _, _, err = k.New(packet.VersionBind); err != nil || k.Len() != 1 {
+		t.Errorf("expired quests not cleaned: %d, %v", k.Len(), err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerQuest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, dh, err := k.New(packet.VersionBind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Chunks(id+1, id+2); !errors.Is(err, ErrUnknown) {
+		t.Errorf("unknown quest: %v", err)
+	}
+	if err = k.Chunks(id, id+1, id+2); err != nil {
+		t.Fatal(err)
+	}
+	if k.Len() != 3 || k.Lookup(id+2) != dh {
+		t.Fatal("chunks not registered")
+	}
+	if _, _, err = k.Reply(testReply(t, packet.VersionBind, id+1, dh)); err != nil {
+		t.Errorf("chunk reply: %v", err)
+	}
+	// 密钥在最后一批结束后才擦除
+	k.Done(id)
+	k.Done(id + 1)
+	if erased(dh) {
+		t.Fatal("key erased while a chunk is pending")
+	}
+	k.Done(id + 2)
+	if !erased(dh) {
+		t.Error("key kept after all chunks done")
+	}
+}
+
+func TestClean(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerQuest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, dh, _ := k.New(packet.VersionBind)
+	fresh, _, _ := k.New(packet.VersionBind)
+
+	k.mu.Lock()
+	k.quests[old].created = time.Now().Add(-config.QuestExpired)
+	k.mu.Unlock()
+
+	// 过期的询问在清理前即不可用
+	if _, _, err = k.Reply(testReply(t, packet.VersionBind, old, dh)); !errors.Is(err, ErrUnknown) {
+		t.Errorf("expired quest: %v", err)
+	}
+	k.Clean()
+
+	if k.Len() != 1 || k.Lookup(fresh) == nil || !erased(dh) {
+		t.Error("expired quest not cleaned")
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	k, err := NewKeys(msg.DH_X25519, KeyPerSession, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 20 {
+				id, dh, err := k.New(packet.VersionBind)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				data := testReply(t, packet.VersionBind, id, dh)
+
+				// 与结束和轮换并发
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					k.Done(id)
+				}()
+				k.Reply(data)
+				k.Report(id, true)
+
+				k.mu.Lock()
+				k.rotated = time.Time{}
+				k.mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/cxio/depots/packet"
+)
+
+// Reachable 本方能否与数据源建立连接。
+// 与驿站节点决定回复还是转播时的判断相同（packet.Reachable），
+// 应用可据此预判回复是否可用，或在询问前了解可用的数据源范围。
+// @self   本方NAT层级
+// @source 数据源NAT层级
+func Reachable(self, source packet.NatLevel) bool {
+	return packet.Reachable(self, source)
+}
+
+// Usable 回复是否可用。
+// 回复的基础信息中为数据源的NAT层级（已解密）。
+// 注：遵循规范的驿站不会发出不可用的回复，但恶意或旧版节点可能。
+// @self 本方NAT层级
+// @b    回复的基础信息（Keys.Reply 返回）
+func Usable(self packet.NatLevel, b *packet.Base) bool {
+	return b != nil && packet.Reachable(self, b.Level)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/cxio/depots/crypto/keystore"
+)
+
+// 密钥口令环境变量名
+const envKeyPass = "DEPOTS_KEYPASS"
+
+// 密钥管理子命令。
+// 子命令：show|rotate|export，目标密钥默认为身份密钥。
+// @args 子命令参数
+func keyCommand(args []string) {
+	cmd, name := "show", keystore.KeyIdentity
+
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	if len(args) > 1 {
+		name = args[1]
+	}
+	ks, err := keystore.Open("", []byte(os.Getenv(envKeyPass)))
+	if err != nil {
+		log.Fatalln("[Error]", err)
+	}
+	switch cmd {
+	case "show":
+		algor, pub, err := ks.Public(name)
+		// 不存在时先创建
+		if errors.Is(err, fs.ErrNotExist) {
+			if err = ensureKey(ks, name); err != nil {
+				log.Fatalln("[Error]", err)
+			}
+			algor, pub, err = ks.Public(name)
+		}
+		if err != nil {
+			log.Fatalln("[Error]", err)
+		}
+		fmt.Printf("%s:%d:%s\n", name, algor, hex.EncodeToString(pub))
+	case "rotate":
+		if err = ks.Rotate(name); err != nil {
+			log.Fatalln("[Error]", err)
+		}
+		fmt.Println("key rotated:", name)
+	case "export":
+		data, err := ks.Export(name)
+		if err != nil {
+			log.Fatalln("[Error]", err)
+		}
+		os.Stdout.Write(data)
+	default:
+		log.Fatalln("[Error] unknown key command:", cmd)
+	}
+}
+
+// 确保目标密钥存在。
+// 不存在时自动创建。
+func ensureKey(ks *keystore.Store, name string) error {
+	var err error
+
+	switch name {
+	case keystore.KeyIdentity:
+		_, err = ks.Identity()
+	case keystore.KeyExchange:
+		_, err = ks.Exchange()
+	default:
+		err = keystore.ErrKind
+	}
+	return err
+}
@@ -2,10 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"io/fs"
 	"log"
 	"net/netip"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hjson/hjson-go"
@@ -47,8 +51,16 @@ func Base() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	return config, hjson.Unmarshal(data, config)
+	// hjson 仅能解码到通用值，
+	// 经由JSON转换到配置结构，未配置的成员保留默认值。
+	var raw any
+	if err = hjson.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, err
+	}
+	return config, json.Unmarshal(data, config)
 }
 
 // Peers 获取用户配置的节点IP信息集。
@@ -83,8 +95,66 @@ func Peers() (map[netip.Addr]*Peer, error) {
 	return list, nil
 }
 
+// SavePeers 保存有效节点清单。
+// 写入 ~/.depots/peers.json，覆盖原有内容，供下次启动时快速组网。
+// @list 节点清单
+func SavePeers(list []*Peer) error {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(usr, fileDir)
+
+	if !pathExists(dir) {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, filePeers), data, 0644)
+}
+
+// Prefixes 获取网段到自治域编号（ASN）的对照表。
+// 配置文件 ~/.depots/asn.txt，可选，不存在时返回空集。
+// 每行一个条目：网段（CIDR）和自治域编号，以空白分隔，# 起始的行为注释。
+// 格式错误的行被忽略。
+func Prefixes() (map[netip.Prefix]uint32, error) {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	list := make(map[netip.Prefix]uint32)
+
+	data, err := os.ReadFile(filepath.Join(usr, fileDir, fileASN))
+	// 容错文件不存在
+	if err != nil {
+		return list, nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fs := strings.Fields(line)
+
+		if len(fs) < 2 || strings.HasPrefix(fs[0], "#") {
+			continue
+		}
+		p, err := netip.ParsePrefix(fs[0])
+		if err != nil {
+			continue
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(fs[1]), "AS"), 10, 32)
+		if err != nil {
+			continue
+		}
+		list[p.Masked()] = uint32(asn)
+	}
+	return list, nil
+}
+
 // Bans 获取用户配置的禁闭节点集。
 // 配置文件 bans.json，存在于应用程序的系统缓存目录下。
+// 条目可为IP、IP:Port或网段（CIDR），键为原始条目，值为载入时间。
 // 注：
 // 地址应当格式正确，无空格。
 func Bans() (map[string]time.Time, error) {
@@ -113,6 +183,118 @@ func Bans() (map[string]time.Time, error) {
 	return pool, nil
 }
 
+// Banned 获取运行时禁闭记录。
+// 记录文件 banned.json，存在于应用程序的系统缓存目录下，不存在时返回空集。
+func Banned() ([]*Ban, error) {
+	var list []*Ban
+
+	dir, err := appCacheDir("")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileBanned))
+	// 容错文件不存在
+	if err != nil {
+		return list, nil
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveBanned 保存运行时禁闭记录。
+// @list 禁闭记录集
+func SaveBanned(list []*Ban) error {
+	dir, err := appCacheDir("")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileBanned), data, 0644)
+}
+
+// Idents 获取已知节点身份记录。
+// 记录文件 idents.json，存在于应用程序的系统缓存目录下，不存在时返回空集。
+func Idents() ([]*Ident, error) {
+	var list []*Ident
+
+	dir, err := appCacheDir("")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileIdents))
+	// 容错文件不存在
+	if err != nil {
+		return list, nil
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveIdents 保存已知节点身份记录。
+// @list 身份记录集
+func SaveIdents(list []*Ident) error {
+	dir, err := appCacheDir("")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileIdents), data, 0644)
+}
+
+// Signers 获取公认心跳签名者清单。
+// 配置文件 ~/.depots/signers.json，不存在时返回空清单。
+func Signers() (*SignerList, error) {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	list := &SignerList{}
+	configPath := filepath.Join(usr, fileDir, fileSigner)
+
+	data, err := os.ReadFile(configPath)
+	// 可选配置，不存在即为空清单
+	if errors.Is(err, fs.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		log.Println("[Error]", err)
+		return list, nil
+	}
+	return list, json.Unmarshal(data, list)
+}
+
+// SaveSigners 保存公认心跳签名者清单。
+// 在线更新成功后调用，写入 ~/.depots/signers.json。
+// 先写入临时文件再替换，避免中断时损坏原配置。
+// @list 签名者清单配置
+func SaveSigners(list *SignerList) error {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(usr, fileDir, fileSigner)
+	tmp := configPath + ".tmp"
+
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, configPath)
+}
+
 // Stakes 读取权益配置集
 // 服务器支持的应用类型名称，以及可受益的账户地址。
 // 对于提供了服务但没有相应区块链收益地址的，账户设置为空串。
@@ -159,6 +341,21 @@ func Ploys(file string) (map[string]string, error) {
 	return ploys, err
 }
 
+// KeysDir 获取节点密钥存储目录。
+// 目录为 ~/.depots/keys/，不存在时自动创建，仅所有者可访问。
+func KeysDir() (string, error) {
+	usr, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(usr, fileDir, dirKeys)
+
+	if !pathExists(path) {
+		err = os.MkdirAll(path, 0700)
+	}
+	return path, err
+}
+
 // CreateLoger 创建一个日志记录器。
 // 实参path为存储路径，如果用户传递一个空串，
 // 则使用相对于应用程序系统缓存目录内的logs子目录。
@@ -11,7 +11,8 @@
 // 程序运行过程中，不友好节点会被临时禁闭排除。
 // 用户也可以配置一个节点清单，但它们也遵循同样的时效期。
 // 禁闭配置文件（bans.json）在应用程序系统缓存目录下，如果不存在可手工创建。
-// 内容为简单的地址（IP:Port）清单。
+// 内容为简单的地址清单，条目可为IP、IP:Port或网段（CIDR）。
+// 运行时因不当行为被禁闭的记录保存在同目录的 banned.json 中，重启后继续生效。
 //
 // @2024.11.30 cxio
 ///////////////////////////////////////////////////////////////////////////////
@@ -54,6 +55,134 @@ const (
 	FinderExpired = time.Minute * 120 // Findings节点在线过期时长（2h）
 )
 
+// 节点连接管理配置
+// 每次巡查（DepotPatrol）时，以新的候选节点替换评分最差的连接节点。
+const (
+	DepotRotate = 2           // 每次巡查替换的节点数
+	DepotFails  = 3           // 候选节点连续连接失败上限，达到即移除
+	DepotRetry  = time.Minute // 连接失败后的重试间隔（按失败次数倍增）
+	DepotBucket = 2           // 同一网段（IPv4 /16、IPv6 /32）或同一自治域的连接数上限
+)
+
+// 候选节点池配置
+// 节点交换和Findings会持续添加候选，候选池有总量和分组上限。
+// 超出时优先淘汰失败过或久未见的候选，其次淘汰候选最多的分组中最久未见的。
+const (
+	DepotPool       = 1024               // 候选节点总数上限
+	DepotPoolBucket = 64                 // 同一分组（网段或自治域）的候选数上限
+	DepotStale      = time.Hour * 24 * 3 // 候选节点久未见（未被添加或连接成功）的时长
+)
+
+// 节点惩罚配置
+// 节点的不当行为累计惩罚分，达到阈值即被禁闭（BanExpired）。
+// 惩罚分随时间衰减（半衰期），偶发的错误不致禁闭。
+const (
+	BanThreshold     = 100              // 禁闭阈值
+	BanHalfLife      = time.Minute * 30 // 惩罚分半衰期
+	BanSave          = time.Minute      // 运行时禁闭记录的保存间隔（有变更时）
+	PenaltyMalformed = 20               // 格式错误的数据包
+	PenaltySignature = 50               // 签名验证失败
+	PenaltyHops      = 25               // 跳数违规（超限或非零起跳）
+	PenaltyFlood     = 5                // 流量超限（每次）
+	PenaltyFake      = 40               // 虚假回复（数据源不提供数据）
+)
+
+// 流量限额默认值
+// 令牌桶，按包类型分别计算，外部配置（config.hjson: limits）可覆盖。
+const (
+	LimitPeerRate    = 20               // 每个对端节点：每秒包数
+	LimitPeerBurst   = 40               // 每个对端节点：突发上限
+	LimitClientRate  = 10               // 每个客户端：每秒包数
+	LimitClientBurst = 20               // 每个客户端：突发上限
+	LimitGlobalRate  = 200              // 本节点总计：每秒包数
+	LimitGlobalBurst = 400              // 本节点总计：突发上限
+	LimitIdle        = time.Minute * 10 // 来源闲置超时，其限额状态被清理
+	LimitSources     = 4096             // 来源限额状态的数量上限
+	LimitWindow      = time.Second * 10 // 超限惩罚的统计窗口
+	LimitExcess      = 20               // 窗口内的超限丢弃达此数时惩罚一次
+)
+
+// 节点信誉配置
+// 转发回复的节点，依询问者对数据源的核实结果累计信誉分，信誉分随时间衰减。
+// 信誉影响回复汇集时的选取权重，以及巡查时的节点替换。
+const (
+	ReputeGood        = 1.0              // 核实有效的加分
+	ReputeBad         = -3.0             // 核实无效（虚假回复）的减分
+	ReputeMax         = 10.0             // 信誉分绝对值上限
+	ReputeHalfLife    = time.Hour * 6    // 信誉分半衰期
+	ReputeLinkExpired = time.Minute * 10 // 回复来源记录的保留时长（等待核实结果）
+)
+
+// 节点交换配置
+// 已连接的驿站之间定期分享近期核实过的节点地址，Findings网络不可用时仍可维持组网。
+const (
+	PexInterval = time.Minute * 15 // 分享间隔
+	PexSample   = 16               // 每次分享的地址数上限
+	PexFresh    = time.Hour * 3    // 分享的地址须在此时长内核实过
+	PexWindow   = time.Minute * 5  // 签名时间可接受的偏差（前后）
+	PexIntake   = 8                // 每个分享间隔内每个连接接收的新候选上限
+	PexSigners  = 1024             // 防重放记录的签名者数量上限
+)
+
+// 消息去重配置
+// 同一询问或探测可能经由多条路径到达，需在时间窗口内去重。
+const (
+	SeenQuestExpired = time.Minute * 2  // 询问ID去重窗口
+	SeenProbeExpired = time.Minute * 10 // 探测摘要去重窗口
+	SeenCapacity     = 1 << 16          // 去重集容量上限（条目数）
+)
+
+// 回复汇集配置
+// 中转节点对同一询问只回传一个回复，从候选中随机选取。
+const (
+	ReplyPool      = 3               // 候选池满额，即时回传
+	ReplyWait      = time.Second     // 第二个回复起的等待时长
+	ReplyTimeout   = time.Second * 5 // 总超时，有回复即回传
+	PendingExpired = time.Minute * 2 // 待决询问状态保留时长
+)
+
+// 探测重放防护
+// 签名探测包的时间戳须在窗口内，窗口内同一签名者的随机数不得重复。
+const (
+	ProbeWindow   = time.Minute * 5 // 时间戳可接受的偏差（前后）
+	ReplaySigners = 1024            // 重放缓存的签名者数量上限
+	ReplayNonces  = 1 << 14         // 每个签名者的随机数缓存上限
+)
+
+// 探测信号权重
+// 未签名或签名者不被信任的探测包，其紧缺性信号打折计算。
+const (
+	ProbeUnsignedWeight = 0.5 // 未签名探测包（经由驿站转播）的权重
+)
+
+// 转播目标配置
+// 转播时优先选取可能拥有或能路由目标数据的节点，
+// 但总保留若干随机选取的节点，使紧缺性感知不受能力声明的影响。
+const (
+	ForwardRandom = 2 // 每次转播中随机选取的节点数
+)
+
+// 批量询问配置
+const (
+	BatchMax = 256 // 单个批量询问包的条目上限
+)
+
+// 客户端询问密钥配置
+const (
+	QuestMax       = 256              // 未决询问数量上限
+	QuestExpired   = time.Minute * 3  // 询问密钥保留时长，超时擦除
+	QuestKeyRotate = time.Minute * 10 // 会话密钥轮换间隔
+)
+
+// 节点连接配置
+const (
+	LinkTimeout    = time.Second * 10    // 加密连接握手超时
+	LinkRetransmit = time.Second         // 数据报握手的重传间隔
+	LinkRetries    = 4                   // 数据报握手的重传次数上限
+	IdentsMax      = 4096                // 已知身份记录的数量上限
+	IdentExpired   = time.Hour * 24 * 30 // 已知身份记录的时效（自最近握手起）
+)
+
 // 本系统（depots:z）
 const (
 	Kind    = "depots" // 基础类别
@@ -91,6 +220,11 @@ const (
 	filePeers  = "peers.json"   // 有效节点清单
 	fileStakes = "stakes.hjson" // 服务器权益账户配置
 	fileBans   = "bans.json"    // 禁闭节点配置
+	fileBanned = "banned.json"  // 运行时禁闭记录（缓存目录）
+	dirKeys    = "keys"         // 节点密钥存储目录
+	fileSigner = "signers.json" // 公认心跳签名者清单
+	fileIdents = "idents.json"  // 已知节点身份记录（缓存目录）
+	fileASN    = "asn.txt"      // 网段到自治域编号的对照表（可选）
 )
 
 //
@@ -100,8 +234,10 @@ const (
 // Peer 端点类型。
 // 仅用于读取用户的节点配置。
 type Peer struct {
-	IP   netip.Addr `json:"ip"`             // 公网IP
-	Port uint16     `json:"port,omitempty"` // 公网端口，作为Config成员时可选
+	IP     netip.Addr `json:"ip"`               // 公网IP
+	Port   uint16     `json:"port,omitempty"`   // 公网端口，作为Config成员时可选
+	Algor  int        `json:"algor,omitempty"`  // 身份签名算法
+	Pubkey string     `json:"pubkey,omitempty"` // 身份公钥（16进制），设置时钉扎
 }
 
 func (p *Peer) String() string {
@@ -122,4 +258,57 @@ type Config struct {
 	LogDir       string `json:"log_dir,omitempty"`       // 日志根目录，注意空串有特定含义
 	PloyLang     string `json:"ploy_lang,omitempty"`     // 策略函数实现语言
 	PloySeed     string `json:"ploy_seed,omitempty"`     // 策略种子
+	Limits       Limits `json:"limits,omitempty"`        // 流量限额
+}
+
+// Rate 流量限额（令牌桶）。
+type Rate struct {
+	Rate  float64 `json:"rate"`            // 每秒补充的令牌数（包数），零值表示不限
+	Burst int     `json:"burst,omitempty"` // 桶容量（突发上限），零值取每秒包数
+}
+
+// Limits 流量限额配置。
+// 键为包类型名（probe|quest|reply|batch|batchreply|cancel|report|exchange|caps），
+// 或包类型名与数据类别名的组合（如 quest.blockchain），组合键的限额附加于类型限额之上。
+// 未配置的包类型采用默认限额，组合键仅在配置时生效。
+type Limits struct {
+	Peer   map[string]Rate `json:"peer,omitempty"`   // 每个对端节点连接
+	Client map[string]Rate `json:"client,omitempty"` // 每个客户端
+	Global map[string]Rate `json:"global,omitempty"` // 本节点总计
+}
+
+// Signer 心跳签名者配置。
+// 仅用于读取用户的签名者清单。
+type Signer struct {
+	Name    string    `json:"name"`              // 名称（备注）
+	Algor   int       `json:"algor"`             // 签名算法标识
+	Pubkey  string    `json:"pubkey"`            // 签名公钥（16进制）
+	Weight  float64   `json:"weight,omitempty"`  // 信号权重，零值视为1
+	Expires time.Time `json:"expires,omitempty"` // 过期时间，零值表示不过期
+}
+
+// Ident 节点身份记录。
+// 加密连接握手时获知的对端身份公钥。
+type Ident struct {
+	IP     netip.Addr `json:"ip"`     // 节点IP
+	Algor  int        `json:"algor"`  // 身份签名算法
+	Pubkey string     `json:"pubkey"` // 身份公钥（16进制）
+	Seen   time.Time  `json:"seen"`   // 最近一次握手时间
+}
+
+// Ban 禁闭记录。
+// 运行时因不当行为被禁闭的节点或网段。
+type Ban struct {
+	Net    netip.Prefix `json:"net"`              // 禁闭的网段（单个IP为全长前缀）
+	Since  time.Time    `json:"since"`            // 禁闭开始时间
+	Reason string       `json:"reason,omitempty"` // 禁闭原因
+}
+
+// SignerList 心跳签名者清单配置。
+// 主控公钥用于验证签名者清单的在线更新，可选。
+// 在线更新成功后，其序号和清单写回本配置。
+type SignerList struct {
+	Master  string    `json:"master,omitempty"` // 主控公钥（ed25519，16进制）
+	Seq     uint64    `json:"seq,omitempty"`    // 已应用的在线更新序号
+	Signers []*Signer `json:"signers"`          // 签名者清单
 }
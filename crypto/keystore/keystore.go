@@ -0,0 +1,372 @@
+// Package keystore 节点密钥的持久存储。
+// 管理节点的长期身份密钥（签名）和长期密钥交换密钥，
+// 存放于 ~/.depots/keys/ 目录下，可选口令加密。
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/findings/crypto/utilx"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 两种密钥的名称
+// 也即存储的文件名（不含扩展名）。
+const (
+	KeyIdentity = "identity" // 身份密钥（签名）
+	KeyExchange = "exchange" // 长期密钥交换密钥
+)
+
+// 密钥文件扩展名
+const fileExt = ".key"
+
+// 口令派生参数（scrypt）
+const (
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	scryptSalt = 16
+)
+
+var (
+	// ErrPerm 密钥文件或目录权限过宽
+	ErrPerm = errors.New("key file is accessible by others")
+
+	// ErrPassphrase 需要口令或口令错误
+	ErrPassphrase = errors.New("passphrase required or incorrect")
+
+	// ErrKind 密钥类型不匹配
+	ErrKind = errors.New("key kind mismatch")
+
+	// ErrPublic 记录的公钥与私钥不匹配（文件被篡改或损坏）
+	ErrPublic = errors.New("public key does not match the private key")
+)
+
+// 密钥记录
+// 存储为JSON格式，私钥明文或密文二选一。
+type record struct {
+	Name    string    `json:"name"`              // 密钥名称
+	Algor   int       `json:"algor"`             // 算法标识
+	Created time.Time `json:"created"`           // 创建时间
+	Public  []byte    `json:"public"`            // 公钥
+	Private []byte    `json:"private,omitempty"` // 私钥（明文）
+	Sealed  []byte    `json:"sealed,omitempty"`  // 私钥（密文）
+	Salt    []byte    `json:"salt,omitempty"`    // 口令派生盐
+}
+
+// Store 密钥存储。
+type Store struct {
+	dir  string
+	pass []byte
+}
+
+// Open 打开密钥存储。
+// 如果目录权限过宽（组或其他用户可访问），返回错误。
+// @dir  存储目录，空串表示默认目录（~/.depots/keys/）
+// @pass 加密口令，可选。为空时私钥明文存储
+func Open(dir string, pass []byte) (*Store, error) {
+	var err error
+
+	if dir == "" {
+		if dir, err = config.KeysDir(); err != nil {
+			return nil, err
+		}
+	} else if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err = checkPerm(dir); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, pass: pass}, nil
+}
+
+// Identity 获取身份签名密钥。
+// 如果密钥不存在，创建一个新的（ed25519）并保存。
+// 记录的公钥须与私钥匹配，否则返回 ErrPublic。
+func (s *Store) Identity() (*msg.SignPack, error) {
+	rec, err := s.load(KeyIdentity)
+	if errors.Is(err, fs.ErrNotExist) {
+		rec, err = s.create(KeyIdentity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sp, err := s.identity(rec)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sp.PublicBytes(), rec.Public) {
+		return nil, ErrPublic
+	}
+	return sp, nil
+}
+
+// Exchange 获取长期密钥交换密钥。
+// 如果密钥不存在，创建一个新的（x25519）并保存。
+// 记录的公钥须与私钥匹配，否则返回 ErrPublic。
+func (s *Store) Exchange() (*msg.DHPack, error) {
+	rec, err := s.load(KeyExchange)
+	if errors.Is(err, fs.ErrNotExist) {
+		rec, err = s.create(KeyExchange)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dh, err := s.exchange(rec)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(dh.PublicBytes(), rec.Public) {
+		return nil, ErrPublic
+	}
+	return dh, nil
+}
+
+// Public 获取目标密钥的公钥。
+// 私钥可用时（明文存储，或设置了口令），公钥由私钥导出，
+// 与记录的公钥不匹配时返回 ErrPublic，避免发布被篡改的身份。
+// 私钥为密文且未设置口令时，只能返回记录的公钥。
+// @name 密钥名称（KeyIdentity|KeyExchange）
+// @return1 算法标识
+// @return2 公钥字节序列
+func (s *Store) Public(name string) (int, []byte, error) {
+	rec, err := s.load(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(rec.Sealed) > 0 && len(s.pass) == 0 {
+		return rec.Algor, rec.Public, nil
+	}
+	var pub []byte
+
+	switch name {
+	case KeyIdentity:
+		sp, err := s.identity(rec)
+		if err != nil {
+			return 0, nil, err
+		}
+		pub = sp.PublicBytes()
+	case KeyExchange:
+		dh, err := s.exchange(rec)
+		if err != nil {
+			return 0, nil, err
+		}
+		pub = dh.PublicBytes()
+	default:
+		return 0, nil, ErrKind
+	}
+	if !bytes.Equal(pub, rec.Public) {
+		return 0, nil, ErrPublic
+	}
+	return rec.Algor, pub, nil
+}
+
+// Rotate 轮换密钥。
+// 原密钥文件改名保留（附加时间戳和 .old 后缀），然后创建新密钥。
+// @name 密钥名称
+func (s *Store) Rotate(name string) error {
+	path := s.path(name)
+
+	if _, err := os.Stat(path); err == nil {
+		old := fmt.Sprintf("%s.%d.old", path, time.Now().Unix())
+		if err = os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	_, err := s.create(name)
+	return err
+}
+
+// Export 导出密钥文件内容。
+// 导出的是原始存储数据，如果设置了口令，私钥为密文。
+// @name 密钥名称
+func (s *Store) Export(name string) ([]byte, error) {
+	path := s.path(name)
+
+	if err := checkPerm(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 密钥文件路径。
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+fileExt)
+}
+
+// 读取密钥记录。
+// 文件权限过宽时拒绝读取。
+func (s *Store) load(name string) (*record, error) {
+	path := s.path(name)
+
+	if err := checkPerm(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &record{}
+
+	if err = json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+	if rec.Name != name {
+		return nil, ErrKind
+	}
+	return rec, nil
+}
+
+// 从记录构造身份签名密钥包。
+func (s *Store) identity(rec *record) (*msg.SignPack, error) {
+	data, err := s.private(rec)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := msg.ParseSignKey(msg.SignTag(rec.Algor), data)
+	if err != nil {
+		return nil, err
+	}
+	return msg.NewSignPack(msg.SignTag(rec.Algor), priv), nil
+}
+
+// 从记录构造密钥交换包。
+func (s *Store) exchange(rec *record) (*msg.DHPack, error) {
+	data, err := s.private(rec)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := msg.ParseDHKey(msg.DHTag(rec.Algor), data)
+	if err != nil {
+		return nil, err
+	}
+	return msg.NewDHPack(msg.DHTag(rec.Algor), priv), nil
+}
+
+// 创建并保存新密钥。
+func (s *Store) create(name string) (*record, error) {
+	var pub, priv []byte
+	var algor int
+
+	switch name {
+	case KeyIdentity:
+		key, err := msg.GenerateSignKey(msg.SIGN_ED25519)
+		if err != nil {
+			return nil, err
+		}
+		sp := msg.NewSignPack(msg.SIGN_ED25519, key)
+		if priv, err = sp.PrivateBytes(); err != nil {
+			return nil, err
+		}
+		pub, algor = sp.PublicBytes(), int(msg.SIGN_ED25519)
+	case KeyExchange:
+		key, err := msg.GenerateKey(msg.DH_X25519)
+		if err != nil {
+			return nil, err
+		}
+		dh := msg.NewDHPack(msg.DH_X25519, key)
+		if priv, err = dh.PrivateBytes(); err != nil {
+			return nil, err
+		}
+		pub, algor = dh.PublicBytes(), int(msg.DH_X25519)
+	default:
+		return nil, ErrKind
+	}
+	rec := &record{
+		Name:    name,
+		Algor:   algor,
+		Created: time.Now().UTC(),
+		Public:  pub,
+	}
+	if err := s.seal(rec, priv); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(rec, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	// 私钥仅所有者可读写
+	return rec, os.WriteFile(s.path(name), data, 0600)
+}
+
+// 封装私钥。
+// 设置了口令时加密存储。
+func (s *Store) seal(rec *record, priv []byte) error {
+	if len(s.pass) == 0 {
+		rec.Private = priv
+		return nil
+	}
+	salt := make([]byte, scryptSalt)
+
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := derive(s.pass, salt)
+	if err != nil {
+		return err
+	}
+	sealed, err := utilx.Encrypt(priv, key)
+	if err != nil {
+		return err
+	}
+	rec.Sealed, rec.Salt = sealed, salt
+	return nil
+}
+
+// 提取私钥。
+// 密文存储时需要口令解密。
+func (s *Store) private(rec *record) ([]byte, error) {
+	if len(rec.Sealed) == 0 {
+		return rec.Private, nil
+	}
+	if len(s.pass) == 0 {
+		return nil, ErrPassphrase
+	}
+	key, err := derive(s.pass, rec.Salt)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := utilx.Decrypt(rec.Sealed, key)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return priv, nil
+}
+
+// 口令派生加密密钥（scrypt）。
+func derive(pass, salt []byte) (*[32]byte, error) {
+	buf, err := scrypt.Key(pass, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	return (*[32]byte)(buf), nil
+}
+
+// 检查文件或目录权限。
+// 组或其他用户有任何权限时视为不安全。
+// 注：Windows 系统的权限模型不同，不检查。
+func checkPerm(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%w: %s (%v)", ErrPerm, path, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package keystore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cxio/depots/crypto/keystore"
+)
+
+// 创建仅所有者可访问的存储目录。
+func keysDir(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "keys")
+}
+
+// 改写密钥文件中的公钥。
+func tamper(t *testing.T, dir, name string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".key")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := make(map[string]any)
+
+	if err = json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+	rec["public"] = bytes.Repeat([]byte{0x42}, 32)
+
+	if data, err = json.Marshal(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for _, pass := range []string{"", "secret"} {
+		dir := keysDir(t)
+		ks, err := keystore.Open(dir, []byte(pass))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sp, err := ks.Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dh, err := ks.Exchange()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, pub, err := ks.Public(keystore.KeyIdentity)
+		if err != nil || !bytes.Equal(pub, sp.PublicBytes()) {
+			t.Errorf("pass %q: identity public %x, %v", pass, pub, err)
+		}
+		_, pub, err = ks.Public(keystore.KeyExchange)
+		if err != nil || !bytes.Equal(pub, dh.PublicBytes()) {
+			t.Errorf("pass %q: exchange public %x, %v", pass, pub, err)
+		}
+	}
+}
+
+func TestPublicTampered(t *testing.T) {
+	for _, pass := range []string{"", "secret"} {
+		dir := keysDir(t)
+		ks, err := keystore.Open(dir, []byte(pass))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ks.Identity(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ks.Exchange(); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{keystore.KeyIdentity, keystore.KeyExchange} {
+			tamper(t, dir, name)
+
+			if _, _, err = ks.Public(name); !errors.Is(err, keystore.ErrPublic) {
+				t.Errorf("pass %q: %s public: %v", pass, name, err)
+			}
+		}
+		if _, err = ks.Identity(); !errors.Is(err, keystore.ErrPublic) {
+			t.Errorf("pass %q: identity: %v", pass, err)
+		}
+		if _, err = ks.Exchange(); !errors.Is(err, keystore.ErrPublic) {
+			t.Errorf("pass %q: exchange: %v", pass, err)
+		}
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	dir := keysDir(t)
+	ks, err := keystore.Open(dir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ks.Identity(); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := keystore.Open(dir, []byte("wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = bad.Identity(); !errors.Is(err, keystore.ErrPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	if _, _, err = bad.Public(keystore.KeyIdentity); !errors.Is(err, keystore.ErrPassphrase) {
+		t.Errorf("public with wrong passphrase: %v", err)
+	}
+	// 无口令时只能返回记录的公钥
+	none, err := keystore.Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = none.Public(keystore.KeyIdentity); err != nil {
+		t.Errorf("public without passphrase: %v", err)
+	}
+	if _, err = none.Identity(); !errors.Is(err, keystore.ErrPassphrase) {
+		t.Errorf("identity without passphrase: %v", err)
+	}
+}
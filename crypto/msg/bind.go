@@ -0,0 +1,145 @@
+package msg
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// 绑定加密的密钥承诺长度
+const commitSize = 32
+
+// 绑定加密的最小密文长度
+const boundMin = commitSize + chacha20poly1305.NonceSizeX + chacha20poly1305.Overhead
+
+var (
+	// ErrCommit 密钥承诺不符（密钥或上下文错误）
+	ErrCommit = errors.New("key commitment mismatch")
+
+	// ErrBound 绑定加密的密文格式错误
+	ErrBound = errors.New("malformed bound ciphertext")
+)
+
+// SealBound 向对端加密消息，并绑定上下文。
+// 与 Seal 相同地构造共享密钥，但：
+// - 以 HKDF-SHA3-256 派生密钥，盐为收发双方公钥和封装密文的记录摘要，信息为上下文。
+// - 以 XChaCha20-Poly1305 加密，上下文和记录摘要作为附加数据。
+// - 密文前附加密钥承诺，接收端先行核对，防止同一密文在不同密钥下解密。
+// 密文格式：承诺（32） | 随机数（24） | 密文
+// @public 对端（接收者）公钥序列
+// @ctx    上下文（如版本、询问ID）
+// @msg    待加密消息
+// @return1 封装密文（KEM），或nil
+// @return2 加密后的密文
+func (dh *DHPack) SealBound(public, ctx, msg []byte) ([]byte, []byte, error) {
+	var ct []byte
+	var key *Secret
+	var err error
+
+	if dh.Algor.IsKEM() {
+		ct, key, err = encapsulate(dh.Algor, public)
+	} else {
+		key, err = dh.SharedKey(public)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := transcript(public, dh.PublicBytes(), ct)
+
+	aead, commit, err := boundKeys(key, hash, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := randomBytes(chacha20poly1305.NonceSizeX)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := make([]byte, 0, boundMin+len(msg))
+	buf = append(buf, commit...)
+	buf = append(buf, nonce...)
+
+	return ct, aead.Seal(buf, nonce, msg, associated(ctx, hash)), nil
+}
+
+// OpenBound 解密对端绑定上下文的消息。
+// 与 SealBound 对应，上下文必须与加密时相同。
+// @public 对端（发送者）公钥序列（KEM时为空）
+// @ct     封装密文（非KEM时忽略）
+// @ctx    上下文
+// @data   待解密数据
+func (dh *DHPack) OpenBound(public, ct, ctx, data []byte) ([]byte, error) {
+	if len(data) < boundMin {
+		return nil, ErrBound
+	}
+	var key *Secret
+	var err error
+
+	if dh.Algor.IsKEM() {
+		key, err = dh.decapsulate(ct)
+	} else {
+		ct = nil
+		key, err = dh.SharedKey(public)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := transcript(dh.PublicBytes(), public, ct)
+
+	aead, commit, err := boundKeys(key, hash, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(commit, data[:commitSize]) != 1 {
+		return nil, ErrCommit
+	}
+	nonce := data[commitSize : commitSize+chacha20poly1305.NonceSizeX]
+
+	return aead.Open(nil, nonce, data[commitSize+len(nonce):], associated(ctx, hash))
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 构造记录摘要。
+// SHA3-256(接收者公钥 | 发送者公钥 | 封装密文)，各项均有2字节长度前缀。
+// @recv 接收者公钥
+// @send 发送者公钥（KEM时为空）
+// @ct   封装密文（非KEM时为空）
+func transcript(recv, send, ct []byte) []byte {
+	d := sha3.New256()
+
+	for _, b := range [][]byte{recv, send, ct} {
+		d.Write(binary.BigEndian.AppendUint16(nil, uint16(len(b))))
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
+
+// 派生加密器和密钥承诺。
+// @key  共享密钥
+// @salt 记录摘要
+// @ctx  上下文
+func boundKeys(key *Secret, salt, ctx []byte) (aead cipher.AEAD, commit []byte, err error) {
+	buf := make([]byte, chacha20poly1305.KeySize+commitSize)
+
+	if _, err = hkdf.New(sha3.New256, key[:], salt, ctx).Read(buf); err != nil {
+		return nil, nil, err
+	}
+	if aead, err = chacha20poly1305.NewX(buf[:chacha20poly1305.KeySize]); err != nil {
+		return nil, nil, err
+	}
+	return aead, buf[chacha20poly1305.KeySize:], nil
+}
+
+// 构造附加数据（上下文 | 记录摘要）。
+func associated(ctx, hash []byte) []byte {
+	buf := make([]byte, 0, len(ctx)+len(hash))
+	buf = append(buf, ctx...)
+	return append(buf, hash...)
+}
@@ -1,6 +1,7 @@
 package msg
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdh"
 	"crypto/rand"
@@ -9,19 +10,21 @@ import (
 	"io"
 
 	"github.com/cloudflare/circl/dh/x25519"
+	"github.com/cloudflare/circl/kem"
 	"github.com/cxio/findings/crypto/utilx"
 	"golang.org/x/crypto/sha3"
 )
 
-const (
-	// 不支持的算法标识
-	failAlgor = "Unsupported algorithm identifier."
+var (
+	// ErrAlgor 不支持的算法标识
+	ErrAlgor = errors.New("unsupported algorithm identifier")
 
-	// 密钥长度不足
-	failKeylen = "The key length is insufficient."
-)
+	// ErrKeylen 密钥长度与算法不符
+	ErrKeylen = errors.New("key length does not match the algorithm")
+
+	// ErrNoKey 私钥缺失或与算法不匹配
+	ErrNoKey = errors.New("private key missing or mismatched")
 
-var (
 	// ErrShared 共享密钥构造错误
 	ErrShared = errors.New("the public key is a low-order point.")
 )
@@ -51,7 +54,7 @@ func (k *Key25519) Public() PublicKey {
 
 // Equal 私钥相等比较
 func (k *Key25519) Equal(x PrivateKey) bool {
-	if priv, ok := x.(*x25519.Key); ok {
+	if priv, ok := x.(*Key25519); ok {
 		return subtle.ConstantTimeCompare(priv[:], k[:]) == 1
 	}
 	return false
@@ -62,13 +65,20 @@ type DHTag int
 
 // 几个常用密钥交换算法
 const (
-	DH_Tradi  DHTag = iota // 惯用DH算法（x25519）
-	DH_X25519              // x25519曲线
-	DH_ECp256              // ECDH-p256曲线
-	DH_ECp384              // ECDH-p384曲线
+	DH_Tradi          DHTag = iota // 惯用DH算法（x25519）
+	DH_X25519                      // x25519曲线
+	DH_ECp256                      // ECDH-p256曲线
+	DH_ECp384                      // ECDH-p384曲线
+	DH_MLKEM768                    // ML-KEM-768（密钥封装，抗量子）
+	DH_X25519MLKEM768              // X25519+ML-KEM-768 混合（密钥封装）
 )
 
+// DHTags 已支持的密钥交换算法清单。
+// 用于对外声明能力（握手协商）。
+var DHTags = []DHTag{DH_Tradi, DH_X25519, DH_ECp256, DH_ECp384, DH_MLKEM768, DH_X25519MLKEM768}
+
 // GenerateKey 创建密钥交换用私钥
+// 不支持的算法返回 ErrAlgor。
 // @tag 密钥交换算法标识
 func GenerateKey(tag DHTag) (PrivateKey, error) {
 	switch tag {
@@ -80,8 +90,36 @@ func GenerateKey(tag DHTag) (PrivateKey, error) {
 		return ecdh.P256().GenerateKey(rand.Reader)
 	case DH_ECp384:
 		return ecdh.P384().GenerateKey(rand.Reader)
+	case DH_MLKEM768, DH_X25519MLKEM768:
+		_, priv, err := kemScheme(tag).GenerateKeyPair()
+		return priv, err
 	}
-	panic(failAlgor)
+	return nil, ErrAlgor
+}
+
+// ParseDHKey 从字节序列恢复密钥交换私钥
+// 与 DHPack.PrivateBytes 对应，用于密钥的持久存储。
+// @tag  密钥交换算法标识
+// @data 私钥字节序列
+func ParseDHKey(tag DHTag, data []byte) (PrivateKey, error) {
+	switch tag {
+	case DH_Tradi:
+		fallthrough
+	case DH_X25519:
+		if len(data) != x25519.Size {
+			return nil, ErrKeylen
+		}
+		key := Key25519{}
+		copy(key[:], data)
+		return &key, nil
+	case DH_ECp256:
+		return ecdh.P256().NewPrivateKey(data)
+	case DH_ECp384:
+		return ecdh.P384().NewPrivateKey(data)
+	case DH_MLKEM768, DH_X25519MLKEM768:
+		return kemScheme(tag).UnmarshalBinaryPrivateKey(data)
+	}
+	return nil, ErrAlgor
 }
 
 // Hash256SHA3 共享密钥哈希封装。
@@ -99,7 +137,7 @@ type DHPack struct {
 }
 
 // NewDHPack 创建一个密钥交换包。
-// 只有被支持的算法才能创建实例，否则返回nil。
+// 只有被支持的算法才能创建实例，私钥（如果有）也需与算法匹配，否则返回nil。
 // 外部注意检查返回的结果。
 func NewDHPack(tag DHTag, priv PrivateKey) *DHPack {
 	switch tag {
@@ -108,14 +146,21 @@ func NewDHPack(tag DHTag, priv PrivateKey) *DHPack {
 	case DH_X25519:
 	case DH_ECp256:
 	case DH_ECp384:
+	case DH_MLKEM768:
+	case DH_X25519MLKEM768:
 	default:
 		return nil
 	}
+	if priv != nil && !dhKeyOK(tag, priv) {
+		return nil
+	}
 	return &DHPack{Algor: tag, privkey: priv}
 }
 
 // SharedKey 构造共享密钥
 // 内部计算的共享密钥会被哈希（SHA3:256）一次后返回。
+// 密钥封装算法不能由双方公钥直接构造，返回 ErrKEM（应使用 Seal/Open）。
+// 对端公钥的长度和有效性会被检查，无效时返回错误。
 // @public 乙方公钥
 // @return 直接可用的共享密钥
 func (dh *DHPack) SharedKey(public []byte) (*Secret, error) {
@@ -128,26 +173,49 @@ func (dh *DHPack) SharedKey(public []byte) (*Secret, error) {
 		fallthrough
 	case DH_ECp384:
 		return sharedECDH(dh.privkey, public)
+	case DH_MLKEM768, DH_X25519MLKEM768:
+		return nil, ErrKEM
 	}
-	panic(failAlgor)
+	return nil, ErrAlgor
 }
 
 // PublicBytes 提取公钥字节序列。
-// 注意：算法和私钥应当匹配，否则会抛出恐慌。
+// 私钥缺失或与算法不匹配时返回nil，
+// 如密钥封装算法的封装方（回复者）无私钥。
 func (dh *DHPack) PublicBytes() []byte {
-	switch dh.Algor {
-	case DH_Tradi:
-		fallthrough
-	case DH_X25519:
-		priv := dh.privkey.(*Key25519)
+	switch priv := dh.privkey.(type) {
+	case *Key25519:
 		return priv.Public().([]byte)
-	case DH_ECp256:
-		fallthrough
-	case DH_ECp384:
-		priv := dh.privkey.(*ecdh.PrivateKey)
+	case *ecdh.PrivateKey:
 		return priv.PublicKey().Bytes()
+	case kem.PrivateKey:
+		return kemPublic(priv)
+	}
+	return nil
+}
+
+// PrivateBytes 提取私钥字节序列。
+// 用于密钥的持久存储。
+func (dh *DHPack) PrivateBytes() ([]byte, error) {
+	switch priv := dh.privkey.(type) {
+	case *Key25519:
+		return bytes.Clone(priv[:]), nil
+	case *ecdh.PrivateKey:
+		return priv.Bytes(), nil
+	case kem.PrivateKey:
+		return priv.MarshalBinary()
+	}
+	return nil, ErrNoKey
+}
+
+// Erase 擦除私钥。
+// X25519 私钥会被清零，其它算法的私钥仅解除引用。
+// 擦除后密钥交换包不再可用。
+func (dh *DHPack) Erase() {
+	if priv, ok := dh.privkey.(*Key25519); ok {
+		clear(priv[:])
 	}
-	panic(failAlgor)
+	dh.privkey = nil
 }
 
 // Encrypt 加密消息
@@ -195,9 +263,12 @@ func sharedX25519(private PrivateKey, public []byte) (*Secret, error) {
 	buf := x25519.Key{}
 
 	if len(public) != x25519.Size {
-		return nil, ErrShared
+		return nil, ErrKeylen
+	}
+	priv, ok := private.(*Key25519)
+	if !ok {
+		return nil, ErrNoKey
 	}
-	priv := private.(*Key25519)
 
 	if !x25519.Shared(&buf, (*x25519.Key)(priv), (*x25519.Key)(public)) {
 		return nil, ErrShared
@@ -214,7 +285,10 @@ func sharedX25519(private PrivateKey, public []byte) (*Secret, error) {
 // @private 己方私钥
 // @public 对方公钥的字节序列
 func sharedECDH(private PrivateKey, public []byte) (*Secret, error) {
-	priv := private.(*ecdh.PrivateKey)
+	priv, ok := private.(*ecdh.PrivateKey)
+	if !ok {
+		return nil, ErrNoKey
+	}
 	curve := priv.Curve()
 
 	pub, err := curve.NewPublicKey(public)
@@ -229,6 +303,20 @@ func sharedECDH(private PrivateKey, public []byte) (*Secret, error) {
 	return Hash256sha3(buf), nil
 }
 
+// 私钥是否与算法匹配。
+func dhKeyOK(tag DHTag, priv PrivateKey) bool {
+	switch key := priv.(type) {
+	case *Key25519:
+		return tag == DH_Tradi || tag == DH_X25519
+	case *ecdh.PrivateKey:
+		return (tag == DH_ECp256 && key.Curve() == ecdh.P256()) ||
+			(tag == DH_ECp384 && key.Curve() == ecdh.P384())
+	case kem.PrivateKey:
+		return tag.IsKEM() && key.Scheme() == kemScheme(tag)
+	}
+	return false
+}
+
 // 创建随机序列（安全）
 // @size 需要的序列长度
 func randomBytes(size int) ([]byte, error) {
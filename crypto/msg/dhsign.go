@@ -0,0 +1,145 @@
+package msg
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+)
+
+// ErrDHSign 密钥交换私钥无法用于签名
+var ErrDHSign = errors.New("the private key cannot be used for signing")
+
+// Sign 用密钥交换私钥签名。
+// 用于证明签名者持有与公钥对应的私钥（如询问者撤销询问）。
+// - X25519：采用 XEdDSA 签名。
+// - NIST 曲线：采用 ECDSA 签名，私钥标量相同。
+// @msg 待签名消息
+// @return 签名数据
+func (dh *DHPack) Sign(msg []byte) ([]byte, error) {
+	switch dh.Algor {
+	case DH_Tradi:
+		fallthrough
+	case DH_X25519:
+		priv, ok := dh.privkey.(*Key25519)
+		if !ok {
+			return nil, ErrDHSign
+		}
+		return xSign(priv[:], msg)
+	case DH_ECp256:
+		fallthrough
+	case DH_ECp384:
+		priv, ok := dh.privkey.(*ecdh.PrivateKey)
+		if !ok {
+			return nil, ErrDHSign
+		}
+		key, err := ecdsaPrivate(priv)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.SignASN1(rand.Reader, key, ecdsaDigest(dh.Algor, msg))
+	}
+	return nil, ErrDHSign
+}
+
+// VerifyDH 用密钥交换公钥验证签名。
+// 与 DHPack.Sign 对应，公钥为对端的密钥交换公钥。
+// 不支持的算法或无效的公钥，简单返回假。
+// @tag 密钥交换算法标识
+// @pub 公钥字节序列
+// @msg 消息
+// @sig 签名数据
+func VerifyDH(tag DHTag, pub, msg, sig []byte) bool {
+	switch tag {
+	case DH_Tradi:
+		fallthrough
+	case DH_X25519:
+		return xVerify(pub, msg, sig)
+	case DH_ECp256:
+		fallthrough
+	case DH_ECp384:
+		key := ecdsaPublic(tag, pub)
+		if key == nil {
+			return false
+		}
+		return ecdsa.VerifyASN1(key, ecdsaDigest(tag, msg), sig)
+	}
+	return false
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 目标算法对应的椭圆曲线
+func ecdsaCurve(tag DHTag) elliptic.Curve {
+	switch tag {
+	case DH_ECp256:
+		return elliptic.P256()
+	case DH_ECp384:
+		return elliptic.P384()
+	}
+	return nil
+}
+
+// 消息摘要，强度与曲线匹配。
+func ecdsaDigest(tag DHTag, msg []byte) []byte {
+	if tag == DH_ECp384 {
+		sum := sha512.Sum384(msg)
+		return sum[:]
+	}
+	sum := sha256.Sum256(msg)
+	return sum[:]
+}
+
+// 从 ECDH 私钥构造 ECDSA 私钥。
+func ecdsaPrivate(priv *ecdh.PrivateKey) (*ecdsa.PrivateKey, error) {
+	var tag DHTag
+
+	switch priv.Curve() {
+	case ecdh.P256():
+		tag = DH_ECp256
+	case ecdh.P384():
+		tag = DH_ECp384
+	default:
+		return nil, ErrDHSign
+	}
+	pub := ecdsaPublic(tag, priv.PublicKey().Bytes())
+	if pub == nil {
+		return nil, ErrDHSign
+	}
+	return &ecdsa.PrivateKey{
+		PublicKey: *pub,
+		D:         new(big.Int).SetBytes(priv.Bytes()),
+	}, nil
+}
+
+// 从未压缩编码的公钥（0x04||X||Y）构造 ECDSA 公钥。
+// 编码无效或点不在曲线上时返回nil。
+func ecdsaPublic(tag DHTag, pub []byte) *ecdsa.PublicKey {
+	curve := ecdsaCurve(tag)
+	if curve == nil {
+		return nil
+	}
+	// 借助 ecdh 完成编码和曲线检查
+	var err error
+	if tag == DH_ECp256 {
+		_, err = ecdh.P256().NewPublicKey(pub)
+	} else {
+		_, err = ecdh.P384().NewPublicKey(pub)
+	}
+	if err != nil {
+		return nil
+	}
+	n := (len(pub) - 1) / 2
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(pub[1 : 1+n]),
+		Y:     new(big.Int).SetBytes(pub[1+n:]),
+	}
+}
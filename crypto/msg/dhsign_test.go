@@ -0,0 +1,64 @@
+package msg_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+)
+
+func TestSignDH(t *testing.T) {
+	m := []byte("depots:cancel")
+
+	for _, tag := range msg.DHTags {
+		key, err := msg.GenerateKey(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dh := msg.NewDHPack(tag, key)
+
+		sig, err := dh.Sign(m)
+		if errors.Is(err, msg.ErrDHSign) {
+			// 密钥封装算法不可签名
+			continue
+		}
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		pub := dh.PublicBytes()
+
+		if !msg.VerifyDH(tag, pub, m, sig) {
+			t.Errorf("algor %d: valid signature rejected", tag)
+		}
+		bad := bytes.Clone(sig)
+		bad[len(bad)/2] ^= 1
+
+		if msg.VerifyDH(tag, pub, m, bad) || msg.VerifyDH(tag, pub, []byte("depots:cancelx"), sig) {
+			t.Errorf("algor %d: forged signature accepted", tag)
+		}
+		other, err := msg.GenerateKey(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.VerifyDH(tag, msg.NewDHPack(tag, other).PublicBytes(), m, sig) {
+			t.Errorf("algor %d: signature accepted for another key", tag)
+		}
+		if msg.VerifyDH(tag, pub[1:], m, sig) || msg.VerifyDH(tag, nil, m, sig) {
+			t.Errorf("algor %d: bad public key accepted", tag)
+		}
+	}
+	// 曲线不符
+	key, err := msg.GenerateKey(msg.DH_ECp256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dh := msg.NewDHPack(msg.DH_ECp256, key)
+	sig, err := dh.Sign(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.VerifyDH(msg.DH_ECp384, dh.PublicBytes(), m, sig) || msg.VerifyDH(msg.DH_X25519, dh.PublicBytes(), m, sig) {
+		t.Error("signature accepted under another algorithm")
+	}
+}
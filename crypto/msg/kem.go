@@ -0,0 +1,139 @@
+package msg
+
+import (
+	"errors"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/hybrid"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cxio/findings/crypto/utilx"
+)
+
+var (
+	// ErrKEM 密钥封装算法不能直接构造共享密钥
+	ErrKEM = errors.New("kem algorithm requires encapsulation")
+
+	// ErrCiphertext 密钥封装密文无效
+	ErrCiphertext = errors.New("invalid kem ciphertext")
+)
+
+// IsKEM 是否为密钥封装算法。
+// 密钥封装算法只有询问者持有私钥，回复者向询问者的公钥封装一个共享密钥，
+// 回复中需要携带封装密文（而非回复者的公钥）。
+func (t DHTag) IsKEM() bool {
+	return t == DH_MLKEM768 || t == DH_X25519MLKEM768
+}
+
+// Seal 向对端加密消息。
+// - 密钥交换算法：以本方私钥和对端公钥构造共享密钥，封装密文为nil。
+// - 密钥封装算法：向对端公钥封装一个共享密钥，返回封装密文。
+// 共享密钥会被哈希（SHA3:256）一次后使用，采用 cipher.GCM 算法加密。
+// @public 对端公钥序列
+// @msg    待加密消息
+// @return1 封装密文（KEM），或nil
+// @return2 加密后的密文
+func (dh *DHPack) Seal(public []byte, msg []byte) ([]byte, []byte, error) {
+	if !dh.Algor.IsKEM() {
+		xdata, err := dh.Encrypt(public, msg)
+		return nil, xdata, err
+	}
+	ct, key, err := encapsulate(dh.Algor, public)
+	if err != nil {
+		return nil, nil, err
+	}
+	xdata, err := utilx.Encrypt(msg, (*[32]byte)(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ct, xdata, nil
+}
+
+// Open 解密对端的消息。
+// 与 Seal 对应，密钥交换算法使用对端公钥，密钥封装算法使用封装密文。
+// @public 对端公钥序列（KEM时忽略）
+// @ct     封装密文（非KEM时忽略）
+// @data   待解密数据
+func (dh *DHPack) Open(public, ct, data []byte) ([]byte, error) {
+	if !dh.Algor.IsKEM() {
+		return dh.Decrypt(public, data)
+	}
+	key, err := dh.decapsulate(ct)
+	if err != nil {
+		return nil, err
+	}
+	return utilx.Decrypt(data, (*[32]byte)(key))
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 获取密钥封装方案。
+// 混合模式的共享密钥由两部分共同决定，任一算法未被攻破即可保密。
+// 非密钥封装算法返回nil。
+func kemScheme(tag DHTag) kem.Scheme {
+	switch tag {
+	case DH_MLKEM768:
+		return mlkem768.Scheme()
+	case DH_X25519MLKEM768:
+		return hybrid.X25519MLKEM768()
+	}
+	return nil
+}
+
+// 提取密钥封装公钥。
+func kemPublic(priv kem.PrivateKey) []byte {
+	buf, err := priv.Public().MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return buf
+}
+
+// 向对端公钥封装共享密钥。
+// 注：共享密钥会经过一层哈希封装（SHA3:256）。
+// @tag    算法标识
+// @public 对端公钥的字节序列
+// @return1 封装密文
+// @return2 共享密钥
+func encapsulate(tag DHTag, public []byte) ([]byte, *Secret, error) {
+	sch := kemScheme(tag)
+	if sch == nil {
+		return nil, nil, ErrAlgor
+	}
+	if len(public) != sch.PublicKeySize() {
+		return nil, nil, ErrKeylen
+	}
+
+	pub, err := sch.UnmarshalBinaryPublicKey(public)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, ss, err := sch.Encapsulate(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ct, Hash256sha3(ss), nil
+}
+
+// 解封共享密钥。
+// 注：共享密钥会经过一层哈希封装（SHA3:256）。
+// @ct 封装密文
+func (dh *DHPack) decapsulate(ct []byte) (*Secret, error) {
+	sch := kemScheme(dh.Algor)
+	if sch == nil {
+		return nil, ErrAlgor
+	}
+	if len(ct) != sch.CiphertextSize() {
+		return nil, ErrCiphertext
+	}
+	priv, ok := dh.privkey.(kem.PrivateKey)
+	if !ok {
+		return nil, ErrNoKey
+	}
+	ss, err := sch.Decapsulate(priv, ct)
+	if err != nil {
+		return nil, err
+	}
+	return Hash256sha3(ss), nil
+}
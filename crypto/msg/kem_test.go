@@ -0,0 +1,99 @@
+package msg_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// 密钥封装算法
+var kemTags = []msg.DHTag{msg.DH_MLKEM768, msg.DH_X25519MLKEM768}
+
+func TestKEMSeal(t *testing.T) {
+	m := []byte("depots contact")
+
+	for _, tag := range kemTags {
+		if !tag.IsKEM() {
+			t.Fatalf("algor %d: not a kem", tag)
+		}
+		quest := newDH(t, tag)
+
+		// 回复者无私钥，只向询问者的公钥封装
+		reply := msg.NewDHPack(tag, nil)
+		if reply.PublicBytes() != nil {
+			t.Fatalf("algor %d: encapsulator has a public key", tag)
+		}
+		ct, data, err := reply.Seal(quest.PublicBytes(), m)
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		// 两次封装的密文不同
+		ct2, _, err := reply.Seal(quest.PublicBytes(), m)
+		if err != nil || bytes.Equal(ct, ct2) {
+			t.Errorf("algor %d: ciphertext reused", tag)
+		}
+		got, err := quest.Open(nil, ct, data)
+		if err != nil || !bytes.Equal(got, m) {
+			t.Fatalf("algor %d: open: %v", tag, err)
+		}
+		// 其它私钥解封出不同的共享密钥
+		if _, err = newDH(t, tag).Open(nil, ct, data); err == nil {
+			t.Errorf("algor %d: opened with a wrong key", tag)
+		}
+		// 截短或加长的封装密文
+		if _, err = quest.Open(nil, ct[:len(ct)-1], data); !errors.Is(err, msg.ErrCiphertext) {
+			t.Errorf("algor %d: truncated ciphertext: %v", tag, err)
+		}
+		if _, err = quest.Open(nil, append(ct, 0), data); !errors.Is(err, msg.ErrCiphertext) {
+			t.Errorf("algor %d: extended ciphertext: %v", tag, err)
+		}
+		if _, err = quest.Open(nil, nil, data); !errors.Is(err, msg.ErrCiphertext) {
+			t.Errorf("algor %d: missing ciphertext: %v", tag, err)
+		}
+		// 篡改的封装密文或密文
+		bad := bytes.Clone(ct)
+		bad[0] ^= 1
+		if _, err = quest.Open(nil, bad, data); err == nil {
+			t.Errorf("algor %d: tampered ciphertext accepted", tag)
+		}
+		bad = bytes.Clone(data)
+		bad[len(bad)-1] ^= 1
+		if _, err = quest.Open(nil, ct, bad); err == nil {
+			t.Errorf("algor %d: tampered data accepted", tag)
+		}
+		// 无私钥时无法解封
+		if _, err = reply.Open(nil, ct, data); !errors.Is(err, msg.ErrNoKey) {
+			t.Errorf("algor %d: open without key: %v", tag, err)
+		}
+		// 对端公钥长度错误
+		if _, _, err = reply.Seal(quest.PublicBytes()[1:], m); !errors.Is(err, msg.ErrKeylen) {
+			t.Errorf("algor %d: short public key: %v", tag, err)
+		}
+	}
+}
+
+func TestKEMMismatch(t *testing.T) {
+	mlkem, hybrid := newDH(t, msg.DH_MLKEM768), newDH(t, msg.DH_X25519MLKEM768)
+
+	// 两种算法的公钥和封装密文互不相容
+	if _, _, err := msg.NewDHPack(msg.DH_X25519MLKEM768, nil).Seal(mlkem.PublicBytes(), []byte("x")); !errors.Is(err, msg.ErrKeylen) {
+		t.Errorf("mlkem key for hybrid: %v", err)
+	}
+	ct, data, err := msg.NewDHPack(msg.DH_MLKEM768, nil).Seal(mlkem.PublicBytes(), []byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = hybrid.Open(nil, ct, data); !errors.Is(err, msg.ErrCiphertext) {
+		t.Errorf("mlkem ciphertext for hybrid: %v", err)
+	}
+	// 私钥与算法不符
+	key, err := msg.GenerateKey(msg.DH_MLKEM768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.NewDHPack(msg.DH_X25519MLKEM768, key) != nil || msg.NewDHPack(msg.DH_X25519, key) != nil {
+		t.Error("kem key accepted by another algorithm")
+	}
+}
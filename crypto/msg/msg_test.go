@@ -0,0 +1,209 @@
+package msg_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// 不支持的算法标识
+const (
+	badDH   = msg.DHTag(99)
+	badSign = msg.SignTag(99)
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, tag := range msg.DHTags {
+		key, err := msg.GenerateKey(tag)
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		if msg.NewDHPack(tag, key) == nil {
+			t.Errorf("algor %d: key does not match the algorithm", tag)
+		}
+	}
+	if _, err := msg.GenerateKey(badDH); !errors.Is(err, msg.ErrAlgor) {
+		t.Errorf("bad algor: %v", err)
+	}
+	if _, err := msg.GenerateSignKey(badSign); !errors.Is(err, msg.ErrAlgor) {
+		t.Errorf("bad sign algor: %v", err)
+	}
+}
+
+func TestParseDHKey(t *testing.T) {
+	for _, tag := range msg.DHTags {
+		key, err := msg.GenerateKey(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dh := msg.NewDHPack(tag, key)
+		data, err := dh.PrivateBytes()
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		back, err := msg.ParseDHKey(tag, data)
+		if err != nil {
+			t.Fatalf("algor %d: parse: %v", tag, err)
+		}
+		if !bytes.Equal(msg.NewDHPack(tag, back).PublicBytes(), dh.PublicBytes()) {
+			t.Errorf("algor %d: parsed key mismatch", tag)
+		}
+		if _, err = msg.ParseDHKey(tag, data[:len(data)-1]); err == nil {
+			t.Errorf("algor %d: short key accepted", tag)
+		}
+	}
+	if _, err := msg.ParseDHKey(msg.DH_X25519, make([]byte, 31)); !errors.Is(err, msg.ErrKeylen) {
+		t.Errorf("x25519 short key: %v", err)
+	}
+	if _, err := msg.ParseDHKey(badDH, make([]byte, 32)); !errors.Is(err, msg.ErrAlgor) {
+		t.Errorf("bad algor: %v", err)
+	}
+	if _, err := msg.ParseSignKey(msg.SIGN_ED25519, make([]byte, 31)); !errors.Is(err, msg.ErrKeylen) {
+		t.Errorf("ed25519 short seed: %v", err)
+	}
+}
+
+func TestKeyEqual(t *testing.T) {
+	a, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.NewDHPack(msg.DH_X25519, a).PrivateBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := msg.ParseDHKey(msg.DH_X25519, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := a.(*msg.Key25519)
+
+	if !k.Equal(a) || !k.Equal(back) {
+		t.Error("equal keys differ")
+	}
+	// 其它密钥或其它类型
+	p256, _ := msg.GenerateKey(msg.DH_ECp256)
+	if k.Equal(b) || k.Equal(p256) || k.Equal(data) || k.Equal(nil) {
+		t.Error("different keys equal")
+	}
+}
+
+func TestSharedKey(t *testing.T) {
+	for _, tag := range []msg.DHTag{msg.DH_X25519, msg.DH_ECp256, msg.DH_ECp384} {
+		a := newDH(t, tag)
+		b := newDH(t, tag)
+
+		ka, err := a.SharedKey(b.PublicBytes())
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		kb, err := b.SharedKey(a.PublicBytes())
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		if *ka != *kb {
+			t.Errorf("algor %d: shared keys differ", tag)
+		}
+		if _, err = a.SharedKey(b.PublicBytes()[1:]); err == nil {
+			t.Errorf("algor %d: short public key accepted", tag)
+		}
+		if _, err = msg.NewDHPack(tag, nil).SharedKey(b.PublicBytes()); !errors.Is(err, msg.ErrNoKey) {
+			t.Errorf("algor %d: no private key: %v", tag, err)
+		}
+	}
+	a := newDH(t, msg.DH_X25519)
+
+	// 零点为 low-order point
+	if _, err := a.SharedKey(make([]byte, 32)); !errors.Is(err, msg.ErrShared) {
+		t.Errorf("low-order point: %v", err)
+	}
+	if _, err := a.SharedKey(nil); !errors.Is(err, msg.ErrKeylen) {
+		t.Errorf("empty public key: %v", err)
+	}
+	k := newDH(t, msg.DH_MLKEM768)
+
+	if _, err := k.SharedKey(k.PublicBytes()); !errors.Is(err, msg.ErrKEM) {
+		t.Errorf("kem shared key: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m := []byte("depots")
+
+	for _, tag := range msg.SignTags {
+		key, err := msg.GenerateSignKey(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sp := msg.NewSignPack(tag, key)
+		sig, err := sp.Sign(m)
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		pub := sp.PublicBytes()
+
+		if !sp.Verify(pub, m, sig) {
+			t.Errorf("algor %d: valid signature rejected", tag)
+		}
+		if sp.Verify(pub[1:], m, sig) || sp.Verify(pub, m, sig[1:]) || sp.Verify(nil, m, nil) {
+			t.Errorf("algor %d: bad length accepted", tag)
+		}
+		bad := bytes.Clone(sig)
+		bad[len(bad)/2] ^= 1
+
+		if sp.Verify(pub, m, bad) || sp.Verify(pub, []byte("depot"), sig) {
+			t.Errorf("algor %d: forged signature accepted", tag)
+		}
+		if _, err = msg.NewSignPack(tag, nil).Sign(m); !errors.Is(err, msg.ErrNoKey) {
+			t.Errorf("algor %d: sign without key: %v", tag, err)
+		}
+	}
+	if msg.NewSignPack(badSign, nil) != nil {
+		t.Error("bad algor pack created")
+	}
+}
+
+func FuzzVerify(f *testing.F) {
+	for _, tag := range msg.SignTags {
+		f.Add(int(tag), make([]byte, tag.PublicSize()), []byte("m"), make([]byte, tag.SignatureSize()))
+	}
+	f.Fuzz(func(t *testing.T, tag int, pub, m, sig []byte) {
+		sp := msg.NewSignPack(msg.SignTag(tag), nil)
+		if sp == nil {
+			return
+		}
+		sp.Verify(pub, m, sig)
+	})
+}
+
+func FuzzVerifyDH(f *testing.F) {
+	for _, tag := range msg.DHTags {
+		f.Add(int(tag), make([]byte, 32), []byte("m"), make([]byte, 64))
+	}
+	f.Fuzz(func(t *testing.T, tag int, pub, m, sig []byte) {
+		msg.VerifyDH(msg.DHTag(tag), pub, m, sig)
+
+		// 外来公钥同样进入共享密钥的构造
+		if dh := msg.NewDHPack(msg.DHTag(tag), nil); dh != nil {
+			dh.SharedKey(pub)
+			dh.Open(pub, sig, m)
+		}
+	})
+}
+
+// 创建一个随机密钥的密钥交换包。
+func newDH(t *testing.T, tag msg.DHTag) *msg.DHPack {
+	t.Helper()
+
+	key, err := msg.GenerateKey(tag)
+	if err != nil {
+		t.Fatalf("algor %d: %v", tag, err)
+	}
+	return msg.NewDHPack(tag, key)
+}
@@ -3,19 +3,64 @@ package msg
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
 )
 
 // SignTag 签名算法标识
 type SignTag int
 
 // 几个常用签名算法
-// 注：当前仅定义了ed25519。
 const (
 	SIGN_Tradi   SignTag = iota // 惯用签名算法（ed25519）
 	SIGN_ED25519                // ed25519 签名
+	SIGN_ED448                  // ed448 签名
+	SIGN_SCHNORR                // secp256k1 Schnorr 签名（BIP-340）
+	SIGN_MLDSA65                // ML-DSA-65 签名（抗量子）
 )
 
+// SignTags 已支持的签名算法清单。
+// 用于对外声明能力（握手协商）。
+var SignTags = []SignTag{SIGN_Tradi, SIGN_ED25519, SIGN_ED448, SIGN_SCHNORR, SIGN_MLDSA65}
+
+// PublicSize 公钥长度。
+// 未知算法返回0。
+func (t SignTag) PublicSize() int {
+	switch t {
+	case SIGN_Tradi, SIGN_ED25519:
+		return ed25519.PublicKeySize
+	case SIGN_ED448:
+		return ed448.PublicKeySize
+	case SIGN_SCHNORR:
+		return schnorr.PubKeyBytesLen
+	case SIGN_MLDSA65:
+		return mldsa65.PublicKeySize
+	}
+	return 0
+}
+
+// SignatureSize 签名长度。
+// 未知算法返回0。
+func (t SignTag) SignatureSize() int {
+	switch t {
+	case SIGN_Tradi, SIGN_ED25519:
+		return ed25519.SignatureSize
+	case SIGN_ED448:
+		return ed448.SignatureSize
+	case SIGN_SCHNORR:
+		return schnorr.SignatureSize
+	case SIGN_MLDSA65:
+		return mldsa65.SignatureSize
+	}
+	return 0
+}
+
 // GenerateSignKey 创建签名用私钥
+// 不支持的算法返回 ErrAlgor。
 // @tag 签名算法标识
 func GenerateSignKey(tag SignTag) (PrivateKey, error) {
 	switch tag {
@@ -24,8 +69,50 @@ func GenerateSignKey(tag SignTag) (PrivateKey, error) {
 	case SIGN_ED25519:
 		_, priv, err := ed25519.GenerateKey(rand.Reader)
 		return priv, err
+	case SIGN_ED448:
+		_, priv, err := ed448.GenerateKey(rand.Reader)
+		return priv, err
+	case SIGN_SCHNORR:
+		return btcec.NewPrivateKey()
+	case SIGN_MLDSA65:
+		_, priv, err := mldsa65.GenerateKey(rand.Reader)
+		return priv, err
 	}
-	panic(failAlgor)
+	return nil, ErrAlgor
+}
+
+// ParseSignKey 从字节序列恢复签名私钥
+// 与 SignPack.PrivateBytes 对应，用于密钥的持久存储。
+// @tag  签名算法标识
+// @data 私钥字节序列（ed25519/ed448为种子，ML-DSA为完整私钥）
+func ParseSignKey(tag SignTag, data []byte) (PrivateKey, error) {
+	switch tag {
+	case SIGN_Tradi:
+		fallthrough
+	case SIGN_ED25519:
+		if len(data) != ed25519.SeedSize {
+			return nil, ErrKeylen
+		}
+		return ed25519.NewKeyFromSeed(data), nil
+	case SIGN_ED448:
+		if len(data) != ed448.SeedSize {
+			return nil, ErrKeylen
+		}
+		return ed448.NewKeyFromSeed(data), nil
+	case SIGN_SCHNORR:
+		if len(data) != btcec.PrivKeyBytesLen {
+			return nil, ErrKeylen
+		}
+		priv, _ := btcec.PrivKeyFromBytes(data)
+		return priv, nil
+	case SIGN_MLDSA65:
+		priv := &mldsa65.PrivateKey{}
+		if err := priv.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return priv, nil
+	}
+	return nil, ErrAlgor
 }
 
 // SignPack 签名封包
@@ -35,7 +122,7 @@ type SignPack struct {
 }
 
 // NewSignPack 创建一个签名封包
-// 只有被支持的算法才能创建实例，否则返回nil。
+// 只有被支持的算法才能创建实例，私钥（如果有）也需与算法匹配，否则返回nil。
 // 注：
 // 如果只是用于验证（Verify），priv可以为nil。
 func NewSignPack(tag SignTag, priv PrivateKey) *SignPack {
@@ -44,51 +131,140 @@ func NewSignPack(tag SignTag, priv PrivateKey) *SignPack {
 		// tag = SIGN_ED25519
 	case SIGN_ED25519:
 		// 占位即可
+	case SIGN_ED448:
+	case SIGN_SCHNORR:
+	case SIGN_MLDSA65:
 	default:
 		return nil
 	}
+	if priv != nil && !signKeyOK(tag, priv) {
+		return nil
+	}
 	return &SignPack{Algor: tag, private: priv}
 }
 
 // Sign 签名消息。
+// Schnorr 签名的是消息的 SHA-256 摘要，ML-DSA 采用随机化签名。
+// 私钥缺失或与算法不匹配时返回 ErrNoKey。
 // @msg 待签名的消息
 // @return 签名数据
-func (sp *SignPack) Sign(msg []byte) []byte {
-	switch sp.Algor {
-	case SIGN_Tradi:
-		fallthrough
-	case SIGN_ED25519:
-		priv := sp.private.(ed25519.PrivateKey)
-		return ed25519.Sign(priv, msg)
+func (sp *SignPack) Sign(msg []byte) ([]byte, error) {
+	switch priv := sp.private.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(priv, msg), nil
+	case ed448.PrivateKey:
+		return ed448.Sign(priv, msg, ""), nil
+	case *btcec.PrivateKey:
+		hash := sha256.Sum256(msg)
+		sig, err := schnorr.Sign(priv, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		return sig.Serialize(), nil
+	case *mldsa65.PrivateKey:
+		sig := make([]byte, mldsa65.SignatureSize)
+		if err := mldsa65.SignTo(priv, msg, nil, true, sig); err != nil {
+			return nil, err
+		}
+		return sig, nil
 	}
-	panic(failAlgor)
+	return nil, ErrNoKey
 }
 
 // Verify 验证消息是否合法
 // 仅验证消息时，构造SignPack仅需传递算法标识，
 // 公钥为外来数据，无需私钥信息。
-// @alg 算法标识
+// 公钥和签名的长度与算法不符时直接返回假，不会引发恐慌。
 // @pub 公钥数据
 // @msg 验证的消息
 // @sig 签名数据（待验证目标）
 func (sp *SignPack) Verify(pub []byte, msg, sig []byte) bool {
+	if len(pub) != sp.Algor.PublicSize() ||
+		len(sig) != sp.Algor.SignatureSize() {
+		return false
+	}
 	switch sp.Algor {
 	case SIGN_Tradi:
 		fallthrough
 	case SIGN_ED25519:
 		return ed25519.Verify(ed25519.PublicKey(pub), msg, sig)
+	case SIGN_ED448:
+		return ed448.Verify(ed448.PublicKey(pub), msg, sig, "")
+	case SIGN_SCHNORR:
+		return verifySchnorr(pub, msg, sig)
+	case SIGN_MLDSA65:
+		key := &mldsa65.PublicKey{}
+		if key.UnmarshalBinary(pub) != nil {
+			return false
+		}
+		return mldsa65.Verify(key, msg, nil, sig)
 	}
-	panic(failAlgor)
+	return false
 }
 
 // PublicBytes 提取公钥字节序列。
+// 私钥缺失或与算法不匹配时返回nil。
 func (sp *SignPack) PublicBytes() []byte {
-	switch sp.Algor {
-	case SIGN_Tradi:
-		fallthrough
-	case SIGN_ED25519:
-		priv := sp.private.(ed25519.PrivateKey)
+	switch priv := sp.private.(type) {
+	case ed25519.PrivateKey:
 		return []byte(priv.Public().(ed25519.PublicKey))
+	case ed448.PrivateKey:
+		return []byte(priv.Public().(ed448.PublicKey))
+	case *btcec.PrivateKey:
+		return schnorr.SerializePubKey(priv.PubKey())
+	case *mldsa65.PrivateKey:
+		return priv.Public().(*mldsa65.PublicKey).Bytes()
+	}
+	return nil
+}
+
+// PrivateBytes 提取私钥字节序列。
+// 用于密钥的持久存储，ed25519/ed448 仅提取种子。
+func (sp *SignPack) PrivateBytes() ([]byte, error) {
+	switch priv := sp.private.(type) {
+	case ed25519.PrivateKey:
+		return priv.Seed(), nil
+	case ed448.PrivateKey:
+		return priv.Seed(), nil
+	case *btcec.PrivateKey:
+		return priv.Serialize(), nil
+	case *mldsa65.PrivateKey:
+		return priv.Bytes(), nil
+	}
+	return nil, ErrNoKey
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 私钥是否与算法匹配。
+// ed25519/ed448 私钥的长度也需正确。
+func signKeyOK(tag SignTag, priv PrivateKey) bool {
+	switch key := priv.(type) {
+	case ed25519.PrivateKey:
+		return (tag == SIGN_Tradi || tag == SIGN_ED25519) && len(key) == ed25519.PrivateKeySize
+	case ed448.PrivateKey:
+		return tag == SIGN_ED448 && len(key) == ed448.PrivateKeySize
+	case *btcec.PrivateKey:
+		return tag == SIGN_SCHNORR
+	case *mldsa65.PrivateKey:
+		return tag == SIGN_MLDSA65
+	}
+	return false
+}
+
+// 验证 Schnorr 签名（BIP-340）。
+// 签名的是消息的 SHA-256 摘要，公钥为32字节的x坐标。
+func verifySchnorr(pub, msg, sig []byte) bool {
+	key, err := schnorr.ParsePubKey(pub)
+	if err != nil {
+		return false
+	}
+	s, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return false
 	}
-	panic(failAlgor)
+	hash := sha256.Sum256(msg)
+	return s.Verify(hash[:], key)
 }
@@ -0,0 +1,138 @@
+package msg
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+)
+
+// XEdDSA 签名（X25519密钥）
+// ---------------------------
+// 参考 Signal 的 XEdDSA 规范，使得密钥交换用的 X25519 私钥也可用于签名，
+// 验证方只需要对方的 X25519 公钥。
+// 询问者用此证明自己持有询问包中公钥对应的私钥（如撤销询问）。
+
+// XSignSize XEdDSA 签名长度
+const XSignSize = 64
+
+// hash1 前缀：2^256-2 的小端序
+var xPrefix1 = func() []byte {
+	buf := bytes.Repeat([]byte{0xff}, 32)
+	buf[0] = 0xfe
+	return buf
+}()
+
+// 由 X25519 私钥计算 Edwards 密钥对。
+// 返回的公钥符号位强制为零，私钥标量相应取负。
+// @k X25519 私钥（原始32字节，内部钳制）
+// @return1 Edwards 公钥编码
+// @return2 私钥标量
+func xKeyPair(k []byte) ([]byte, *edwards25519.Scalar, error) {
+	a, err := edwards25519.NewScalar().SetBytesWithClamping(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := new(edwards25519.Point).ScalarBaseMult(a).Bytes()
+
+	if pub[31]&0x80 != 0 {
+		a.Negate(a)
+		pub[31] &= 0x7f
+	}
+	return pub, a, nil
+}
+
+// xSign XEdDSA 签名
+// @k   X25519 私钥
+// @msg 待签名消息
+func xSign(k []byte, msg []byte) ([]byte, error) {
+	pub, a, err := xKeyPair(k)
+	if err != nil {
+		return nil, err
+	}
+	z, err := randomBytes(64)
+	if err != nil {
+		return nil, err
+	}
+	// r = hash1(a || M || Z)
+	h := sha512.New()
+	h.Write(xPrefix1)
+	h.Write(a.Bytes())
+	h.Write(msg)
+	h.Write(z)
+	r, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	// s = r + hash(R || A || M) * a
+	c, err := xChallenge(R, pub, msg)
+	if err != nil {
+		return nil, err
+	}
+	s := edwards25519.NewScalar().MultiplyAdd(c, a, r)
+
+	return append(R, s.Bytes()...), nil
+}
+
+// xVerify XEdDSA 验证
+// @u   X25519 公钥（Montgomery u 坐标）
+// @msg 消息
+// @sig 签名数据
+func xVerify(u []byte, msg, sig []byte) bool {
+	if len(u) != 32 || len(sig) != XSignSize {
+		return false
+	}
+	pub, ok := xMontToEdwards(u)
+	if !ok {
+		return false
+	}
+	A, err := new(edwards25519.Point).SetBytes(pub)
+	if err != nil {
+		return false
+	}
+	R := sig[:32]
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+	c, err := xChallenge(R, pub, msg)
+	if err != nil {
+		return false
+	}
+	// R' = sB - cA
+	A.Negate(A)
+	check := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(c, A, s)
+
+	return bytes.Equal(check.Bytes(), R)
+}
+
+// 挑战值：hash(R || A || M) mod q
+func xChallenge(R, A, msg []byte) (*edwards25519.Scalar, error) {
+	h := sha512.New()
+	h.Write(R)
+	h.Write(A)
+	h.Write(msg)
+	return edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+}
+
+// Montgomery u 坐标转换为 Edwards 公钥编码（符号位为零）。
+// y = (u - 1) / (u + 1)
+// 非规范编码（u >= p）视为无效。
+func xMontToEdwards(u []byte) ([]byte, bool) {
+	fu, err := new(field.Element).SetBytes(u)
+	if err != nil {
+		return nil, false
+	}
+	if !bytes.Equal(fu.Bytes(), u) {
+		return nil, false
+	}
+	one := new(field.Element).One()
+	num := new(field.Element).Subtract(fu, one)
+	den := new(field.Element).Add(fu, one)
+	y := new(field.Element).Multiply(num, den.Invert(den))
+
+	return y.Bytes(), true
+}
@@ -0,0 +1,139 @@
+package msg
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+	"golang.org/x/crypto/curve25519"
+)
+
+// RFC 8032 的 Ed25519 测试向量（TEST 1、TEST 2）。
+// XEdDSA 的验证与 Ed25519 相同，公钥的 Montgomery 形式须验证通过同一签名。
+var xVectors = []struct {
+	seed, pub, msg, sig string
+}{
+	{
+		seed: "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pub:  "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		msg:  "",
+		sig:  "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		seed: "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		pub:  "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		msg:  "72",
+		sig:  "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+}
+
+// 解码十六进制串。
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Edwards 公钥转换为 Montgomery u 坐标：u = (1 + y) / (1 - y)
+func edwardsToMont(t *testing.T, pub []byte) []byte {
+	t.Helper()
+
+	y := bytes.Clone(pub)
+	y[31] &= 0x7f
+	fy, err := new(field.Element).SetBytes(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := new(field.Element).One()
+	num := new(field.Element).Add(one, fy)
+	den := new(field.Element).Subtract(one, fy)
+
+	return new(field.Element).Multiply(num, den.Invert(den)).Bytes()
+}
+
+func TestXEdDSAVector(t *testing.T) {
+	for i, v := range xVectors {
+		pub, m, sig := unhex(t, v.pub), unhex(t, v.msg), unhex(t, v.sig)
+		u := edwardsToMont(t, pub)
+
+		// Ed25519 私钥的标量即 X25519 私钥
+		h := sha512.Sum512(unhex(t, v.seed))
+		k := h[:32]
+
+		x, err := curve25519.X25519(k, curve25519.Basepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(x, u) {
+			t.Fatalf("vector %d: montgomery key mismatch", i)
+		}
+		if e, ok := xMontToEdwards(u); !ok || !bytes.Equal(e, pub) {
+			t.Fatalf("vector %d: edwards key mismatch", i)
+		}
+		if !xVerify(u, m, sig) {
+			t.Errorf("vector %d: published signature rejected", i)
+		}
+		// 本方的签名可由标准 Ed25519 验证
+		mine, err := xSign(k, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ed25519.Verify(pub, m, mine) || !xVerify(u, m, mine) {
+			t.Errorf("vector %d: own signature rejected", i)
+		}
+		bad := bytes.Clone(sig)
+		bad[0] ^= 1
+		if xVerify(u, m, bad) || xVerify(u, append(m, 0), sig) {
+			t.Errorf("vector %d: forged signature accepted", i)
+		}
+	}
+}
+
+func TestXEdDSASign(t *testing.T) {
+	m := []byte("depots")
+	negated := 0
+
+	// 符号位为1的公钥，私钥标量取负
+	for negated < 4 {
+		k, err := randomBytes(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := edwards25519.NewScalar().SetBytesWithClamping(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if new(edwards25519.Point).ScalarBaseMult(a).Bytes()[31]&0x80 != 0 {
+			negated++
+		}
+		pub, _, err := xKeyPair(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := curve25519.X25519(k, curve25519.Basepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := xSign(k, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !xVerify(u, m, sig) || !ed25519.Verify(pub, m, sig) {
+			t.Fatalf("key %x: signature rejected", k)
+		}
+	}
+	// 非规范的 u 坐标（>= p）
+	u := bytes.Repeat([]byte{0xff}, 32)
+	u[31] = 0x7f
+	if _, ok := xMontToEdwards(u); ok {
+		t.Error("non-canonical u accepted")
+	}
+}
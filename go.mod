@@ -11,8 +11,16 @@ require (
 require github.com/hjson/hjson-go v3.3.0+incompatible
 
 require (
+	filippo.io/edwards25519 v1.1.0
+	github.com/btcsuite/btcd/btcec/v2 v2.3.4
 	github.com/traefik/yaegi v0.16.1
 	github.com/yuin/gopher-lua v1.1.1
 	golang.org/x/crypto v0.29.0
+)
+
+require (
+	github.com/btcsuite/btcd/chaincfg/chainhash v1.0.1 // indirect
+	github.com/decred/dcrd/crypto/blake256 v1.0.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
 	golang.org/x/sys v0.27.0 // indirect
 )
@@ -0,0 +1,461 @@
+// Package limit 询问和探测等数据包的流量限额。
+// 以令牌桶分别限制每个对端节点、每个客户端以及本节点总计的流量，
+// 按包类型计算，也可对包类型与数据类别的组合附加限额。
+// 伪造的数据ID可激发逐级转播和补充浪潮，流量限额防止某个邻居耗尽本节点的上行带宽。
+//
+// 来源按IP计算（IPv6按 /64 网段），同一主机的多个连接共享限额。
+// 超出限额的包被丢弃：
+// - 来源自身的限额不足时丢弃，持续超限时记为流量超限（可交由禁闭管理器惩罚）。
+// - 总计限额拥塞时（余量低于一半），仅放行自身限额余量过半的来源。
+// 流量较大的来源先被丢弃，使总计带宽在各来源间公平分配。
+package limit
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/ban"
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+// Scope 限额范围。
+type Scope int
+
+// 限额范围值
+const (
+	Peer   Scope = iota // 对端节点连接
+	Client              // 客户端
+	Global              // 本节点总计
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Peer:
+		return "peer"
+	case Client:
+		return "client"
+	case Global:
+		return "global"
+	}
+	return fmt.Sprintf("scope(%d)", int(s))
+}
+
+// 包类型名（按标识值索引）
+var typeNames = [...]string{
+	packet.PACKET_PROBE:      "probe",
+	packet.PACKET_QUEST:      "quest",
+	packet.PACKET_REPLY:      "reply",
+	packet.PACKET_HELLO:      "hello",
+	packet.PACKET_ACCEPT:     "accept",
+	packet.PACKET_BATCH:      "batch",
+	packet.PACKET_BATCHREPLY: "batchreply",
+	packet.PACKET_CANCEL:     "cancel",
+	packet.PACKET_REPORT:     "report",
+	packet.PACKET_EXCHANGE:   "exchange",
+	packet.PACKET_CAPS:       "caps",
+}
+
+// 数据类别名（按类别值索引）
+var kindNames = [...]string{
+	packet.KIND_ARCHIVE:    "archive",
+	packet.KIND_BLOCKCHAIN: "blockchain",
+}
+
+// TypeName 返回包类型名。
+// @typ 包类型标识
+func TypeName(typ byte) string {
+	if int(typ) < len(typeNames) {
+		return typeNames[typ]
+	}
+	return fmt.Sprintf("type(%d)", typ)
+}
+
+// KindName 返回数据类别名。
+// @kind 数据类别
+func KindName(kind packet.Kind) string {
+	if int(kind) < len(kindNames) {
+		return kindNames[kind]
+	}
+	return fmt.Sprintf("kind(%d)", kind)
+}
+
+// Stat 流量统计。
+// 放行计入来源的范围，丢弃计入导致丢弃的范围。
+type Stat struct {
+	Scope   Scope  // 限额范围
+	Class   string // 包类型名，或类型与类别的组合名
+	Passed  uint64 // 放行的包数
+	Dropped uint64 // 丢弃的包数
+}
+
+// 统计键
+type statKey struct {
+	scope Scope
+	class string
+}
+
+// 令牌桶
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// 创建一个满额的令牌桶。
+// 零值速率（不限）返回nil。
+func newBucket(r config.Rate, now time.Time) *bucket {
+	if r.Rate <= 0 {
+		return nil
+	}
+	burst := float64(r.Burst)
+	if burst <= 0 {
+		burst = max(r.Rate, 1)
+	}
+	return &bucket{rate: r.Rate, burst: burst, tokens: burst, last: now}
+}
+
+// 补充令牌。
+func (b *bucket) fill(now time.Time) {
+	if d := now.Sub(b.last); d > 0 {
+		b.tokens = min(b.burst, b.tokens+d.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// 来源的限额状态
+type source struct {
+	buckets map[string]*bucket
+	last    time.Time
+	window  time.Time // 超限统计窗口的起始时间
+	over    int       // 窗口内的超限丢弃数
+}
+
+// 记录一次超限丢弃。
+// 窗口内的丢弃数达到 config.LimitExcess 时返回真，每个窗口至多一次。
+func (s *source) excess(now time.Time) bool {
+	if now.Sub(s.window) >= config.LimitWindow {
+		s.window, s.over = now, 0
+	}
+	s.over++
+	return s.over == config.LimitExcess
+}
+
+// 限额是否已恢复满额。
+func (s *source) full(now time.Time) bool {
+	for _, b := range s.buckets {
+		if b != nil {
+			b.fill(now)
+
+			if b.tokens < b.burst {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 来源标识
+type sourceKey struct {
+	scope Scope
+	net   netip.Prefix // 来源IP，IPv6为 /64 网段
+}
+
+// Limiter 流量限额器。
+// 并发安全，由各连接的接收处理共享。
+type Limiter struct {
+	mu      sync.Mutex
+	rates   [3]map[string]config.Rate // 按范围索引的限额配置
+	global  map[string]*bucket
+	sources map[sourceKey]*source
+	stats   map[statKey]*Stat
+	bans    *ban.Manager
+	sweep   time.Time // 上次清理闲置来源的时间
+}
+
+// New 创建流量限额器。
+// 未配置的包类型采用默认限额（config.Limit*），组合键仅在配置时生效。
+// @conf 限额配置，可为nil
+func New(conf *config.Limits) *Limiter {
+	if conf == nil {
+		conf = &config.Limits{}
+	}
+	l := &Limiter{
+		global:  make(map[string]*bucket),
+		sources: make(map[sourceKey]*source),
+		stats:   make(map[statKey]*Stat),
+		sweep:   time.Now(),
+	}
+	l.rates[Peer] = rates(conf.Peer, config.LimitPeerRate, config.LimitPeerBurst)
+	l.rates[Client] = rates(conf.Client, config.LimitClientRate, config.LimitClientBurst)
+	l.rates[Global] = rates(conf.Global, config.LimitGlobalRate, config.LimitGlobalBurst)
+
+	return l
+}
+
+// Guard 设置禁闭管理器。
+// 对端节点持续超出自身限额时（统计窗口 config.LimitWindow 内的丢弃达到 config.LimitExcess），
+// 被记为一次流量超限（ban.Flood），每个窗口至多一次。
+// 客户端通常为本地应用，不予惩罚。
+// @b 禁闭管理器
+func (l *Limiter) Guard(b *ban.Manager) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bans = b
+}
+
+// Allow 检查一个数据包是否放行。
+// 适用于没有数据类别的包（如回复、撤销）。
+// @scope 来源范围（Peer|Client）
+// @from  来源地址
+// @typ   包类型标识
+func (l *Limiter) Allow(scope Scope, from netip.AddrPort, typ byte) bool {
+	return l.allow(scope, from, TypeName(typ))
+}
+
+// AllowKind 检查一个数据包是否放行。
+// 适用于有数据类别的包（探测、询问、批量询问），组合键的限额附加于类型限额之上。
+// @scope 来源范围（Peer|Client）
+// @from  来源地址
+// @typ   包类型标识
+// @kind  数据类别
+func (l *Limiter) AllowKind(scope Scope, from netip.AddrPort, typ byte, kind packet.Kind) bool {
+	name := TypeName(typ)
+	return l.allow(scope, from, name, name+"."+KindName(kind))
+}
+
+// Forget 清除一个来源的限额状态。
+// 应当在连接关闭时调用，闲置的来源也会被定期清理。
+// 来源的限额由同一IP的各连接共享，仅在已恢复满额时清除，以免借重连规避限额。
+// @scope 来源范围
+// @from  来源地址
+func (l *Limiter) Forget(scope Scope, from netip.AddrPort) {
+	k := sourceKey{scope, ban.Group(from.Addr())}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if src := l.sources[k]; src != nil && src.full(time.Now()) {
+		delete(l.sources, k)
+	}
+}
+
+// Stats 返回流量统计。
+// 按范围和类别名排序。
+func (l *Limiter) Stats() []Stat {
+	l.mu.Lock()
+	list := make([]Stat, 0, len(l.stats))
+
+	for _, s := range l.stats {
+		list = append(list, *s)
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(list, func(a, b Stat) int {
+		if a.Scope != b.Scope {
+			return int(a.Scope) - int(b.Scope)
+		}
+		return strings.Compare(a.Class, b.Class)
+	})
+	return list
+}
+
+// Dropped 返回丢弃的总包数。
+func (l *Limiter) Dropped() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n uint64
+	for _, s := range l.stats {
+		n += s.Dropped
+	}
+	return n
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 检查并扣减限额。
+// 所有相关的令牌桶均有余量时才扣减，否则丢弃并统计。
+// @classes 类别名清单（类型名，及可选的组合名）
+func (l *Limiter) allow(scope Scope, from netip.AddrPort, classes ...string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	l.prune(now)
+
+	// 按类别对应，不限的类别为nil
+	src := l.source(scope, from, now)
+	own := make([]*bucket, len(classes))
+	all := make([]*bucket, len(classes))
+
+	for i, c := range classes {
+		own[i] = l.bucket(src.buckets, scope, c, now)
+		all[i] = l.bucket(l.global, Global, c, now)
+	}
+	// 自身限额
+	for i, b := range own {
+		if b != nil && b.tokens < 1 {
+			l.count(scope, classes[i], false)
+			fine := scope == Peer && src.excess(now)
+			bans := l.bans
+			l.mu.Unlock()
+
+			if fine && bans != nil {
+				bans.Penalize(from.Addr(), ban.Flood)
+			}
+			return false
+		}
+	}
+	// 总计限额，拥塞时仅放行轻量来源
+	for i, b := range all {
+		if b != nil && (b.tokens < 1 || b.tokens < b.burst/2 && !light(own)) {
+			l.count(Global, classes[i], false)
+			l.mu.Unlock()
+			return false
+		}
+	}
+	for i := range classes {
+		take(own[i])
+		take(all[i])
+	}
+	for _, c := range classes {
+		l.count(scope, c, true)
+	}
+	l.mu.Unlock()
+
+	return true
+}
+
+// 获取或创建来源的限额状态。
+// 来源数达到上限（config.LimitSources）时，淘汰最久未活动的来源。
+// 调用者需持有锁。
+func (l *Limiter) source(scope Scope, from netip.AddrPort, now time.Time) *source {
+	k := sourceKey{scope, ban.Group(from.Addr())}
+	src := l.sources[k]
+
+	if src == nil {
+		if len(l.sources) >= config.LimitSources {
+			l.evict()
+		}
+		src = &source{buckets: make(map[string]*bucket)}
+		l.sources[k] = src
+	}
+	src.last = now
+	return src
+}
+
+// 获取或创建令牌桶，并补充令牌。
+// 未配置（组合键）或不限的类别返回nil。
+// 调用者需持有锁。
+func (l *Limiter) bucket(set map[string]*bucket, scope Scope, class string, now time.Time) *bucket {
+	b, ok := set[class]
+	if !ok {
+		b = newBucket(l.rate(scope, class), now)
+		set[class] = b
+	}
+	if b != nil {
+		b.fill(now)
+	}
+	return b
+}
+
+// 获取类别的限额配置。
+// 组合键未配置时为零值（不限）。
+// 调用者需持有锁。
+func (l *Limiter) rate(scope Scope, class string) config.Rate {
+	if r, ok := l.rates[scope][class]; ok {
+		return r
+	}
+	if strings.Contains(class, ".") {
+		return config.Rate{}
+	}
+	return l.rates[scope][""]
+}
+
+// 累计统计。
+// 调用者需持有锁。
+func (l *Limiter) count(scope Scope, class string, pass bool) {
+	k := statKey{scope, class}
+	s := l.stats[k]
+
+	if s == nil {
+		s = &Stat{Scope: scope, Class: class}
+		l.stats[k] = s
+	}
+	if pass {
+		s.Passed++
+	} else {
+		s.Dropped++
+	}
+}
+
+// 清理闲置的来源。
+// 每隔 config.LimitIdle 检查一次。
+// 调用者需持有锁。
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.sweep) < config.LimitIdle {
+		return
+	}
+	l.sweep = now
+
+	for k, src := range l.sources {
+		if now.Sub(src.last) >= config.LimitIdle {
+			delete(l.sources, k)
+		}
+	}
+}
+
+// 淘汰最久未活动的来源。
+// 调用者需持有锁。
+func (l *Limiter) evict() {
+	var old sourceKey
+	var last time.Time
+
+	for k, src := range l.sources {
+		if last.IsZero() || src.last.Before(last) {
+			old, last = k, src.last
+		}
+	}
+	delete(l.sources, old)
+}
+
+// 构造范围的限额配置集。
+// 空键（""）存储该范围的默认限额。
+// @conf  外部配置
+// @rate  默认速率
+// @burst 默认突发上限
+func rates(conf map[string]config.Rate, rate float64, burst int) map[string]config.Rate {
+	set := make(map[string]config.Rate, len(conf)+1)
+
+	for k, r := range conf {
+		set[strings.ToLower(k)] = r
+	}
+	set[""] = config.Rate{Rate: rate, Burst: burst}
+
+	return set
+}
+
+// 来源是否为轻量来源。
+// 自身的各令牌桶余量均过半，或自身不受限。
+func light(own []*bucket) bool {
+	for _, b := range own {
+		if b != nil && b.tokens < b.burst/2 {
+			return false
+		}
+	}
+	return true
+}
+
+// 扣减一个令牌。
+func take(b *bucket) {
+	if b != nil {
+		b.tokens--
+	}
+}
@@ -0,0 +1,125 @@
+package limit
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/ban"
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+// 连续发送n个询问包，返回放行数。
+func flood(l *Limiter, from netip.AddrPort, n int) int {
+	pass := 0
+
+	for range n {
+		if l.Allow(Peer, from, packet.PACKET_QUEST) {
+			pass++
+		}
+	}
+	return pass
+}
+
+func TestPenalizeOnce(t *testing.T) {
+	l := New(nil)
+	b := ban.New()
+	l.Guard(b)
+
+	from := netip.MustParseAddrPort("10.0.0.1:7788")
+
+	// 短暂的突发超限不予惩罚
+	if n := flood(l, from, config.LimitPeerBurst+config.LimitExcess-1); n < config.LimitPeerBurst {
+		t.Fatalf("passed: %d", n)
+	}
+	if b.Score(from.Addr()) != 0 {
+		t.Fatalf("brief excess penalized: %f", b.Score(from.Addr()))
+	}
+	// 持续超限，每个窗口仅惩罚一次
+	flood(l, from, config.LimitExcess*10)
+
+	if s := b.Score(from.Addr()); s < config.PenaltyFlood-1 || s > config.PenaltyFlood {
+		t.Fatalf("score after a window: %f", s)
+	}
+	l.mu.Lock()
+	l.sources[sourceKey{Peer, ban.Group(from.Addr())}].window = time.Now().Add(-config.LimitWindow)
+	l.mu.Unlock()
+
+	flood(l, from, config.LimitExcess*10)
+
+	if s := b.Score(from.Addr()); s < 2*config.PenaltyFlood-1 || s > 2*config.PenaltyFlood {
+		t.Errorf("score after two windows: %f", s)
+	}
+	// 客户端不予惩罚
+	client := netip.MustParseAddrPort("10.0.0.2:7788")
+
+	for range config.LimitClientBurst + config.LimitExcess*2 {
+		l.Allow(Client, client, packet.PACKET_QUEST)
+	}
+	if b.Score(client.Addr()) != 0 {
+		t.Error("client penalized")
+	}
+}
+
+func TestSourceKey(t *testing.T) {
+	l := New(nil)
+
+	// 同一IP的不同端口共享限额
+	flood(l, netip.MustParseAddrPort("10.0.0.1:1000"), config.LimitPeerBurst)
+
+	if l.Allow(Peer, netip.MustParseAddrPort("10.0.0.1:2000"), packet.PACKET_QUEST) {
+		t.Error("new port got a fresh budget")
+	}
+	if !l.Allow(Peer, netip.MustParseAddrPort("10.0.0.2:1000"), packet.PACKET_QUEST) {
+		t.Error("other ip limited")
+	}
+	// IPv6 按 /64 网段
+	flood(l, netip.MustParseAddrPort("[2001:db8::1]:1000"), config.LimitPeerBurst)
+
+	if l.Allow(Peer, netip.MustParseAddrPort("[2001:db8::2]:1000"), packet.PACKET_QUEST) {
+		t.Error("rotated ipv6 address got a fresh budget")
+	}
+	if !l.Allow(Peer, netip.MustParseAddrPort("[2001:db8:0:1::1]:1000"), packet.PACKET_QUEST) {
+		t.Error("other /64 limited")
+	}
+	// 未满额的来源不因连接关闭而清除
+	l.Forget(Peer, netip.MustParseAddrPort("10.0.0.1:1000"))
+
+	if l.Allow(Peer, netip.MustParseAddrPort("10.0.0.1:3000"), packet.PACKET_QUEST) {
+		t.Error("forget reset an exhausted budget")
+	}
+	// 恢复满额后清除
+	time.Sleep(time.Second / config.LimitPeerRate * 2)
+	l.Forget(Peer, netip.MustParseAddrPort("10.0.0.2:1000"))
+
+	l.mu.Lock()
+	_, ok := l.sources[sourceKey{Peer, netip.MustParsePrefix("10.0.0.2/32")}]
+	l.mu.Unlock()
+
+	if ok {
+		t.Error("idle source not forgotten")
+	}
+}
+
+func TestSourceCap(t *testing.T) {
+	l := New(nil)
+	first := netip.MustParseAddrPort("10.0.0.0:7788")
+	l.Allow(Peer, first, packet.PACKET_QUEST)
+
+	for i := 1; i < config.LimitSources+10; i++ {
+		ip := netip.AddrFrom4([4]byte{10, byte(i >> 16), byte(i >> 8), byte(i)})
+		l.Allow(Peer, netip.AddrPortFrom(ip, 7788), packet.PACKET_QUEST)
+	}
+	l.mu.Lock()
+	n := len(l.sources)
+	_, ok := l.sources[sourceKey{Peer, ban.Group(first.Addr())}]
+	l.mu.Unlock()
+
+	if n != config.LimitSources {
+		t.Errorf("sources: %d", n)
+	}
+	if ok {
+		t.Error("oldest source not evicted")
+	}
+}
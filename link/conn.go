@@ -0,0 +1,251 @@
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// 流式帧长度上限（含认证标签）
+const frameMax = 0xffff
+
+// ErrFrame 数据帧长度错误
+var ErrFrame = errors.New("link frame size invalid")
+
+// Conn 加密的流式连接（TCP）。
+// 每个数据帧为：长度（2字节，大端序） | 密文。
+// 握手消息也以同样的长度前缀传输。
+type Conn struct {
+	net.Conn
+	s   *Session
+	buf []byte // 已解密未读取的数据
+}
+
+// Client 作为发起方在连接上握手。
+// 握手成功后，对端身份会交由已知身份集核实（钉扎检查并记录）。
+// @conn  底层连接
+// @self  本方身份签名包
+// @tag   临时密钥交换算法
+// @known 已知身份集，可选
+func Client(conn net.Conn, self *msg.SignPack, tag msg.DHTag, known *Known) (*Conn, error) {
+	h, err := NewInitiator(self, tag)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(config.LinkTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	data, err := h.Init()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, data); err != nil {
+		return nil, err
+	}
+	if data, err = readFrame(conn); err != nil {
+		return nil, err
+	}
+	if err = h.ReadReply(data); err != nil {
+		return nil, err
+	}
+	// 先核实对端，再发送本方身份
+	if err = check(known, conn, h.Peer()); err != nil {
+		return nil, err
+	}
+	data, s, err := h.Final()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, data); err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, s: s}, nil
+}
+
+// Server 作为回应方在连接上握手。
+// @conn  底层连接
+// @self  本方身份签名包
+// @known 已知身份集，可选
+func Server(conn net.Conn, self *msg.SignPack, known *Known) (*Conn, error) {
+	h := NewResponder(self)
+
+	conn.SetDeadline(time.Now().Add(config.LinkTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	data, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.ReadInit(data); err != nil {
+		return nil, err
+	}
+	if data, err = h.Reply(); err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, data); err != nil {
+		return nil, err
+	}
+	if data, err = readFrame(conn); err != nil {
+		return nil, err
+	}
+	s, err := h.ReadFinal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = check(known, conn, s.Peer()); err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, s: s}, nil
+}
+
+// Greet 发起方在加密连接上协商通讯参数。
+// 握手完成后即调用，发送能力声明并确认对端的回应。
+// 对端拒绝时返回 *packet.Refused，调用者应关闭连接。
+// @c     加密连接
+// @local 己方能力
+func Greet(c *Conn, local *packet.Features) (*packet.Params, error) {
+	data, err := packet.EncodeHello(local)
+	if err != nil {
+		return nil, err
+	}
+	c.SetDeadline(time.Now().Add(config.LinkTimeout))
+	defer c.SetDeadline(time.Time{})
+
+	if _, err = c.Write(data); err != nil {
+		return nil, err
+	}
+	if data, err = c.frame(); err != nil {
+		return nil, err
+	}
+	return packet.Settle(local, data)
+}
+
+// Welcome 回应方在加密连接上协商通讯参数。
+// 握手完成后即调用，读取对端的能力声明并回应。
+// 无法兼容时回应拒绝原因并返回协商错误，调用者应关闭连接。
+// @c     加密连接
+// @local 己方能力
+func Welcome(c *Conn, local *packet.Features) (*packet.Params, error) {
+	c.SetDeadline(time.Now().Add(config.LinkTimeout))
+	defer c.SetDeadline(time.Time{})
+
+	data, err := c.frame()
+	if err != nil {
+		return nil, err
+	}
+	reply, p, err := packet.Respond(local, data)
+	if reply == nil {
+		return nil, err
+	}
+	if _, werr := c.Write(reply); werr != nil && err == nil {
+		return nil, werr
+	}
+	return p, err
+}
+
+// Peer 对端身份。
+func (c *Conn) Peer() *Identity {
+	return c.s.Peer()
+}
+
+// Read 读取解密后的数据。
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		data, err := readFrame(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		if c.buf, err = c.s.Open(data); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+
+	return n, nil
+}
+
+// Write 加密并写入数据。
+// 超长的数据会被切分为多个帧。
+func (c *Conn) Write(p []byte) (int, error) {
+	max := frameMax - chacha20poly1305.Overhead
+	n := 0
+
+	for len(p) > 0 {
+		chunk := p[:min(len(p), max)]
+
+		data, err := c.s.Seal(chunk)
+		if err != nil {
+			return n, err
+		}
+		if err = writeFrame(c.Conn, data); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 读取并解密一个帧。
+// 用于握手后的协商消息，此时没有未读取的数据。
+func (c *Conn) frame() ([]byte, error) {
+	data, err := readFrame(c.Conn)
+	if err != nil {
+		return nil, err
+	}
+	return c.s.Open(data)
+}
+
+// 写入一个帧。
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > frameMax {
+		return ErrFrame
+	}
+	buf := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(data)), uint16(len(data)))
+	_, err := w.Write(append(buf, data...))
+	return err
+}
+
+// 读取一个帧。
+func readFrame(r io.Reader) ([]byte, error) {
+	var head [2]byte
+
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint16(head[:])
+	if n == 0 {
+		return nil, ErrFrame
+	}
+	buf := make([]byte, n)
+
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// 核实对端身份。
+func check(known *Known, conn net.Conn, id *Identity) error {
+	if known == nil {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+	return known.Check(ap.Addr().Unmap(), id)
+}
@@ -0,0 +1,252 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 指定对端地址的连接（net.Pipe 的地址无法解析为IP）。
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// 创建一对内存连接，对端地址分别为 a 和 b 的反向。
+// @a 客户端看到的服务端地址
+// @b 服务端看到的客户端地址
+func pipe(a, b string) (net.Conn, net.Conn) {
+	c, s := net.Pipe()
+	ca := net.TCPAddrFromAddrPort(netip.MustParseAddrPort(a))
+	sa := net.TCPAddrFromAddrPort(netip.MustParseAddrPort(b))
+
+	return &addrConn{Conn: c, remote: ca}, &addrConn{Conn: s, remote: sa}
+}
+
+// 创建一个随机的身份签名包。
+func newSelf(t *testing.T, tag msg.SignTag) *msg.SignPack {
+	t.Helper()
+
+	key, err := msg.GenerateSignKey(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg.NewSignPack(tag, key)
+}
+
+// 在一对连接上完成握手。
+func handshake(t *testing.T, cli, srv *msg.SignPack, ck, sk *Known) (*Conn, *Conn, error, error) {
+	t.Helper()
+
+	c, s := pipe("10.0.0.2:7799", "10.0.0.1:7799")
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+
+	go func() {
+		conn, err := Server(s, srv, sk)
+		if err != nil {
+			s.Close()
+		}
+		ch <- result{conn, err}
+	}()
+	cc, cerr := Client(c, cli, msg.DH_X25519, ck)
+	if cerr != nil {
+		c.Close()
+	}
+	r := <-ch
+
+	return cc, r.conn, cerr, r.err
+}
+
+func TestHandshake(t *testing.T) {
+	for _, tag := range []msg.SignTag{msg.SIGN_ED25519, msg.SIGN_ED448, msg.SIGN_SCHNORR, msg.SIGN_MLDSA65} {
+		cli, srv := newSelf(t, tag), newSelf(t, msg.SIGN_ED25519)
+
+		cc, sc, cerr, serr := handshake(t, cli, srv, NewKnown(), NewKnown())
+		if cerr != nil || serr != nil {
+			t.Fatalf("algor %d: %v, %v", tag, cerr, serr)
+		}
+		if !cc.Peer().Equal(identity(srv)) || !sc.Peer().Equal(identity(cli)) {
+			t.Fatalf("algor %d: peer identity mismatch", tag)
+		}
+		// 超过一帧的数据被切分
+		data := bytes.Repeat([]byte("depots"), frameMax/3)
+		go cc.Write(data)
+
+		buf := make([]byte, len(data))
+		n := 0
+		for n < len(buf) {
+			m, err := sc.Read(buf[n:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			n += m
+		}
+		if !bytes.Equal(buf, data) {
+			t.Fatalf("algor %d: data mismatch", tag)
+		}
+	}
+}
+
+func TestHandshakeAlgor(t *testing.T) {
+	self := newSelf(t, msg.SIGN_ED25519)
+
+	for _, tag := range []msg.DHTag{msg.DH_MLKEM768, msg.DH_X25519MLKEM768, msg.DHTag(0xff)} {
+		if _, err := NewInitiator(self, tag); !errors.Is(err, ErrAlgor) {
+			t.Errorf("dh %d: %v", tag, err)
+		}
+	}
+	h := NewResponder(self)
+
+	if err := h.ReadInit([]byte{TypeInit, byte(msg.DH_MLKEM768), 1, 2, 3}); !errors.Is(err, ErrAlgor) {
+		t.Errorf("kem init: %v", err)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	cli, srv, other := newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519)
+
+	// 客户端钉扎了另一个身份：不发送完成消息
+	ck := NewKnown()
+	ck.Pin(netip.MustParseAddr("10.0.0.2"), identity(other))
+
+	_, _, cerr, serr := handshake(t, cli, srv, ck, nil)
+	if !errors.Is(cerr, ErrPinned) || serr == nil {
+		t.Errorf("client pin: %v, %v", cerr, serr)
+	}
+	// 服务端钉扎了另一个身份
+	sk := NewKnown()
+	sk.Pin(netip.MustParseAddr("10.0.0.1"), identity(other))
+
+	if _, _, _, serr = handshake(t, cli, srv, nil, sk); !errors.Is(serr, ErrPinned) {
+		t.Errorf("server pin: %v", serr)
+	}
+	if sk.idents[netip.MustParseAddr("10.0.0.1")] != nil {
+		t.Error("rejected identity recorded")
+	}
+	// 钉扎相符，未钉扎的一方记录对端身份
+	sk.Pin(netip.MustParseAddr("10.0.0.1"), identity(cli))
+	ck = NewKnown()
+
+	if _, _, cerr, serr = handshake(t, cli, srv, ck, sk); cerr != nil || serr != nil {
+		t.Fatalf("matching pin: %v, %v", cerr, serr)
+	}
+	if !ck.Lookup(netip.MustParseAddr("10.0.0.2")).Equal(identity(srv)) {
+		t.Error("peer identity not recorded")
+	}
+}
+
+func TestForged(t *testing.T) {
+	cli, srv := newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519)
+
+	h, err := NewInitiator(cli, msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewResponder(srv)
+	init, _ := h.Init()
+
+	if err = r.ReadInit(init); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := r.Reply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 篡改回应
+	bad := bytes.Clone(reply)
+	bad[len(bad)-1] ^= 1
+
+	if err = h.ReadReply(bad); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("tampered reply: %v", err)
+	}
+	if h.Peer() != nil {
+		t.Error("peer set by a tampered reply")
+	}
+}
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeFrame(&buf, make([]byte, frameMax+1)); !errors.Is(err, ErrFrame) {
+		t.Errorf("oversized frame: %v", err)
+	}
+	if _, err := readFrame(bytes.NewReader([]byte{0, 0})); !errors.Is(err, ErrFrame) {
+		t.Errorf("empty frame: %v", err)
+	}
+	cc, sc, cerr, serr := handshake(t, newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519), nil, nil)
+	if cerr != nil || serr != nil {
+		t.Fatal(cerr, serr)
+	}
+	// 篡改的数据帧
+	data, err := cc.s.Seal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] ^= 1
+	go writeFrame(cc.Conn, data)
+
+	if _, err = sc.Read(make([]byte, 16)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("tampered frame: %v", err)
+	}
+}
+
+func TestGreet(t *testing.T) {
+	cc, sc, cerr, serr := handshake(t, newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519), nil, nil)
+	if cerr != nil || serr != nil {
+		t.Fatal(cerr, serr)
+	}
+	local := packet.Local()
+	old := &packet.Features{Vmin: packet.VersionMin, Vmax: packet.VersionMin, DHs: 1 << msg.DH_X25519, Xnets: 1 << packet.XNET_TCP}
+
+	ch := make(chan *packet.Params, 1)
+	go func() {
+		p, err := Welcome(sc, local)
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- p
+	}()
+	cp, err := Greet(cc, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp := <-ch; sp == nil || *sp != *cp || cp.Ver != packet.VersionMin || cp.Xnets != 1<<packet.XNET_TCP {
+		t.Errorf("params: %+v, %+v", cp, sp)
+	}
+	// 版本不兼容：回应方拒绝
+	cc, sc, cerr, serr = handshake(t, newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519), nil, nil)
+	if cerr != nil || serr != nil {
+		t.Fatal(cerr, serr)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := Welcome(sc, local)
+		errc <- err
+	}()
+	old.Vmin, old.Vmax = 1, packet.VersionMin-1
+
+	var refused *packet.Refused
+	if _, err = Greet(cc, old); !errors.As(err, &refused) {
+		t.Errorf("greet: %v", err)
+	}
+	if err = <-errc; !errors.Is(err, packet.ErrVersion) {
+		t.Errorf("welcome: %v", err)
+	}
+}
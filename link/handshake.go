@@ -0,0 +1,394 @@
+// Package link 驿站节点间的认证加密连接。
+// 握手采用类 Noise 的三消息模式：双方以临时密钥交换构造共享密钥，
+// 再以各自的长期身份密钥签名握手记录，相互认证。
+// 握手完成后临时私钥即被丢弃，连接数据以 AEAD 加密，具备前向保密性。
+// 握手消息与传输无关，TCP 连接和 UDP 数据报都采用同样的握手流程。
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/cxio/depots/crypto/msg"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// 消息类型
+// 每条消息的首字节，UDP 数据报据此分派。
+const (
+	TypeInit  byte = iota + 1 // 握手：发起
+	TypeReply                 // 握手：回应
+	TypeFinal                 // 握手：完成
+	TypeData                  // 数据报（UDP）
+)
+
+// 握手的上下文标识
+const (
+	prologue    = "depots:link:v1"   // 握手记录前缀
+	labelResp   = "depots:link:r"    // 回应方签名前缀
+	labelInit   = "depots:link:i"    // 发起方签名前缀
+	infoShake   = "depots:link:hs"   // 握手密钥派生信息
+	infoTraffic = "depots:link:data" // 通信密钥派生信息
+)
+
+var (
+	// ErrMessage 握手消息格式错误
+	ErrMessage = errors.New("malformed handshake message")
+
+	// ErrIdentity 身份签名验证失败
+	ErrIdentity = errors.New("peer identity verification failed")
+
+	// ErrState 握手状态错误
+	ErrState = errors.New("handshake in wrong state")
+
+	// ErrAlgor 不支持的算法（握手需要密钥交换算法）
+	ErrAlgor = errors.New("unsupported link algorithm")
+)
+
+// Identity 对端身份。
+// 握手完成后可得，用于节点识别和公钥钉扎。
+type Identity struct {
+	Algor  msg.SignTag // 签名算法
+	Pubkey []byte      // 身份公钥
+}
+
+// 编码身份（算法1字节 + 公钥长度2字节 + 公钥）。
+func (id *Identity) append(buf []byte) []byte {
+	buf = append(buf, byte(id.Algor))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(id.Pubkey)))
+	return append(buf, id.Pubkey...)
+}
+
+// Handshake 握手状态。
+// 发起方：Init -> (ReadReply) -> Final
+// 回应方：(ReadInit) -> Reply -> (ReadFinal)
+type Handshake struct {
+	self      *msg.SignPack // 本方身份
+	initiator bool          // 是否为发起方
+	step      int           // 已完成的步骤
+	eph       *msg.DHPack   // 本方临时密钥（握手后丢弃）
+	tag       msg.DHTag     // 密钥交换算法
+	ei, er    []byte        // 双方临时公钥
+	secret    *msg.Secret   // 临时共享密钥
+	hash      []byte        // 握手记录摘要
+	kinit     []byte        // 发起方握手载荷密钥
+	kresp     []byte        // 回应方握手载荷密钥
+	peer      *Identity     // 对端身份
+	selfID    *Identity     // 本方身份
+}
+
+// NewInitiator 创建发起方握手。
+// @self 本方身份签名包（通常为节点的长期身份密钥）
+// @tag  临时密钥的交换算法，不支持密钥封装算法
+func NewInitiator(self *msg.SignPack, tag msg.DHTag) (*Handshake, error) {
+	if msg.NewDHPack(tag, nil) == nil || tag.IsKEM() {
+		return nil, ErrAlgor
+	}
+	key, err := msg.GenerateKey(tag)
+	if err != nil {
+		return nil, err
+	}
+	eph := msg.NewDHPack(tag, key)
+
+	return &Handshake{
+		self:      self,
+		selfID:    identity(self),
+		initiator: true,
+		eph:       eph,
+		tag:       tag,
+		ei:        eph.PublicBytes(),
+	}, nil
+}
+
+// NewResponder 创建回应方握手。
+// 临时密钥的算法由发起方决定。
+// @self 本方身份签名包
+func NewResponder(self *msg.SignPack) *Handshake {
+	return &Handshake{self: self, selfID: identity(self)}
+}
+
+// Peer 对端身份。
+// 握手完成前为nil。
+func (h *Handshake) Peer() *Identity {
+	return h.peer
+}
+
+// Init 发起方：创建发起消息。
+// 格式：类型 | 算法（1） | 临时公钥
+func (h *Handshake) Init() ([]byte, error) {
+	if !h.initiator || h.step != 0 {
+		return nil, ErrState
+	}
+	h.step = 1
+	buf := []byte{TypeInit, byte(h.tag)}
+
+	return append(buf, h.ei...), nil
+}
+
+// ReadInit 回应方：读取发起消息。
+// @data 发起消息
+func (h *Handshake) ReadInit(data []byte) error {
+	if h.initiator || h.step != 0 {
+		return ErrState
+	}
+	if len(data) < 3 || data[0] != TypeInit {
+		return ErrMessage
+	}
+	tag := msg.DHTag(data[1])
+
+	if msg.NewDHPack(tag, nil) == nil || tag.IsKEM() {
+		return ErrAlgor
+	}
+	key, err := msg.GenerateKey(tag)
+	if err != nil {
+		return err
+	}
+	h.tag = tag
+	h.eph = msg.NewDHPack(tag, key)
+	h.ei = append([]byte(nil), data[2:]...)
+	h.er = h.eph.PublicBytes()
+	h.step = 1
+
+	return h.mix()
+}
+
+// Reply 回应方：创建回应消息。
+// 格式：类型 | 临时公钥长度（2） | 临时公钥 | 密文（本方身份 + 签名）
+func (h *Handshake) Reply() ([]byte, error) {
+	if h.initiator || h.step != 1 {
+		return nil, ErrState
+	}
+	h.step = 2
+	sig, err := h.self.Sign(append([]byte(labelResp), h.hash...))
+	if err != nil {
+		return nil, err
+	}
+
+	plain := h.selfID.append(nil)
+	plain = append(plain, sig...)
+
+	buf := []byte{TypeReply}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(h.er)))
+	buf = append(buf, h.er...)
+
+	return seal(h.kresp, buf, plain)
+}
+
+// ReadReply 发起方：读取回应消息，认证回应方身份。
+// @data 回应消息
+func (h *Handshake) ReadReply(data []byte) error {
+	if !h.initiator || h.step != 1 {
+		return ErrState
+	}
+	if len(data) < 3 || data[0] != TypeReply {
+		return ErrMessage
+	}
+	n := int(binary.BigEndian.Uint16(data[1:]))
+	if len(data) < 3+n {
+		return ErrMessage
+	}
+	h.er = append([]byte(nil), data[3:3+n]...)
+
+	if err := h.mix(); err != nil {
+		return err
+	}
+	plain, err := open(h.kresp, data[:3+n], data[3+n:])
+	if err != nil {
+		return err
+	}
+	id, sig, err := parseIdentity(plain)
+	if err != nil {
+		return err
+	}
+	if !verify(id, append([]byte(labelResp), h.hash...), sig) {
+		return ErrIdentity
+	}
+	h.peer = id
+	h.step = 2
+
+	return nil
+}
+
+// Final 发起方：创建完成消息，并返回会话。
+// 签名覆盖握手记录和回应方身份，证明发起方确实与其通信。
+// 格式：类型 | 密文（本方身份 + 签名）
+func (h *Handshake) Final() ([]byte, *Session, error) {
+	if !h.initiator || h.step != 2 {
+		return nil, nil, ErrState
+	}
+	h.step = 3
+	sig, err := h.self.Sign(h.peer.append(append([]byte(labelInit), h.hash...)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plain := h.selfID.append(nil)
+	plain = append(plain, sig...)
+
+	data, err := seal(h.kinit, []byte{TypeFinal}, plain)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := h.session()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, s, nil
+}
+
+// ReadFinal 回应方：读取完成消息，认证发起方身份，并返回会话。
+// @data 完成消息
+func (h *Handshake) ReadFinal(data []byte) (*Session, error) {
+	if h.initiator || h.step != 2 {
+		return nil, ErrState
+	}
+	if len(data) < 1 || data[0] != TypeFinal {
+		return nil, ErrMessage
+	}
+	plain, err := open(h.kinit, data[:1], data[1:])
+	if err != nil {
+		return nil, err
+	}
+	id, sig, err := parseIdentity(plain)
+	if err != nil {
+		return nil, err
+	}
+	if !verify(id, h.selfID.append(append([]byte(labelInit), h.hash...)), sig) {
+		return nil, ErrIdentity
+	}
+	h.peer = id
+	h.step = 3
+
+	return h.session()
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 提取签名包的身份。
+func identity(sp *msg.SignPack) *Identity {
+	return &Identity{Algor: sp.Algor, Pubkey: sp.PublicBytes()}
+}
+
+// 混入双方临时公钥，构造共享密钥和握手载荷密钥。
+// 握手记录摘要：SHA3-256(前缀 | 算法 | 发起方临时公钥 | 回应方临时公钥)
+func (h *Handshake) mix() error {
+	var err error
+	peer := h.er
+	if !h.initiator {
+		peer = h.ei
+	}
+	if h.secret, err = h.eph.SharedKey(peer); err != nil {
+		return err
+	}
+	d := sha3.New256()
+	d.Write([]byte(prologue))
+	d.Write([]byte{byte(h.tag)})
+	d.Write(h.ei)
+	d.Write(h.er)
+	h.hash = d.Sum(nil)
+
+	keys, err := derive(h.secret, h.hash, infoShake)
+	if err != nil {
+		return err
+	}
+	h.kinit, h.kresp = keys[:chacha20poly1305.KeySize], keys[chacha20poly1305.KeySize:]
+	return nil
+}
+
+// 创建会话。
+// 通信密钥的派生盐包含双方的身份，之后丢弃临时密钥。
+func (h *Handshake) session() (*Session, error) {
+	salt := append([]byte(nil), h.hash...)
+	ini, resp := h.selfID, h.peer
+	if !h.initiator {
+		ini, resp = h.peer, h.selfID
+	}
+	salt = resp.append(ini.append(salt))
+
+	keys, err := derive(h.secret, salt, infoTraffic)
+	if err != nil {
+		return nil, err
+	}
+	send, recv := keys[:chacha20poly1305.KeySize], keys[chacha20poly1305.KeySize:]
+	if !h.initiator {
+		send, recv = recv, send
+	}
+	s, err := newSession(h.peer, send, recv)
+	if err != nil {
+		return nil, err
+	}
+	// 前向保密
+	h.eph, h.secret, h.kinit, h.kresp = nil, nil, nil, nil
+
+	return s, nil
+}
+
+// 派生两个密钥（HKDF-SHA3-256）。
+func derive(secret *msg.Secret, salt []byte, info string) ([]byte, error) {
+	buf := make([]byte, chacha20poly1305.KeySize*2)
+	r := hkdf.New(sha3.New256, secret[:], salt, []byte(info))
+
+	if _, err := r.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// 握手载荷加密。
+// 每个载荷密钥仅使用一次，随机数固定为零。
+// 消息头部作为附加数据。
+func seal(key, head, plain []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(head, nonce, plain, head), nil
+}
+
+// 握手载荷解密。
+func open(key, head, data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+
+	plain, err := aead.Open(nil, nonce, data, head)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// 解析身份和签名。
+func parseIdentity(data []byte) (*Identity, []byte, error) {
+	if len(data) < 3 {
+		return nil, nil, ErrMessage
+	}
+	n := int(binary.BigEndian.Uint16(data[1:]))
+	if len(data) < 3+n {
+		return nil, nil, ErrMessage
+	}
+	id := &Identity{
+		Algor:  msg.SignTag(data[0]),
+		Pubkey: append([]byte(nil), data[3:3+n]...),
+	}
+	return id, data[3+n:], nil
+}
+
+// 验证身份签名。
+// 公钥和签名的长度需与算法相符。
+func verify(id *Identity, m, sig []byte) bool {
+	sp := msg.NewSignPack(id.Algor, nil)
+	if sp == nil ||
+		len(id.Pubkey) != id.Algor.PublicSize() ||
+		len(sig) != id.Algor.SignatureSize() {
+		return false
+	}
+	return sp.Verify(id.Pubkey, m, sig)
+}
@@ -0,0 +1,190 @@
+package link
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// ErrPinned 对端身份与钉扎的公钥不符
+var ErrPinned = errors.New("peer identity does not match the pinned key")
+
+// 身份记录
+type known struct {
+	id   *Identity
+	seen time.Time
+}
+
+// Known 已知节点身份集。
+// - 钉扎：peers.json 中配置了公钥的节点，握手时身份必须与之相符。
+// - 记录：其它节点在握手成功后记录其身份，供后续参考和持久保存。
+// 记录超过时效（config.IdentExpired）后失效，数量超出上限（config.IdentsMax）时移除最久未握手者。
+type Known struct {
+	mu     sync.Mutex
+	pins   map[netip.Addr]*Identity
+	idents map[netip.Addr]*known
+}
+
+// NewKnown 创建一个空的已知身份集。
+func NewKnown() *Known {
+	return &Known{
+		pins:   make(map[netip.Addr]*Identity),
+		idents: make(map[netip.Addr]*known),
+	}
+}
+
+// LoadKnown 从用户配置载入已知身份集。
+// 钉扎来自 peers.json 中配置了公钥的条目，记录来自缓存目录的 idents.json。
+// 公钥格式错误的条目会被忽略。
+func LoadKnown() (*Known, error) {
+	peers, err := config.Peers()
+	if err != nil {
+		return nil, err
+	}
+	list, err := config.Idents()
+	if err != nil {
+		return nil, err
+	}
+	k := NewKnown()
+
+	for ip, p := range peers {
+		if id := parseIdent(p.Algor, p.Pubkey); id != nil {
+			k.pins[ip] = id
+		}
+	}
+	now := time.Now()
+
+	for _, r := range list {
+		if now.Sub(r.Seen) >= config.IdentExpired {
+			continue
+		}
+		if id := parseIdent(r.Algor, r.Pubkey); id != nil {
+			k.idents[r.IP] = &known{id: id, seen: r.Seen}
+		}
+	}
+	k.trim(now)
+
+	return k, nil
+}
+
+// Pin 钉扎一个节点的身份。
+// @ip 节点IP
+// @id 身份
+func (k *Known) Pin(ip netip.Addr, id *Identity) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.pins[ip] = id
+}
+
+// Check 核实并记录对端身份。
+// 钉扎的节点身份不符时返回 ErrPinned，否则记录其身份。
+// 注：未钉扎节点的身份变化只是更新记录，不视为错误。
+// @ip 节点IP
+// @id 握手获得的对端身份
+func (k *Known) Check(ip netip.Addr, id *Identity) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if pin := k.pins[ip]; pin != nil && !pin.Equal(id) {
+		return fmt.Errorf("%w: %s", ErrPinned, ip)
+	}
+	now := time.Now()
+	k.idents[ip] = &known{id: id, seen: now}
+	k.trim(now)
+
+	return nil
+}
+
+// Lookup 查询节点的已知身份。
+// 钉扎优先，未知时返回nil。
+// @ip 节点IP
+func (k *Known) Lookup(ip netip.Addr) *Identity {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if pin := k.pins[ip]; pin != nil {
+		return pin
+	}
+	if r := k.idents[ip]; r != nil && time.Since(r.seen) < config.IdentExpired {
+		return r.id
+	}
+	return nil
+}
+
+// Save 保存身份记录。
+// 钉扎来自用户配置，不在此保存。过期的记录先被移除。
+func (k *Known) Save() error {
+	k.mu.Lock()
+	k.trim(time.Now())
+	list := make([]*config.Ident, 0, len(k.idents))
+
+	for ip, r := range k.idents {
+		list = append(list, &config.Ident{
+			IP:     ip,
+			Algor:  int(r.id.Algor),
+			Pubkey: hex.EncodeToString(r.id.Pubkey),
+			Seen:   r.seen,
+		})
+	}
+	k.mu.Unlock()
+
+	return config.SaveIdents(list)
+}
+
+// Equal 身份是否相同。
+func (id *Identity) Equal(x *Identity) bool {
+	return x != nil && id.Algor == x.Algor && bytes.Equal(id.Pubkey, x.Pubkey)
+}
+
+// String 身份的文本表示（算法:公钥）。
+func (id *Identity) String() string {
+	return fmt.Sprintf("%d:%x", id.Algor, id.Pubkey)
+}
+
+// 移除过期的记录，数量超出上限时移除最久未握手者。
+// 调用者需持有锁。
+func (k *Known) trim(now time.Time) {
+	for ip, r := range k.idents {
+		if now.Sub(r.seen) >= config.IdentExpired {
+			delete(k.idents, ip)
+		}
+	}
+	if len(k.idents) <= config.IdentsMax {
+		return
+	}
+	list := make([]netip.Addr, 0, len(k.idents))
+
+	for ip := range k.idents {
+		list = append(list, ip)
+	}
+	slices.SortFunc(list, func(a, b netip.Addr) int {
+		return k.idents[a].seen.Compare(k.idents[b].seen)
+	})
+	for _, ip := range list[:len(list)-config.IdentsMax] {
+		delete(k.idents, ip)
+	}
+}
+
+// 解析配置中的身份。
+// 公钥为空、格式错误或长度与算法不符时返回nil。
+func parseIdent(algor int, pubkey string) *Identity {
+	if pubkey == "" {
+		return nil
+	}
+	pub, err := hex.DecodeString(pubkey)
+	tag := msg.SignTag(algor)
+
+	if err != nil || len(pub) != tag.PublicSize() {
+		return nil
+	}
+	return &Identity{Algor: tag, Pubkey: pub}
+}
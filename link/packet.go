@@ -0,0 +1,225 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// 数据报的大小上限
+const packetMax = 0xffff
+
+// ErrTimeout 数据报握手超时（重传次数耗尽）
+var ErrTimeout = errors.New("link packet handshake timed out")
+
+// PacketConn 加密的数据报连接（UDP）。
+// 底层为已连接的数据报连接（如 net.DialUDP，或服务端按对端地址分派的连接），
+// 每个数据报为一条握手消息，或一个加密的数据报文（见 Session.SealPacket）。
+// 数据报可能丢失，握手消息会重传：
+// - 发起方超时未收到回应时重发发起消息，回应方收到重复的发起消息时重发回应。
+// - 回应方超时未收到完成消息时重发回应，发起方握手后收到回应时重发完成消息。
+// 握手后仅补发与对端原握手消息相同的重传，收到首个数据报文后不再补发。
+type PacketConn struct {
+	net.Conn
+	s     *Session
+	last  []byte // 本方最后的握手消息（对端重传时补发）
+	retry []byte // 对端最后的握手消息（与之相同的数据报视为重传）
+}
+
+// ClientPacket 作为发起方在数据报连接上握手。
+// 每隔 config.LinkRetransmit 重发一次，最多 config.LinkRetries 次。
+// @conn  底层数据报连接
+// @self  本方身份签名包
+// @tag   临时密钥交换算法
+// @known 已知身份集，可选
+func ClientPacket(conn net.Conn, self *msg.SignPack, tag msg.DHTag, known *Known) (*PacketConn, error) {
+	h, err := NewInitiator(self, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	init, err := h.Init()
+	if err != nil {
+		return nil, err
+	}
+	var reply []byte
+
+	err = exchange(conn, init, func(data []byte) (bool, error) {
+		if len(data) == 0 || data[0] != TypeReply {
+			return false, nil
+		}
+		// 伪造或损坏的回应被忽略，继续等待
+		if h.ReadReply(data) != nil {
+			return false, nil
+		}
+		reply = data
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 先核实对端，再发送本方身份
+	if err = check(known, conn, h.Peer()); err != nil {
+		return nil, err
+	}
+	data, s, err := h.Final()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write(data); err != nil {
+		return nil, err
+	}
+	return &PacketConn{Conn: conn, s: s, last: data, retry: reply}, nil
+}
+
+// ServerPacket 作为回应方在数据报连接上握手。
+// 等待发起消息的时长为 config.LinkTimeout，之后的重传同发起方。
+// 握手完成前收到的数据报文被丢弃。
+// @conn  底层数据报连接
+// @self  本方身份签名包
+// @known 已知身份集，可选
+func ServerPacket(conn net.Conn, self *msg.SignPack, known *Known) (*PacketConn, error) {
+	h := NewResponder(self)
+	defer conn.SetReadDeadline(time.Time{})
+
+	conn.SetReadDeadline(time.Now().Add(config.LinkTimeout))
+	var init []byte
+
+	for init == nil {
+		data, err := readPacket(conn)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 && data[0] == TypeInit {
+			init = data
+		}
+	}
+	if err := h.ReadInit(init); err != nil {
+		return nil, err
+	}
+	reply, err := h.Reply()
+	if err != nil {
+		return nil, err
+	}
+	var s *Session
+
+	err = exchange(conn, reply, func(data []byte) (bool, error) {
+		switch {
+		case bytes.Equal(data, init):
+			_, err := conn.Write(reply)
+			return false, err
+		case len(data) == 0 || data[0] != TypeFinal:
+			return false, nil
+		}
+		var err error
+		s, err = h.ReadFinal(data)
+		return err == nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = check(known, conn, s.Peer()); err != nil {
+		return nil, err
+	}
+	return &PacketConn{Conn: conn, s: s, last: reply, retry: init}, nil
+}
+
+// Peer 对端身份。
+func (c *PacketConn) Peer() *Identity {
+	return c.s.Peer()
+}
+
+// Read 读取一个解密后的数据报文。
+// 缓冲区不足时多余的数据被截断（同UDP）。
+// 重放、过旧或无法解密的数据报被丢弃。
+// 对端重传的握手消息会被补发回应，直到收到首个数据报文（对端已完成握手）。
+func (c *PacketConn) Read(p []byte) (int, error) {
+	for {
+		data, err := readPacket(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if data[0] == TypeData {
+			plain, err := c.s.OpenPacket(data)
+			if err != nil {
+				continue
+			}
+			c.last, c.retry = nil, nil
+			return copy(p, plain), nil
+		}
+		if c.retry != nil && bytes.Equal(data, c.retry) {
+			if _, err = c.Conn.Write(c.last); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// Write 加密并发送一个数据报文。
+// 数据过大时返回 ErrFrame，不切分。
+func (c *PacketConn) Write(p []byte) (int, error) {
+	data, err := c.s.SealPacket(p)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) > packetMax {
+		return 0, ErrFrame
+	}
+	if _, err = c.Conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 发送握手消息并等待对端的下一条消息，超时重传。
+// 收到的数据报交由处理函数，返回真时结束。
+func exchange(conn net.Conn, out []byte, fn func(data []byte) (bool, error)) error {
+	for try := 0; try <= config.LinkRetries; try++ {
+		if _, err := conn.Write(out); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(config.LinkRetransmit))
+
+		for {
+			data, err := readPacket(conn)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			done, err := fn(data)
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+	}
+	return ErrTimeout
+}
+
+// 读取一个数据报。
+func readPacket(conn net.Conn) ([]byte, error) {
+	buf := make([]byte, packetMax)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
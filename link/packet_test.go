@@ -0,0 +1,283 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// 丢弃部分发送的数据报，并记录所有发送。
+type lossy struct {
+	net.Conn
+	mu   sync.Mutex
+	drop map[byte]int // 各消息类型待丢弃的个数
+	sent [][]byte     // 已发送的数据报（含丢弃的）
+}
+
+func newLossy(conn net.Conn) *lossy {
+	return &lossy{Conn: conn, drop: make(map[byte]int)}
+}
+
+func (c *lossy) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	c.sent = append(c.sent, bytes.Clone(p))
+	drop := len(p) > 0 && c.drop[p[0]] > 0
+	if drop {
+		c.drop[p[0]]--
+	}
+	c.mu.Unlock()
+
+	if drop {
+		return len(p), nil
+	}
+	return c.Conn.Write(p)
+}
+
+// 已发送的某类型数据报。
+func (c *lossy) of(typ byte) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var list [][]byte
+	for _, p := range c.sent {
+		if p[0] == typ {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// 创建一对互相连接的本地UDP连接。
+func udpPair(t *testing.T) (*net.UDPConn, *net.UDPConn) {
+	t.Helper()
+
+	a, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	la, lb := a.LocalAddr().(*net.UDPAddr), b.LocalAddr().(*net.UDPAddr)
+	a.Close()
+	b.Close()
+
+	if a, err = net.DialUDP("udp", la, lb); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = net.DialUDP("udp", lb, la); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b
+}
+
+// 在UDP连接对上握手，客户端按drop丢弃部分发送。
+// 返回包装的底层连接和加密连接。
+func packetPair(t *testing.T, drop map[byte]int) (*lossy, *lossy, *PacketConn, *PacketConn) {
+	t.Helper()
+
+	cli, srv := newSelf(t, msg.SIGN_ED25519), newSelf(t, msg.SIGN_ED25519)
+	a, b := udpPair(t)
+	cl, sl := newLossy(a), newLossy(b)
+	cl.drop = drop
+
+	ch := make(chan *PacketConn, 1)
+	go func() {
+		sc, err := ServerPacket(sl, srv, NewKnown())
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- sc
+	}()
+	cc, err := ClientPacket(cl, cli, msg.DH_X25519, NewKnown())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 完成消息丢失时，由客户端的读取补发
+	go cc.Read(make([]byte, 16))
+
+	sc := <-ch
+	if sc == nil {
+		t.FailNow()
+	}
+	if !cc.Peer().Equal(identity(srv)) || !sc.Peer().Equal(identity(cli)) {
+		t.Fatal("peer identity mismatch")
+	}
+	return cl, sl, cc, sc
+}
+
+// 读取一个数据报文，带超时。
+func readTimeout(c *PacketConn, d time.Duration) ([]byte, error) {
+	c.SetReadDeadline(time.Now().Add(d))
+	defer c.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 64)
+	n, err := c.Read(buf)
+	return buf[:n], err
+}
+
+func TestPacketHandshake(t *testing.T) {
+	_, _, cc, sc := packetPair(t, nil)
+
+	if _, err := sc.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := cc.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readTimeout(sc, time.Second); err != nil || string(data) != "world" {
+		t.Errorf("read: %q, %v", data, err)
+	}
+	if _, err := sc.Write(make([]byte, packetMax)); !errors.Is(err, ErrFrame) {
+		t.Errorf("oversized packet: %v", err)
+	}
+}
+
+func TestPacketRetransmit(t *testing.T) {
+	// 丢失首个发起消息和首个完成消息
+	cl, sl, cc, sc := packetPair(t, map[byte]int{TypeInit: 1, TypeFinal: 1})
+
+	if n := len(cl.of(TypeInit)); n != 2 {
+		t.Errorf("init sent: %d", n)
+	}
+	if n := len(cl.of(TypeFinal)); n != 2 {
+		t.Errorf("final sent: %d", n)
+	}
+	if n := len(sl.of(TypeReply)); n < 2 {
+		t.Errorf("reply sent: %d", n)
+	}
+	if _, err := cc.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readTimeout(sc, time.Second); err != nil || string(data) != "hello" {
+		t.Fatalf("read: %q, %v", data, err)
+	}
+	// 收到数据报文后，重传的发起消息不再补发回应
+	replies := len(sl.of(TypeReply))
+	cl.Conn.Write(cl.of(TypeInit)[0])
+
+	if _, err := readTimeout(sc, 200*time.Millisecond); err == nil {
+		t.Error("unexpected data")
+	}
+	if n := len(sl.of(TypeReply)); n != replies {
+		t.Errorf("reply resent after data: %d", n-replies)
+	}
+}
+
+func TestPacketStrayInit(t *testing.T) {
+	cl, sl, _, sc := packetPair(t, nil)
+	replies := len(sl.of(TypeReply))
+
+	// 与原握手消息不同的发起消息不予回应
+	h, err := NewInitiator(newSelf(t, msg.SIGN_ED25519), msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	init, _ := h.Init()
+	cl.Conn.Write(init)
+
+	if _, err = readTimeout(sc, 200*time.Millisecond); err == nil {
+		t.Error("unexpected data")
+	}
+	if n := len(sl.of(TypeReply)); n != replies {
+		t.Errorf("reply sent to a stray init: %d", n-replies)
+	}
+	// 收到数据报文前，重传的原发起消息获得补发
+	cl.Conn.Write(cl.of(TypeInit)[0])
+
+	if _, err = readTimeout(sc, 200*time.Millisecond); err == nil {
+		t.Error("unexpected data")
+	}
+	if n := len(sl.of(TypeReply)); n != replies+1 {
+		t.Errorf("reply resent to the original init: %d", n-replies)
+	}
+}
+
+func TestPacketReplay(t *testing.T) {
+	cl, _, cc, sc := packetPair(t, nil)
+
+	for _, s := range []string{"one", "two"} {
+		if _, err := cc.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := readTimeout(sc, time.Second); err != nil || string(data) != s {
+			t.Fatalf("read: %q, %v", data, err)
+		}
+	}
+	sent := cl.of(TypeData)
+
+	// 重放的数据报被丢弃
+	cl.Conn.Write(sent[0])
+	cl.Conn.Write(sent[1])
+
+	// 篡改的数据报被丢弃
+	bad := bytes.Clone(sent[1])
+	bad[len(bad)-1] ^= 1
+	bad[8]++
+	cl.Conn.Write(bad)
+
+	if data, err := readTimeout(sc, 200*time.Millisecond); err == nil {
+		t.Fatalf("replayed or tampered packet accepted: %q", data)
+	}
+	if _, err := cc.Write([]byte("three")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readTimeout(sc, time.Second); err != nil || string(data) != "three" {
+		t.Errorf("read after replay: %q, %v", data, err)
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	send, _ := newSession(nil, key, key)
+	recv, _ := newSession(nil, key, key)
+
+	var list [][]byte
+	for range replayWindow + 4 {
+		data, err := send.SealPacket([]byte("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, data)
+	}
+	// 乱序到达
+	for _, i := range []int{1, 0, 3} {
+		if _, err := recv.OpenPacket(list[i]); err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+	}
+	if _, err := recv.OpenPacket(list[0]); !errors.Is(err, ErrReplay) {
+		t.Errorf("duplicate: %v", err)
+	}
+	// 窗口前移后，过旧的数据报被拒绝
+	if _, err := recv.OpenPacket(list[replayWindow+3]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recv.OpenPacket(list[2]); !errors.Is(err, ErrReplay) {
+		t.Errorf("too old: %v", err)
+	}
+	if _, err := recv.OpenPacket(list[replayWindow]); err != nil {
+		t.Errorf("in window: %v", err)
+	}
+	// 计数值被篡改
+	bad := bytes.Clone(list[5])
+	bad[8] = 0xff
+	if _, err := recv.OpenPacket(bad); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("tampered counter: %v", err)
+	}
+	if _, err := recv.OpenPacket(list[5]); err != nil {
+		t.Errorf("window updated by a forged packet: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package link
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// 数据报重放窗口（计数值个数）
+const replayWindow = 64
+
+var (
+	// ErrDecrypt 数据解密失败
+	ErrDecrypt = errors.New("link data decryption failed")
+
+	// ErrReplay 数据报重放或过旧
+	ErrReplay = errors.New("link packet replayed or too old")
+
+	// ErrExhausted 计数值耗尽，需要重新握手
+	ErrExhausted = errors.New("link nonce exhausted, rehandshake required")
+)
+
+// Session 加密会话。
+// 双向各有独立的密钥和计数值，计数值作为 AEAD 的随机数。
+// - 流式（TCP）：计数值隐含，双方按序递增。
+// - 数据报（UDP）：计数值显式携带，接收端以滑动窗口防重放。
+type Session struct {
+	peer  *Identity
+	mu    sync.Mutex
+	send  cipher.AEAD
+	recv  cipher.AEAD
+	sendN uint64 // 发送计数
+	recvN uint64 // 接收计数（流式）
+	maxN  uint64 // 已接收的最大计数（数据报）
+	mask  uint64 // 窗口位图（数据报）
+}
+
+// 创建会话。
+func newSession(peer *Identity, send, recv []byte) (*Session, error) {
+	s := &Session{peer: peer}
+	var err error
+
+	if s.send, err = chacha20poly1305.New(send); err != nil {
+		return nil, err
+	}
+	if s.recv, err = chacha20poly1305.New(recv); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Peer 对端身份。
+func (s *Session) Peer() *Identity {
+	return s.peer
+}
+
+// Seal 加密一个流式数据帧。
+// @data 明文数据
+func (s *Session) Seal(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.next()
+	if err != nil {
+		return nil, err
+	}
+	return s.send.Seal(nil, nonce(n), data, nil), nil
+}
+
+// Open 解密一个流式数据帧。
+// 数据帧必须按发送的顺序解密。
+// @data 密文数据
+func (s *Session) Open(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plain, err := s.recv.Open(nil, nonce(s.recvN), data, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	s.recvN++
+	return plain, nil
+}
+
+// SealPacket 加密一个数据报。
+// 格式：类型（TypeData） | 计数值（8） | 密文
+// 头部作为附加数据。
+// @data 明文数据
+func (s *Session) SealPacket(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.next()
+	if err != nil {
+		return nil, err
+	}
+	head := binary.BigEndian.AppendUint64([]byte{TypeData}, n)
+
+	return s.send.Seal(head, nonce(n), data, head), nil
+}
+
+// OpenPacket 解密一个数据报。
+// 数据报可能丢失或乱序，窗口内未见过的计数值均可接受，
+// 重复或早于窗口的数据报被拒绝。
+// @data 数据报
+func (s *Session) OpenPacket(data []byte) ([]byte, error) {
+	if len(data) < 9 || data[0] != TypeData {
+		return nil, ErrMessage
+	}
+	n := binary.BigEndian.Uint64(data[1:9])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.fresh(n) {
+		return nil, ErrReplay
+	}
+	plain, err := s.recv.Open(nil, nonce(n), data[9:], data[:9])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	// 认证通过后才更新窗口
+	s.mark(n)
+
+	return plain, nil
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 获取下一个发送计数。
+// 调用者需持有锁。
+func (s *Session) next() (uint64, error) {
+	if s.sendN == math.MaxUint64 {
+		return 0, ErrExhausted
+	}
+	n := s.sendN
+	s.sendN++
+	return n, nil
+}
+
+// 数据报计数是否可接受。
+// 注：计数值从0开始，窗口位图的第0位对应最大计数。
+func (s *Session) fresh(n uint64) bool {
+	if n > s.maxN || (n == 0 && s.mask == 0) {
+		return true
+	}
+	d := s.maxN - n
+	if d >= replayWindow {
+		return false
+	}
+	return s.mask&(1<<d) == 0
+}
+
+// 标记数据报计数已接收。
+func (s *Session) mark(n uint64) {
+	if n > s.maxN || s.mask == 0 {
+		d := n - s.maxN
+		if s.mask == 0 || d >= replayWindow {
+			s.mask = 1
+		} else {
+			s.mask = s.mask<<d | 1
+		}
+		s.maxN = n
+		return
+	}
+	s.mask |= 1 << (s.maxN - n)
+}
+
+// 构造 AEAD 随机数（12字节，计数值大端序置于尾部）。
+func nonce(n uint64) []byte {
+	buf := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(buf[4:], n)
+	return buf
+}
@@ -3,12 +3,27 @@
 //////////////////////////////////////////////////////////////////////////////
 // 使用：
 //
+//	depots key show [identity|exchange]   打印公钥（用于发布）
+//	depots key rotate [identity|exchange] 轮换密钥
+//	depots key export [identity|exchange] 导出密钥文件
+//
+// 密钥口令从环境变量 DEPOTS_KEYPASS 读取，未设置时私钥明文存储。
 //////////////////////////////////////////////////////////////////////////////
 //
 
 // Depots 数据驿站主程序。
 package main
 
+import (
+	"flag"
+)
+
 func main() {
+	flag.Parse()
 
+	switch flag.Arg(0) {
+	case "key":
+		keyCommand(flag.Args()[1:])
+		return
+	}
 }
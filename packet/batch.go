@@ -0,0 +1,224 @@
+package packet
+
+import (
+	"errors"
+
+	"github.com/cxio/depots/config"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrBatchSize 批量条目数超限
+	ErrBatchSize = errors.New("batch items exceeds the limit")
+
+	// ErrBatchEmpty 批量条目为空
+	ErrBatchEmpty = errors.New("batch items is empty")
+)
+
+// Item 批量询问条目。
+// 序位为条目在原始批量中的位置，拆分转播时保持不变，
+// 回复条目以此对应其请求。
+type Item struct {
+	Slot  int    // 原始批量中的序位
+	Index []byte // 数据索引
+	Size  uint32 // 数据大小（字节数），可选
+}
+
+// NewItems 从索引集创建批量条目。
+// 序位按索引集的顺序依次编号。
+// @index 数据索引集
+// @sizes 对应的数据大小，可为nil
+func NewItems(index [][]byte, sizes []uint32) []*Item {
+	list := make([]*Item, len(index))
+
+	for i, id := range index {
+		its := &Item{Slot: i, Index: id}
+		if i < len(sizes) {
+			its.Size = sizes[i]
+		}
+		list[i] = its
+	}
+	return list
+}
+
+// Data 转换为单个数据信息。
+// @kind 批量的数据类别
+func (it *Item) Data(kind Kind) *Data {
+	return NewData(kind, it.Index, it.Size)
+}
+
+// Found 批量回复条目。
+// 连系信息为密文，中转节点无需解密即可合并转发。
+type Found struct {
+	Slot    int    // 对应询问条目的序位
+	Pubkey  []byte // 数据源公钥
+	Contact []byte // 连系信息（密文）
+	Kemct   []byte // 密钥封装密文（仅KEM算法）
+}
+
+// EncodeBatch 编码批量询问包
+// 条目数不能为空，也不能超过上限（config.BatchMax）。
+// @b  基础信息包
+// @k  数据类别
+// @its 询问条目集
+// @dh 密钥交换包
+func EncodeBatch(b *Base, k Kind, its []*Item, dh *DHPack) ([]byte, error) {
+	if len(its) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(its) > config.BatchMax {
+		return nil, ErrBatchSize
+	}
+	items := make([]*BatchItem, len(its))
+
+	for i, it := range its {
+		items[i] = &BatchItem{
+			Slot:  uint32(it.Slot),
+			Index: it.Index,
+			Size:  it.Size,
+		}
+	}
+	buf := &BatchQuest{
+		Ver:    int32(b.Ver),
+		Id:     b.ID,
+		Hops:   int32(b.Hops),
+		Algor:  int32(dh.Algor),
+		Pubkey: dh.PublicBytes(),
+		Level:  int32(b.Level),
+		Kind:   int32(k),
+		Items:  items,
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeBatch 解码批量询问包
+// 返回的对端公钥用于节点构建共享密钥。
+// @return1 基础信息包
+// @return2 数据类别
+// @return3 询问条目集
+// @return4 公钥算法，-1表示无效值
+// @return5 公钥字节序列
+func DecodeBatch(data []byte) (*Base, Kind, []*Item, DHTag, []byte, error) {
+	buf := &BatchQuest{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, 0, nil, -1, nil, err
+	}
+	if len(buf.Items) == 0 {
+		return nil, 0, nil, -1, nil, ErrBatchEmpty
+	}
+	if len(buf.Items) > config.BatchMax {
+		return nil, 0, nil, -1, nil, ErrBatchSize
+	}
+	b := NewBase(
+		int(buf.Ver),
+		buf.Id,
+		int(buf.Hops),
+		NatLevel(buf.Level),
+	)
+	its := make([]*Item, len(buf.Items))
+
+	for i, it := range buf.Items {
+		its[i] = &Item{
+			Slot:  int(it.Slot),
+			Index: it.Index,
+			Size:  it.Size,
+		}
+	}
+	return b, Kind(buf.Kind), its, DHTag(buf.Algor), buf.Pubkey, nil
+}
+
+// EncodeFound 创建一个批量回复条目
+// 连系信息用共享密钥加密，与单个回复包相同。
+// @b    基础信息（跳数和NAT层级会被加密封装）
+// @a    连系协助信息
+// @dh   密钥交换包
+// @pub  询问者公钥
+// @slot 对应询问条目的序位
+func EncodeFound(b *Base, a *AidInfo, dh *DHPack, pub []byte, slot int) (*Found, error) {
+	data, err := EncodeContact(b, a)
+	if err != nil {
+		return nil, err
+	}
+	ct, xdata, err := sealContact(b.Ver, FoundContext(b.Ver, b.ID, slot), dh, pub, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Found{Slot: slot, Pubkey: dh.PublicBytes(), Contact: xdata, Kemct: ct}, nil
+}
+
+// OpenFound 解密批量回复条目
+// 由询问者调用，dh为发出批量询问时的密钥交换包。
+// 询问以 VersionBind+ 发出时，低于 VersionBind 的回复被拒绝（ErrDowngrade）。
+// @f    回复条目
+// @ver  回复包版本
+// @qver 询问包版本
+// @id   询问ID
+// @dh   密钥交换包
+func OpenFound(f *Found, ver, qver int, id uint64, dh *DHPack) (*Base, *AidInfo, error) {
+	if err := checkDowngrade(qver, ver); err != nil {
+		return nil, nil, err
+	}
+	cdata, err := openContact(ver, FoundContext(ver, id, f.Slot), dh, f.Pubkey, f.Kemct, f.Contact)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DecodeContact(cdata, ver, id)
+}
+
+// EncodeBatchReply 编码批量回复包
+// @b  基础信息（仅用版本和询问ID）
+// @fs 回复条目集
+func EncodeBatchReply(b *Base, fs []*Found) ([]byte, error) {
+	if len(fs) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(fs) > config.BatchMax {
+		return nil, ErrBatchSize
+	}
+	found := make([]*BatchFound, len(fs))
+
+	for i, f := range fs {
+		found[i] = &BatchFound{
+			Slot:    uint32(f.Slot),
+			Pubkey:  f.Pubkey,
+			Contact: f.Contact,
+			Kemct:   f.Kemct,
+		}
+	}
+	buf := &BatchReply{
+		Ver:   int32(b.Ver),
+		Id:    b.ID,
+		Found: found,
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeBatchReply 解码批量回复包
+// 条目中的连系信息保持密文，询问者通过 OpenFound 解密。
+// @return1 基础信息（版本和询问ID）
+// @return2 回复条目集
+func DecodeBatchReply(data []byte) (*Base, []*Found, error) {
+	buf := &BatchReply{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, nil, err
+	}
+	if len(buf.Found) == 0 {
+		return nil, nil, ErrBatchEmpty
+	}
+	if len(buf.Found) > config.BatchMax {
+		return nil, nil, ErrBatchSize
+	}
+	fs := make([]*Found, len(buf.Found))
+
+	for i, f := range buf.Found {
+		fs[i] = &Found{
+			Slot:    int(f.Slot),
+			Pubkey:  f.Pubkey,
+			Contact: f.Contact,
+			Kemct:   f.Kemct,
+		}
+	}
+	return &Base{Ver: int(buf.Ver), ID: buf.Id}, fs, nil
+}
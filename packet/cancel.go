@@ -0,0 +1,66 @@
+package packet
+
+import (
+	"encoding/binary"
+
+	"github.com/cxio/depots/crypto/msg"
+	"google.golang.org/protobuf/proto"
+)
+
+// 撤销消息的上下文前缀
+const cancelContext = "depots:cancel"
+
+// EncodeCancel 编码撤销询问包
+// 用询问时的密钥交换包签名，证明撤销者即询问者。
+// @b  基础信息（版本和询问ID）
+// @dh 发出询问时的密钥交换包
+func EncodeCancel(b *Base, dh *DHPack) ([]byte, error) {
+	sig, err := dh.Sign(CancelMessage(b.Ver, b.ID))
+	if err != nil {
+		return nil, err
+	}
+	buf := &Cancel{
+		Ver:   int32(b.Ver),
+		Id:    b.ID,
+		Signd: sig,
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeCancel 解码撤销询问包
+// 签名需要由持有询问公钥的节点验证（VerifyCancel）。
+// @return1 基础信息（版本和询问ID）
+// @return2 签名数据
+func DecodeCancel(data []byte) (*Base, []byte, error) {
+	buf := &Cancel{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, nil, err
+	}
+	return &Base{Ver: int(buf.Ver), ID: buf.Id}, buf.Signd, nil
+}
+
+// VerifyCancel 验证撤销询问的签名
+// @b   撤销包基础信息
+// @tag 询问包的公钥算法
+// @pub 询问包的公钥
+// @sig 签名数据
+func VerifyCancel(b *Base, tag DHTag, pub, sig []byte) bool {
+	return msg.VerifyDH(tag, pub, CancelMessage(b.Ver, b.ID), sig)
+}
+
+// CancelMessage 构建撤销消息
+// 串联：
+// - 上下文前缀：depots:cancel
+// - 版本：4字节，大端序
+// - 询问ID：8字节，大端序
+func CancelMessage(ver int, id uint64) []byte {
+	n := len(cancelContext)
+	buf := make([]byte, n+12)
+
+	copy(buf, cancelContext)
+	binary.BigEndian.PutUint32(buf[n:], uint32(ver))
+	binary.BigEndian.PutUint64(buf[n+4:], id)
+
+	return buf
+}
@@ -0,0 +1,55 @@
+package packet_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 创建一个随机密钥的密钥交换包。
+func newDH(t testing.TB, tag msg.DHTag) *msg.DHPack {
+	t.Helper()
+
+	key, err := msg.GenerateKey(tag)
+	if err != nil {
+		t.Fatalf("algor %d: %v", tag, err)
+	}
+	return msg.NewDHPack(tag, key)
+}
+
+func TestCancel(t *testing.T) {
+	b := &packet.Base{Ver: packet.Version, ID: 0x1234}
+
+	for _, tag := range msg.DHTags {
+		dh := newDH(t, tag)
+
+		data, err := packet.EncodeCancel(b, dh)
+		if errors.Is(err, msg.ErrDHSign) {
+			// 密钥封装算法不可签名
+			continue
+		}
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		got, sig, err := packet.DecodeCancel(data)
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		pub := dh.PublicBytes()
+
+		if *got != *b || !packet.VerifyCancel(got, tag, pub, sig) {
+			t.Errorf("algor %d: valid cancel rejected", tag)
+		}
+		if packet.VerifyCancel(&packet.Base{Ver: b.Ver, ID: b.ID + 1}, tag, pub, sig) {
+			t.Errorf("algor %d: cancel accepted for another quest", tag)
+		}
+		if packet.VerifyCancel(&packet.Base{Ver: b.Ver + 1, ID: b.ID}, tag, pub, sig) {
+			t.Errorf("algor %d: cancel accepted for another version", tag)
+		}
+		if packet.VerifyCancel(got, tag, newDH(t, tag).PublicBytes(), sig) {
+			t.Errorf("algor %d: cancel accepted for another key", tag)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/protobuf/proto"
+)
+
+// 能力声明的限制
+const (
+	FilterMax    = 8192 // 单个过滤器位图的字节数上限
+	FilterHashes = 16   // 哈希函数个数上限
+	FiltersMax   = 16   // 单个能力声明的过滤器数量上限
+)
+
+// ErrCaps 能力声明包无效（过滤器过多或过大）
+var ErrCaps = errors.New("capability advertisement invalid")
+
+// Filter 紧凑过滤器（布隆过滤器）。
+// 元素为某类数据索引的前缀，如区块链名称、文档索引的前几个字节。
+// 各哈希位置由元素的 SHA3-256 摘要派生：
+// 取摘要的前两个8字节（大端序）为 h1、h2，第i个位置为 (h1 + i*h2) mod 位数。
+type Filter struct {
+	Kind   Kind   // 数据类别
+	Prefix int    // 索引前缀长度（字节数）
+	Hashes int    // 哈希函数个数
+	Bits   []byte // 位图
+}
+
+// NewFilter 创建一个空的过滤器。
+// 按预计元素数和误判率计算位图大小和哈希函数个数，位图不超过 FilterMax 字节。
+// @kind   数据类别
+// @prefix 索引前缀长度
+// @n      预计元素数
+// @fp     误判率（0~1）
+func NewFilter(kind Kind, prefix, n int, fp float64) *Filter {
+	n = max(n, 1)
+	if fp <= 0 || fp >= 1 {
+		fp = 0.01
+	}
+	m := int(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	m = min(max((m+7)/8, 1), FilterMax)
+	k := int(math.Round(float64(m*8) / float64(n) * math.Ln2))
+
+	return &Filter{
+		Kind:   kind,
+		Prefix: prefix,
+		Hashes: min(max(k, 1), FilterHashes),
+		Bits:   make([]byte, m),
+	}
+}
+
+// Add 添加一个元素。
+// 元素超过前缀长度时截取，与查询时的截取一致。
+// 位图为空时忽略。
+// @elem 元素（索引前缀）
+func (f *Filter) Add(elem []byte) {
+	if len(f.Bits) == 0 {
+		return
+	}
+	for _, i := range f.positions(elem) {
+		f.Bits[i/8] |= 1 << (i % 8)
+	}
+}
+
+// Has 检查数据索引是否可能匹配。
+// 假值确定不匹配，真值可能误判。
+// @index 数据索引
+func (f *Filter) Has(index []byte) bool {
+	if len(f.Bits) == 0 {
+		return false
+	}
+	for _, i := range f.positions(index) {
+		if f.Bits[i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Caps 能力声明。
+type Caps struct {
+	Seq     uint64    // 更新序号，较大者有效
+	Kinds   uint32    // 支持的数据类别（位图，第n位对应类别值n）
+	Filters []*Filter // 紧凑过滤器，可选
+}
+
+// NewCaps 创建一个能力声明。
+// 更新序号取当前时间（纳秒），重启后仍然递增。
+// @kinds   支持的数据类别集
+// @filters 紧凑过滤器，可选
+func NewCaps(kinds []Kind, filters ...*Filter) *Caps {
+	c := &Caps{
+		Seq:     uint64(time.Now().UnixNano()),
+		Filters: filters,
+	}
+	for _, k := range kinds {
+		if k < 32 {
+			c.Kinds |= 1 << k
+		}
+	}
+	return c
+}
+
+// Supports 是否支持目标数据类别。
+// @kind 数据类别
+func (c *Caps) Supports(kind Kind) bool {
+	return kind < 32 && c.Kinds&(1<<kind) != 0
+}
+
+// Match 数据是否可能由声明者提供。
+// 不支持该类别时为假。支持但没有该类别的过滤器时为真（未知），
+// 有过滤器时取任一过滤器的匹配结果。
+// @d 数据信息
+func (c *Caps) Match(d *Data) bool {
+	if !c.Supports(d.Kind) {
+		return false
+	}
+	found := false
+
+	for _, f := range c.Filters {
+		if f.Kind != d.Kind {
+			continue
+		}
+		if f.Has(d.Index) {
+			return true
+		}
+		found = true
+	}
+	return !found
+}
+
+// EncodeCaps 编码能力声明包
+// @ver 协议版本（VersionCaps+）
+// @c   能力声明
+func EncodeCaps(ver int, c *Caps) ([]byte, error) {
+	if len(c.Filters) > FiltersMax {
+		return nil, ErrCaps
+	}
+	buf := &CapsAdvert{
+		Ver:     int32(ver),
+		Seq:     c.Seq,
+		Kinds:   c.Kinds,
+		Filters: make([]*CapsFilter, 0, len(c.Filters)),
+	}
+	for _, f := range c.Filters {
+		buf.Filters = append(buf.Filters, &CapsFilter{
+			Kind:   int32(f.Kind),
+			Prefix: uint32(f.Prefix),
+			Hashes: uint32(f.Hashes),
+			Bits:   f.Bits,
+		})
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeCaps 解码能力声明包
+// 过滤器的数量、位图大小和哈希函数个数需在限制之内。
+// @return1 协议版本
+// @return2 能力声明
+func DecodeCaps(data []byte) (int, *Caps, error) {
+	buf := &CapsAdvert{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return 0, nil, err
+	}
+	if len(buf.Filters) > FiltersMax {
+		return 0, nil, ErrCaps
+	}
+	c := &Caps{Seq: buf.Seq, Kinds: buf.Kinds}
+
+	for _, f := range buf.Filters {
+		if len(f.Bits) > FilterMax || f.Hashes == 0 || f.Hashes > FilterHashes ||
+			f.Kind < 0 || f.Kind > 0xff || f.Prefix > 0xff {
+			return 0, nil, ErrCaps
+		}
+		c.Filters = append(c.Filters, &Filter{
+			Kind:   Kind(f.Kind),
+			Prefix: int(f.Prefix),
+			Hashes: int(f.Hashes),
+			Bits:   f.Bits,
+		})
+	}
+	return int(buf.Ver), c, nil
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 计算元素的哈希位置。
+// 元素超过前缀长度时截取。
+func (f *Filter) positions(elem []byte) []uint64 {
+	if f.Prefix > 0 && len(elem) > f.Prefix {
+		elem = elem[:f.Prefix]
+	}
+	sum := sha3.Sum256(elem)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16])
+	m := uint64(len(f.Bits)) * 8
+
+	list := make([]uint64, f.Hashes)
+
+	for i := range list {
+		list[i] = (h1 + uint64(i)*h2) % m
+	}
+	return list
+}
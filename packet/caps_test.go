@@ -0,0 +1,100 @@
+package packet_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cxio/depots/packet"
+)
+
+func TestFilter(t *testing.T) {
+	f := packet.NewFilter(packet.KIND_BLOCKCHAIN, 4, 100, 0.01)
+
+	for i := range 100 {
+		f.Add(fmt.Appendf(nil, "c%03d", i))
+	}
+	for i := range 100 {
+		// 超过前缀长度的索引按前缀匹配
+		if !f.Has(fmt.Appendf(nil, "c%03d-block", i)) {
+			t.Fatalf("element %d not found", i)
+		}
+	}
+	miss := 0
+	for i := range 1000 {
+		if f.Has(fmt.Appendf(nil, "x%03d", i)) {
+			miss++
+		}
+	}
+	if miss > 50 {
+		t.Errorf("false positives: %d/1000", miss)
+	}
+	// 空位图：不崩溃，也不匹配
+	empty := &packet.Filter{Kind: packet.KIND_BLOCKCHAIN, Hashes: 3}
+	empty.Add([]byte("c001"))
+
+	if empty.Has([]byte("c001")) {
+		t.Error("empty filter matched")
+	}
+}
+
+func TestCapsMatch(t *testing.T) {
+	f := packet.NewFilter(packet.KIND_BLOCKCHAIN, 3, 10, 0.01)
+	f.Add([]byte("btc"))
+	c := packet.NewCaps([]packet.Kind{packet.KIND_ARCHIVE, packet.KIND_BLOCKCHAIN}, f)
+
+	tests := []struct {
+		d  *packet.Data
+		ok bool
+	}{
+		{packet.NewData(packet.KIND_BLOCKCHAIN, []byte("btc:0001"), 0), true},
+		{packet.NewData(packet.KIND_BLOCKCHAIN, []byte("eth:0001"), 0), false},
+		// 没有该类别的过滤器
+		{packet.NewData(packet.KIND_ARCHIVE, []byte("anything"), 0), true},
+		// 不支持的类别
+		{packet.NewData(packet.Kind(5), []byte("btc:0001"), 0), false},
+		{packet.NewData(packet.Kind(40), []byte("btc:0001"), 0), false},
+	}
+	for i, tt := range tests {
+		if got := c.Match(tt.d); got != tt.ok {
+			t.Errorf("case %d: %v", i, got)
+		}
+	}
+	// 同类别多个过滤器，任一匹配即可
+	g := packet.NewFilter(packet.KIND_BLOCKCHAIN, 3, 10, 0.01)
+	g.Add([]byte("eth"))
+	c.Filters = append(c.Filters, g)
+
+	if !c.Match(tests[1].d) || !c.Match(tests[0].d) {
+		t.Error("second filter ignored")
+	}
+}
+
+func TestCapsEncode(t *testing.T) {
+	f := packet.NewFilter(packet.KIND_BLOCKCHAIN, 3, 10, 0.01)
+	f.Add([]byte("btc"))
+	c := packet.NewCaps([]packet.Kind{packet.KIND_BLOCKCHAIN}, f)
+
+	data, err := packet.EncodeCaps(packet.VersionCaps, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver, got, err := packet.DecodeCaps(data)
+	if err != nil || ver != packet.VersionCaps {
+		t.Fatal(ver, err)
+	}
+	if got.Seq != c.Seq || got.Kinds != c.Kinds || len(got.Filters) != 1 || !got.Filters[0].Has([]byte("btc1")) {
+		t.Errorf("decoded: %+v", got)
+	}
+	c.Filters = make([]*packet.Filter, packet.FiltersMax+1)
+	if _, err = packet.EncodeCaps(packet.VersionCaps, c); !errors.Is(err, packet.ErrCaps) {
+		t.Errorf("too many filters: %v", err)
+	}
+	// 哈希个数超出限制
+	c.Filters = []*packet.Filter{{Kind: packet.KIND_BLOCKCHAIN, Hashes: packet.FilterHashes + 1, Bits: []byte{1}}}
+	data, _ = packet.EncodeCaps(packet.VersionCaps, c)
+
+	if _, _, err = packet.DecodeCaps(data); !errors.Is(err, packet.ErrCaps) {
+		t.Errorf("too many hashes: %v", err)
+	}
+}
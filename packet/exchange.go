@@ -0,0 +1,141 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/cxio/depots/crypto/msg"
+	"google.golang.org/protobuf/proto"
+)
+
+// 节点交换签名消息的上下文前缀
+const exchangeContext = "depots:pex"
+
+// ExchangeMax 单个节点交换包的地址条目上限
+const ExchangeMax = 64
+
+// ErrExchange 节点交换包无效（缺少签名、条目过多或地址错误）
+var ErrExchange = errors.New("peer exchange packet invalid")
+
+// PeerAddr 节点交换的地址条目。
+type PeerAddr struct {
+	Addr  netip.AddrPort // 节点地址
+	Level NatLevel       // NAT 层级
+	Kinds uint32         // 支持的数据类别（位图，第n位对应类别值n）
+	Seen  time.Time      // 最近核实时间（秒精度）
+}
+
+// EncodeExchange 编码节点交换包
+// 签名必须，时间戳取当前时间。
+// @ver   协议版本（VersionPex+）
+// @addrs 地址样本，不超过 ExchangeMax 条
+// @sp    签名封包（身份密钥）
+func EncodeExchange(ver int, addrs []*PeerAddr, sp *SignPack) ([]byte, error) {
+	if sp == nil || len(addrs) > ExchangeMax {
+		return nil, ErrExchange
+	}
+	now := time.Unix(time.Now().Unix(), 0)
+
+	sig, err := sp.Sign(ExchangeMessage(ver, sp.Algor, now, addrs))
+	if err != nil {
+		return nil, err
+	}
+	buf := &Exchange{
+		Ver:    int32(ver),
+		Time:   now.Unix(),
+		Addrs:  make([]*ExchangeItem, 0, len(addrs)),
+		Algor:  int32(sp.Algor),
+		Pubkey: sp.PublicBytes(),
+		Signd:  sig,
+	}
+	for _, a := range addrs {
+		buf.Addrs = append(buf.Addrs, &ExchangeItem{
+			Ip:    a.Addr.Addr().AsSlice(),
+			Port:  uint32(a.Addr.Port()),
+			Level: int32(a.Level),
+			Kinds: a.Kinds,
+			Seen:  a.Seen.Unix(),
+		})
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeExchange 解码节点交换包
+// 内部会验证签名，公钥和签名的长度需与签名算法相符。
+// 返回的公钥可用于外部核实发送方的身份（与连接握手时的身份比对）。
+// @return1 地址样本
+// @return2 签名时间
+// @return3 签名公钥
+func DecodeExchange(data []byte) ([]*PeerAddr, time.Time, []byte, error) {
+	buf := &Exchange{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, time.Time{}, nil, err
+	}
+	if len(buf.Addrs) > ExchangeMax || len(buf.Pubkey) == 0 {
+		return nil, time.Time{}, nil, ErrExchange
+	}
+	sp := msg.NewSignPack(SignTag(buf.Algor), nil)
+	if sp == nil {
+		return nil, time.Time{}, nil, ErrAlgor
+	}
+	if len(buf.Pubkey) != sp.Algor.PublicSize() ||
+		len(buf.Signd) != sp.Algor.SignatureSize() {
+		return nil, time.Time{}, nil, ErrSignLen
+	}
+	addrs := make([]*PeerAddr, 0, len(buf.Addrs))
+
+	for _, a := range buf.Addrs {
+		ip, ok := netip.AddrFromSlice(a.Ip)
+		if !ok || a.Port > 0xffff {
+			return nil, time.Time{}, nil, ErrExchange
+		}
+		addrs = append(addrs, &PeerAddr{
+			Addr:  netip.AddrPortFrom(ip, uint16(a.Port)),
+			Level: NatLevel(a.Level),
+			Kinds: a.Kinds,
+			Seen:  time.Unix(a.Seen, 0),
+		})
+	}
+	t := time.Unix(buf.Time, 0)
+
+	if !sp.Verify(buf.Pubkey, ExchangeMessage(int(buf.Ver), sp.Algor, t, addrs), buf.Signd) {
+		return nil, time.Time{}, nil, ErrSign
+	}
+	return addrs, t, buf.Pubkey, nil
+}
+
+// ExchangeMessage 构建节点交换包的签名消息
+// 串联（整数均为大端序）：
+// - 上下文前缀：depots:pex
+// - 版本：4字节
+// - 签名算法：1字节
+// - 签名时间：8字节，Unix秒
+// - 条目数：2字节
+// 之后为各条目，每个条目串联：
+// - IP：16字节，IPv4为映射形式
+// - 端口：2字节
+// - NAT层级：1字节
+// - 类别位图：4字节
+// - 核实时间：8字节，Unix秒
+func ExchangeMessage(ver int, algor SignTag, t time.Time, addrs []*PeerAddr) []byte {
+	buf := make([]byte, 0, len(exchangeContext)+15+len(addrs)*31)
+
+	buf = append(buf, exchangeContext...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(ver))
+	buf = append(buf, byte(algor))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(t.Unix()))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(addrs)))
+
+	for _, a := range addrs {
+		ip := a.Addr.Addr().As16()
+		buf = append(buf, ip[:]...)
+		buf = binary.BigEndian.AppendUint16(buf, a.Addr.Port())
+		buf = append(buf, byte(a.Level))
+		buf = binary.BigEndian.AppendUint32(buf, a.Kinds)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(a.Seen.Unix()))
+	}
+	return buf
+}
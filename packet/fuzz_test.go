@@ -0,0 +1,135 @@
+package packet_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 模糊测试的种子数据
+var fuzzData = packet.NewData(packet.KIND_BLOCKCHAIN, []byte("btc:block:1"), 4096)
+
+// 创建种子添加函数，编码失败时终止。
+// 用法：seed(packet.EncodeXXX(...))
+func seeder(f *testing.F) func([]byte, error) {
+	return func(data []byte, err error) {
+		f.Helper()
+
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzDecodeProbe(f *testing.F) {
+	seed := seeder(f)
+
+	for _, tag := range msg.SignTags {
+		for _, ver := range []int{packet.Version, packet.VersionStamp} {
+			seed(packet.EncodeProbe(packet.NewBase(ver, 0, 0, 0), fuzzData, newPack(f, tag)))
+		}
+	}
+	seed(packet.EncodeProbe(packet.NewBase(packet.VersionStamp, 0, 3, 0), fuzzData, nil))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, d, algor, pub, st, err := packet.DecodeProbe(data)
+		if err != nil {
+			return
+		}
+		if d == nil || (len(pub) > 0 && algor < 0) || (st != nil && len(pub) == 0) {
+			t.Fatalf("inconsistent probe: algor %d, pub %x, stamp %v", algor, pub, st)
+		}
+	})
+}
+
+func FuzzDecodeQuest(f *testing.F) {
+	seed := seeder(f)
+
+	for _, tag := range msg.DHTags {
+		seed(packet.EncodeQuest(packet.NewBase(packet.VersionBind, 7, 0, packet.NAT_LEVEL_RC), fuzzData, newDH(f, tag)))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, d, _, _, err := packet.DecodeQuest(data)
+		if err == nil && (b == nil || d == nil) {
+			t.Fatal("nil result without error")
+		}
+	})
+}
+
+func FuzzDecodeReply(f *testing.F) {
+	seed := seeder(f)
+	quest := newDH(f, msg.DH_X25519)
+	a := &packet.AidInfo{Network: "udp", IP: netip.MustParseAddr("10.0.0.1"), Port: 7790}
+
+	for _, ver := range []int{packet.VersionStamp, packet.VersionBind} {
+		seed(packet.EncodeReply(packet.NewBase(ver, 7, 1, 0), a, newDH(f, msg.DH_X25519), quest.PublicBytes()))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		packet.ReplyID(data)
+
+		if _, a, err := packet.DecodeReply(data, packet.VersionBind, quest); err == nil && a == nil {
+			t.Fatal("nil contact without error")
+		}
+		if _, fs, err := packet.DecodeBatchReply(data); err == nil {
+			for _, f := range fs {
+				packet.OpenFound(f, packet.VersionBind, packet.VersionBind, 7, quest)
+			}
+		}
+	})
+}
+
+func FuzzDecodeBatch(f *testing.F) {
+	seed := seeder(f)
+	its := packet.NewItems([][]byte{[]byte("a"), []byte("b"), []byte("c")}, []uint32{1, 2})
+	seed(packet.EncodeBatch(packet.NewBase(packet.VersionBind, 7, 0, 0), packet.KIND_ARCHIVE, its, newDH(f, msg.DH_X25519)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, its, _, _, err := packet.DecodeBatch(data)
+		if err != nil {
+			return
+		}
+		if len(its) == 0 || len(its) > 256 {
+			t.Fatalf("batch items: %d", len(its))
+		}
+	})
+}
+
+func FuzzDecodeExchange(f *testing.F) {
+	seed := seeder(f)
+	addrs := []*packet.PeerAddr{
+		{Addr: netip.MustParseAddrPort("10.0.0.1:7790"), Level: packet.NAT_LEVEL_RC, Kinds: 3, Seen: time.Unix(1767225600, 0)},
+		{Addr: netip.MustParseAddrPort("[2001:db8::1]:7790"), Seen: time.Unix(1767225600, 0)},
+	}
+	for _, tag := range msg.SignTags {
+		seed(packet.EncodeExchange(packet.VersionPex, addrs, newPack(f, tag)))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		addrs, _, pub, err := packet.DecodeExchange(data)
+		if err != nil {
+			return
+		}
+		if len(pub) == 0 || len(addrs) > packet.ExchangeMax {
+			t.Fatalf("exchange: pub %x, %d addrs", pub, len(addrs))
+		}
+	})
+}
+
+func FuzzDecodeCaps(f *testing.F) {
+	seed := seeder(f)
+	flt := packet.NewFilter(packet.KIND_BLOCKCHAIN, 4, 100, 0.01)
+	flt.Add(fuzzData.Index)
+	seed(packet.EncodeCaps(packet.VersionCaps, packet.NewCaps([]packet.Kind{packet.KIND_BLOCKCHAIN}, flt)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, c, err := packet.DecodeCaps(data)
+		if err != nil {
+			return
+		}
+		// 外来过滤器参与匹配，不得恐慌
+		c.Match(fuzzData)
+	})
+}
@@ -0,0 +1,270 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/cxio/depots/crypto/msg"
+	"google.golang.org/protobuf/proto"
+)
+
+// Xnet 传输协议标识
+type Xnet int
+
+// 支持的几种传输协议
+const (
+	XNET_WEBSOCKET Xnet = iota // websocket
+	XNET_DTLS                  // dtls
+	XNET_TCP                   // 原始TCP
+	XNET_UDP                   // 原始UDP
+)
+
+// 传输协议名称
+// 与 AidInfo.Network 的取值对应。
+var xnetNames = []string{"websocket", "dtls", "tcp", "udp"}
+
+func (x Xnet) String() string {
+	if x < 0 || int(x) >= len(xnetNames) {
+		return fmt.Sprintf("xnet(%d)", int(x))
+	}
+	return xnetNames[x]
+}
+
+// Xnets 本地支持的传输协议清单
+var Xnets = []Xnet{XNET_WEBSOCKET, XNET_DTLS, XNET_TCP, XNET_UDP}
+
+var (
+	// ErrVersion 版本范围无交集
+	ErrVersion = errors.New("no common protocol version")
+
+	// ErrNoDH 没有共同的密钥交换算法
+	ErrNoDH = errors.New("no common key exchange algorithm")
+
+	// ErrNoXnet 没有共同的传输协议
+	ErrNoXnet = errors.New("no common transport")
+
+	// ErrAccept 协商结果不合法（超出己方声明的范围）
+	ErrAccept = errors.New("accepted parameters beyond the offer")
+)
+
+// Refused 对端拒绝连接。
+// 包含对端给出的原因。
+type Refused struct {
+	Reason string
+}
+
+func (e *Refused) Error() string {
+	return "refused by peer: " + e.Reason
+}
+
+// Features 能力声明。
+// 各功能集为位图，第n位对应标识值为n的算法或协议。
+// 注：算法标识值小于16，32位足够。
+type Features struct {
+	Vmin  int    // 支持的最低版本
+	Vmax  int    // 支持的最高版本
+	Signs uint32 // 签名算法集
+	DHs   uint32 // 密钥交换算法集
+	Xnets uint32 // 传输协议集
+}
+
+// Local 本地的能力声明。
+func Local() *Features {
+	f := &Features{
+		Vmin: VersionMin,
+		Vmax: VersionMax,
+	}
+	for _, t := range msg.SignTags {
+		f.Signs |= 1 << t
+	}
+	for _, t := range msg.DHTags {
+		f.DHs |= 1 << t
+	}
+	for _, x := range Xnets {
+		f.Xnets |= 1 << x
+	}
+	return f
+}
+
+// Params 协商确定的共同参数。
+type Params struct {
+	Ver   int    // 通讯版本
+	Signs uint32 // 共同的签名算法集
+	DHs   uint32 // 共同的密钥交换算法集
+	Xnets uint32 // 共同的传输协议集
+}
+
+// SignOK 是否支持目标签名算法。
+func (p *Params) SignOK(tag SignTag) bool {
+	return tag >= 0 && tag < 32 && p.Signs&(1<<tag) != 0
+}
+
+// DHOK 是否支持目标密钥交换算法。
+func (p *Params) DHOK(tag DHTag) bool {
+	return tag >= 0 && tag < 32 && p.DHs&(1<<tag) != 0
+}
+
+// XnetOK 是否支持目标传输协议。
+func (p *Params) XnetOK(x Xnet) bool {
+	return x >= 0 && x < 32 && p.Xnets&(1<<x) != 0
+}
+
+// DH 首选的密钥交换算法。
+// 取共同集中标识值最小者（惯用算法优先）。
+func (p *Params) DH() DHTag {
+	return DHTag(bits.TrailingZeros32(p.DHs))
+}
+
+// Negotiate 协商共同参数。
+// 版本取双方共同范围内的最高者，功能集取交集。
+// 签名为可选功能，交集可为空；密钥交换和传输协议则必须有共同项。
+// 协商过程对称，双方各自计算的结果相同。
+// @local 己方能力
+// @peer  对端能力
+func Negotiate(local, peer *Features) (*Params, error) {
+	ver := min(local.Vmax, peer.Vmax)
+
+	if ver < max(local.Vmin, peer.Vmin) {
+		return nil, ErrVersion
+	}
+	p := &Params{
+		Ver:   ver,
+		Signs: local.Signs & peer.Signs,
+		DHs:   local.DHs & peer.DHs,
+		Xnets: local.Xnets & peer.Xnets,
+	}
+	if p.DHs == 0 {
+		return nil, ErrNoDH
+	}
+	if p.Xnets == 0 {
+		return nil, ErrNoXnet
+	}
+	return p, nil
+}
+
+//
+// 握手流程：
+// 1. 发起方发送 Hello（EncodeHello）。
+// 2. 接收方解码后协商（Respond），回应 Accept，
+//    无法兼容时回应拒绝并关闭连接。
+// 3. 发起方检查回应（Settle），获得共同参数。
+//////////////////////////////////////////////////////////////////////////////
+
+// EncodeHello 编码能力声明
+func EncodeHello(f *Features) ([]byte, error) {
+	buf := &Hello{
+		Vmin:  int32(f.Vmin),
+		Vmax:  int32(f.Vmax),
+		Signs: f.Signs,
+		Dhs:   f.DHs,
+		Xnets: f.Xnets,
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeHello 解码能力声明
+func DecodeHello(data []byte) (*Features, error) {
+	buf := &Hello{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, err
+	}
+	f := &Features{
+		Vmin:  int(buf.Vmin),
+		Vmax:  int(buf.Vmax),
+		Signs: buf.Signs,
+		DHs:   buf.Dhs,
+		Xnets: buf.Xnets,
+	}
+	return f, nil
+}
+
+// EncodeAccept 编码协商结果
+func EncodeAccept(p *Params) ([]byte, error) {
+	buf := &Accept{
+		Ver:   int32(p.Ver),
+		Signs: p.Signs,
+		Dhs:   p.DHs,
+		Xnets: p.Xnets,
+	}
+	return proto.Marshal(buf)
+}
+
+// EncodeRefuse 编码拒绝回应
+// @reason 拒绝原因，通常为协商错误的描述
+func EncodeRefuse(reason string) ([]byte, error) {
+	return proto.Marshal(&Accept{Refuse: reason})
+}
+
+// DecodeAccept 解码协商结果
+// 如果对端拒绝，返回 *Refused 错误。
+func DecodeAccept(data []byte) (*Params, error) {
+	buf := &Accept{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, err
+	}
+	if buf.Refuse != "" {
+		return nil, &Refused{Reason: buf.Refuse}
+	}
+	p := &Params{
+		Ver:   int(buf.Ver),
+		Signs: buf.Signs,
+		DHs:   buf.Dhs,
+		Xnets: buf.Xnets,
+	}
+	return p, nil
+}
+
+// Respond 接收方处理能力声明。
+// 解码对端的声明并协商，返回应当回应的编码数据：
+// 成功时为 Accept，无法兼容时为拒绝回应，同时返回协商错误，回应发送后应关闭连接。
+// 声明无法解码时没有回应数据。
+// @local 己方能力
+// @data  对端声明的编码数据
+// @return1 回应数据
+// @return2 协商的共同参数
+func Respond(local *Features, data []byte) ([]byte, *Params, error) {
+	peer, err := DecodeHello(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := Negotiate(local, peer)
+	if err != nil {
+		reply, err2 := EncodeRefuse(err.Error())
+		if err2 != nil {
+			return nil, nil, err2
+		}
+		return reply, nil, err
+	}
+	reply, err := EncodeAccept(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reply, p, nil
+}
+
+// Settle 发起方确认协商结果。
+// 对端回应的参数必须在己方声明的范围内，否则视为不兼容。
+// @local 己方声明的能力
+// @data  对端回应的编码数据
+func Settle(local *Features, data []byte) (*Params, error) {
+	p, err := DecodeAccept(data)
+	if err != nil {
+		return nil, err
+	}
+	if p.Ver < local.Vmin || p.Ver > local.Vmax ||
+		p.Signs&^local.Signs != 0 ||
+		p.DHs&^local.DHs != 0 ||
+		p.Xnets&^local.Xnets != 0 {
+		return nil, ErrAccept
+	}
+	if p.DHs == 0 {
+		return nil, ErrNoDH
+	}
+	if p.Xnets == 0 {
+		return nil, ErrNoXnet
+	}
+	return p, nil
+}
@@ -0,0 +1,149 @@
+package packet_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+func TestNegotiate(t *testing.T) {
+	local := &packet.Features{Vmin: 0x10, Vmax: 0x14, Signs: 0b0110, DHs: 0b0011, Xnets: 0b1100}
+	peer := &packet.Features{Vmin: 0x12, Vmax: 0x16, Signs: 0b1000, DHs: 0b0110, Xnets: 0b0101}
+
+	p, err := packet.Negotiate(local, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 共同范围内的最高版本，功能取交集（签名交集可为空）
+	if p.Ver != 0x14 || p.Signs != 0 || p.DHs != 0b0010 || p.Xnets != 0b0100 {
+		t.Errorf("params: %+v", p)
+	}
+	if p.DH() != msg.DHTag(1) || !p.DHOK(1) || p.DHOK(0) || !p.XnetOK(packet.XNET_TCP) || p.XnetOK(packet.XNET_UDP) {
+		t.Errorf("params lookup: %+v", p)
+	}
+	// 协商对称
+	if q, err := packet.Negotiate(peer, local); err != nil || *q != *p {
+		t.Errorf("asymmetric: %+v, %v", q, err)
+	}
+	// 仅一个版本重合
+	peer.Vmin, peer.Vmax = 0x08, 0x10
+	if p, err = packet.Negotiate(local, peer); err != nil || p.Ver != 0x10 {
+		t.Errorf("single common version: %+v, %v", p, err)
+	}
+}
+
+func TestNegotiateRefuse(t *testing.T) {
+	local := &packet.Features{Vmin: 0x10, Vmax: 0x14, DHs: 0b0011, Xnets: 0b1100}
+
+	tests := []struct {
+		name string
+		peer packet.Features
+		err  error
+	}{
+		{"older", packet.Features{Vmin: 0x01, Vmax: 0x0f, DHs: 1, Xnets: 4}, packet.ErrVersion},
+		{"newer", packet.Features{Vmin: 0x15, Vmax: 0x20, DHs: 1, Xnets: 4}, packet.ErrVersion},
+		{"inverted", packet.Features{Vmin: 0x14, Vmax: 0x10, DHs: 1, Xnets: 4}, packet.ErrVersion},
+		{"dh", packet.Features{Vmin: 0x10, Vmax: 0x14, DHs: 0b0100, Xnets: 4}, packet.ErrNoDH},
+		{"xnet", packet.Features{Vmin: 0x10, Vmax: 0x14, DHs: 1, Xnets: 0b0011}, packet.ErrNoXnet},
+	}
+	for _, tt := range tests {
+		if _, err := packet.Negotiate(local, &tt.peer); !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		// 接收方回应拒绝原因
+		hello, err := packet.EncodeHello(&tt.peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, p, err := packet.Respond(local, hello)
+		if !errors.Is(err, tt.err) || p != nil || reply == nil {
+			t.Errorf("%s: respond: %v", tt.name, err)
+			continue
+		}
+		var refused *packet.Refused
+		if _, err = packet.Settle(&tt.peer, reply); !errors.As(err, &refused) || refused.Reason != tt.err.Error() {
+			t.Errorf("%s: settle: %v", tt.name, err)
+		}
+	}
+}
+
+func TestHello(t *testing.T) {
+	local := packet.Local()
+
+	data, err := packet.EncodeHello(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := packet.DecodeHello(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *f != *local {
+		t.Errorf("hello: %+v", f)
+	}
+	for _, tag := range msg.DHTags {
+		if local.DHs&(1<<tag) == 0 {
+			t.Errorf("dh %d not declared", tag)
+		}
+	}
+	if _, err = packet.DecodeHello([]byte{0xff}); err == nil {
+		t.Error("bad hello decoded")
+	}
+	p := &packet.Params{Ver: 0x13, Signs: 0b0010, DHs: 0b0110, Xnets: 0b1000}
+
+	if data, err = packet.EncodeAccept(p); err != nil {
+		t.Fatal(err)
+	}
+	if q, err := packet.DecodeAccept(data); err != nil || *q != *p {
+		t.Errorf("accept: %+v, %v", q, err)
+	}
+}
+
+func TestSettle(t *testing.T) {
+	cli := &packet.Features{Vmin: packet.VersionMin, Vmax: packet.VersionMax, Signs: 0b0110, DHs: 0b0011, Xnets: 0b1100}
+	srv := packet.Local()
+
+	hello, err := packet.EncodeHello(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, sp, err := packet.Respond(srv, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := packet.Settle(cli, reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cp != *sp || cp.Ver != packet.VersionMax {
+		t.Errorf("settled: %+v, %+v", cp, sp)
+	}
+	if _, _, err = packet.Respond(srv, []byte{0xff}); err == nil {
+		t.Error("bad hello answered")
+	}
+	// 回应超出己方声明的范围
+	tests := []struct {
+		name string
+		p    packet.Params
+		err  error
+	}{
+		{"version low", packet.Params{Ver: packet.VersionMin - 1, DHs: 1, Xnets: 4}, packet.ErrAccept},
+		{"version high", packet.Params{Ver: packet.VersionMax + 1, DHs: 1, Xnets: 4}, packet.ErrAccept},
+		{"sign", packet.Params{Ver: packet.VersionMax, Signs: 0b1000, DHs: 1, Xnets: 4}, packet.ErrAccept},
+		{"dh", packet.Params{Ver: packet.VersionMax, DHs: 0b0101, Xnets: 4}, packet.ErrAccept},
+		{"xnet", packet.Params{Ver: packet.VersionMax, DHs: 1, Xnets: 0b0110}, packet.ErrAccept},
+		{"no dh", packet.Params{Ver: packet.VersionMax, Xnets: 4}, packet.ErrNoDH},
+		{"no xnet", packet.Params{Ver: packet.VersionMax, DHs: 1}, packet.ErrNoXnet},
+	}
+	for _, tt := range tests {
+		data, err := packet.EncodeAccept(&tt.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = packet.Settle(cli, data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
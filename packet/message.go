@@ -5,16 +5,35 @@ import (
 	"encoding/binary"
 	"errors"
 	"net/netip"
+	"time"
 
 	"github.com/cxio/depots/base"
 	"github.com/cxio/depots/crypto/msg"
 	"github.com/cxio/findings/stun"
+	"golang.org/x/crypto/sha3"
 	"google.golang.org/protobuf/proto"
 )
 
 // Version 数据包版本（0xf）
 const Version = 0b0000_1111
 
+// 协议版本演进。
+// 新版本的特性以版本号区分，低于该版本的数据包按旧格式处理。
+const (
+	VersionStamp  = 0x10 // 探测包签名含时间戳和随机数（防重放）
+	VersionBind   = 0x11 // 连系信息加密绑定上下文（HKDF + 附加数据）
+	VersionReport = 0x12 // 核实报告包（Report）
+	VersionPex    = 0x13 // 节点交换包（Exchange）
+	VersionCaps   = 0x14 // 能力声明包（CapsAdvert）
+)
+
+// 本地支持的协议版本范围。
+// 与对端握手时声明，双方取共同范围内的最高版本。
+const (
+	VersionMin = Version     // 最低兼容版本
+	VersionMax = VersionCaps // 最高支持版本
+)
+
 // HopsMax 转播跳数最大值。
 const HopsMax = 0x0F
 
@@ -42,11 +61,19 @@ type SignTag = msg.SignTag
 // SignPack 类型引用（签名）
 type SignPack = msg.SignPack
 
-// 三个消息包标识
+// 消息包标识
 const (
-	PACKET_PROBE byte = iota // 探测包
-	PACKET_QUEST             // 询问包
-	PACKET_REPLY             // 回复包
+	PACKET_PROBE      byte = iota // 探测包
+	PACKET_QUEST                  // 询问包
+	PACKET_REPLY                  // 回复包
+	PACKET_HELLO                  // 握手：能力声明
+	PACKET_ACCEPT                 // 握手：协商结果
+	PACKET_BATCH                  // 批量询问包
+	PACKET_BATCHREPLY             // 批量回复包
+	PACKET_CANCEL                 // 撤销询问包
+	PACKET_REPORT                 // 核实报告包
+	PACKET_EXCHANGE               // 节点交换包
+	PACKET_CAPS                   // 能力声明包
 )
 
 var (
@@ -59,13 +86,13 @@ var (
 	// ErrSign 签名验证失败错误
 	ErrSign = errors.New("signature verification failed")
 
+	// ErrSignLen 公钥或签名长度与算法不符
+	ErrSignLen = errors.New("public key or signature size mismatch")
+
 	// IP 解析错误。
 	ErrParseIP = errors.New("parse ip bytes failed")
 )
 
-// 通用日志记录器
-var loger = base.Log
-
 // 数据类别
 type Kind byte
 
@@ -92,7 +119,10 @@ type Base struct {
 func NewBase(ver int, id uint64, hops int, lev NatLevel) *Base {
 	if lev < 0 ||
 		lev > NAT_LEVEL_SYM {
-		loger.Printf("[Warning] NAT level: %d is invalid.\n", lev)
+		// 日志可能尚未初始化
+		if base.Log != nil {
+			base.Log.Printf("[Warning] NAT level: %d is invalid.\n", lev)
+		}
 		lev = NAT_LEVEL_SYM
 	}
 	return &Base{
@@ -115,8 +145,10 @@ func (b *Base) HopAdd(n int) error {
 }
 
 // VersionOK 版本是否兼容
+// 数据包版本应当不低于本地最低兼容版本，且不高于与对端协商的版本。
+// @ver 与对端协商确定的版本（参考 Params.Ver）
 func (b *Base) VersionOK(ver int) bool {
-	return ver >= b.Ver
+	return b.Ver >= VersionMin && b.Ver <= ver
 }
 
 // Data 数据信息
@@ -145,6 +177,18 @@ type AidInfo struct {
 	Fip     netip.Addr // Findings节点IP
 	Fport   int        // Findings节点服务端口
 	Fkind   string     // 数据节点的Findings登记类别名
+	Stake   *Stake     // 权益声明，可选
+}
+
+// VerifyStake 验证权益声明的签名。
+// 连系端点取自本协助信息，询问ID取自所在的回复。
+// 没有权益声明或未签名时返回假。
+// @id 询问ID
+func (a *AidInfo) VerifyStake(id uint64) bool {
+	if a.Stake == nil {
+		return false
+	}
+	return a.Stake.Verify(id, a.Network, netip.AddrPortFrom(a.IP, uint16(a.Port)))
 }
 
 //
@@ -194,60 +238,109 @@ func DecodeQuest(data []byte) (*Base, *Data, DHTag, []byte, error) {
 }
 
 // EncodeProbe 编码探测包
+// 签名为可选，sp为nil时不签名。
+// 新版本（VersionStamp+）的签名包含时间戳和随机数。
 // @b  基础信息包
 // @d  请求的数据信息
-// @sp 签名封包
+// @sp 签名封包，可选
 func EncodeProbe(b *Base, d *Data, sp *SignPack) ([]byte, error) {
-	// 数据消息
-	msg := DataMessage(byte(d.Kind), d.Index, d.Size)
-
 	buf := &Probe{
-		Ver:    int32(b.Ver),
-		Hops:   int32(b.Hops),
-		Algor:  int32(sp.Algor),
-		Pubkey: sp.PublicBytes(),
-		Signd:  sp.Sign(msg),
-		Kind:   int32(d.Kind),
-		Index:  d.Index,
-		Size:   uint32(d.Size),
+		Ver:   int32(b.Ver),
+		Hops:  int32(b.Hops),
+		Kind:  int32(d.Kind),
+		Index: d.Index,
+		Size:  uint32(d.Size),
+	}
+	if sp != nil {
+		// 数据消息（旧版本）
+		msg := DataMessage(byte(d.Kind), d.Index, d.Size)
+
+		if b.Ver >= VersionStamp {
+			st, err := NewStamp()
+			if err != nil {
+				return nil, err
+			}
+			buf.Stamp, buf.Nonce = st.Time.Unix(), st.Nonce
+
+			if msg, err = ProbeMessage(b.Ver, sp.Algor, d, st); err != nil {
+				return nil, err
+			}
+		}
+		sig, err := sp.Sign(msg)
+		if err != nil {
+			return nil, err
+		}
+		buf.Algor = int32(sp.Algor)
+		buf.Pubkey = sp.PublicBytes()
+		buf.Signd = sig
 	}
 	return proto.Marshal(buf)
 }
 
 // DecodeProbe 解码探测包
-// 如果存在公钥，内部会先验证签名数据的有效性。
-// 返回的公钥可用于外部的支持清单核实。
+// 如果存在公钥，内部会先验证签名数据的有效性，
+// 公钥和签名的长度需与签名算法相符。
+// 返回的签名算法和公钥可用于外部的支持清单核实。
+// 新版本的签名探测包会返回时间戳信息，外部据此执行重放检查，
+// 旧版本（兼容路径）或未签名的探测包，时间戳信息为nil。
 // @return1 基础信息包
 // @return2 目标数据信息
-// @return3 公钥字节序列
-func DecodeProbe(data []byte) (*Base, *Data, []byte, error) {
+// @return3 签名算法，未签名时为-1
+// @return4 公钥字节序列
+// @return5 签名时间戳信息
+func DecodeProbe(data []byte) (*Base, *Data, SignTag, []byte, *Stamp, error) {
 	buf := &Probe{}
 
 	if err := proto.Unmarshal(data, buf); err != nil {
-		return nil, nil, nil, err
+		return nil, nil, -1, nil, nil, err
 	}
+	var st *Stamp
+	algor := SignTag(-1)
+
 	// 如果有签名（可选）
 	if len(buf.Pubkey) > 0 {
 		sp := msg.NewSignPack(SignTag(buf.Algor), nil)
 		if sp == nil {
-			return nil, nil, nil, ErrAlgor
+			return nil, nil, -1, nil, nil, ErrAlgor
+		}
+		// 长度先行检查，避免无效数据进入验证
+		if len(buf.Pubkey) != sp.Algor.PublicSize() ||
+			len(buf.Signd) != sp.Algor.SignatureSize() {
+			return nil, nil, -1, nil, nil, ErrSignLen
+		}
+		// 旧版本（兼容路径）
+		m := DataMessage(byte(buf.Kind), buf.Index, buf.Size)
+
+		if buf.Ver >= VersionStamp {
+			if len(buf.Nonce) != NonceSize {
+				return nil, nil, -1, nil, nil, ErrStamp
+			}
+			st = &Stamp{Time: time.Unix(buf.Stamp, 0), Nonce: buf.Nonce}
+			d := NewData(Kind(buf.Kind), buf.Index, buf.Size)
+			var err error
+
+			if m, err = ProbeMessage(int(buf.Ver), SignTag(buf.Algor), d, st); err != nil {
+				return nil, nil, -1, nil, nil, err
+			}
 		}
 		// 验证签名
-		msg := DataMessage(byte(buf.Algor), buf.Index, buf.Size)
-
-		if !sp.Verify(buf.Pubkey, msg, buf.Signd) {
-			return nil, nil, nil, ErrSign
+		if !sp.Verify(buf.Pubkey, m, buf.Signd) {
+			return nil, nil, -1, nil, nil, ErrSign
 		}
+		algor = sp.Algor
 	}
-	b := NewBase(int(buf.Ver), 0, int(buf.Hops), NAT_LEVEL_UNDEFINED)
+	// 探测包无ID和NAT层级
+	b := &Base{Ver: int(buf.Ver), Hops: int(buf.Hops), Level: NAT_LEVEL_UNDEFINED}
 	d := NewData(Kind(buf.Kind), buf.Index, buf.Size)
 
-	return b, d, buf.Pubkey, nil
+	return b, d, algor, buf.Pubkey, st, nil
 }
 
 // EncodeReply 编码回复包
 // 连系信息会被加密传输，
 // 密钥交换包用于加密连系信息，以及输出自己的公钥。
+// 密钥封装算法（KEM）时，密钥交换包无需私钥，回复中携带封装密文而非公钥。
+// 加密模式由基础信息的版本决定（参见 ReplyContext）。
 // @b   基础信息
 // @c   连系信息
 // @dh  密钥交换包
@@ -258,7 +351,7 @@ func EncodeReply(b *Base, a *AidInfo, dh *DHPack, pub []byte) ([]byte, error) {
 		return nil, err
 	}
 	// 连系信息加密
-	xdata, err := dh.Encrypt(pub, data)
+	ct, xdata, err := sealContact(b.Ver, ReplyContext(b.Ver, b.ID), dh, pub, data)
 	if err != nil {
 		return nil, err
 	}
@@ -267,24 +360,33 @@ func EncodeReply(b *Base, a *AidInfo, dh *DHPack, pub []byte) ([]byte, error) {
 		Id:      b.ID,
 		Pubkey:  dh.PublicBytes(),
 		Contact: xdata,
+		Kemct:   ct,
 	}
 	return proto.Marshal(buf)
 }
 
 // DecodeReply 解码回复包
-// 内部的连系信息已加密，需要解密（GCM）。
+// 内部的连系信息已加密，需要解密。
+// 新版本（VersionBind+）的密文绑定版本、询问ID和双方公钥，旧版本为 GCM 兼容路径。
+// 询问以 VersionBind+ 发出时，低于 VersionBind 的回复被拒绝（ErrDowngrade）。
 // @data 已编码数据
+// @qver 询问包版本
 // @dh 密钥交换封包
 // @return1 基础信息
 // @return2 连系信息
-func DecodeReply(data []byte, dh *DHPack) (*Base, *AidInfo, error) {
+func DecodeReply(data []byte, qver int, dh *DHPack) (*Base, *AidInfo, error) {
 	buf := &Reply{}
 
 	if err := proto.Unmarshal(data, buf); err != nil {
 		return nil, nil, err
 	}
+	ver := int(buf.Ver)
+
+	if err := checkDowngrade(qver, ver); err != nil {
+		return nil, nil, err
+	}
 	// 连系信息解密
-	cdata, err := dh.Decrypt(buf.Pubkey, buf.Contact)
+	cdata, err := openContact(ver, ReplyContext(ver, buf.Id), dh, buf.Pubkey, buf.Kemct, buf.Contact)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -305,6 +407,14 @@ func EncodeContact(b *Base, a *AidInfo) ([]byte, error) {
 		Fport: int32(a.Fport),
 		Fkind: a.Fkind,
 	}
+	// 权益声明可选
+	if s := a.Stake; s != nil {
+		buf.Uid = s.UserID
+		buf.Stake = s.Address
+		buf.Salgor = int32(s.Algor)
+		buf.Spubkey = s.Pubkey
+		buf.Ssignd = s.Signd
+	}
 	return proto.Marshal(buf)
 }
 
@@ -342,6 +452,16 @@ func DecodeContact(data []byte, ver int, id uint64) (*Base, *AidInfo, error) {
 		Fport:   int(buf.Fport),
 		Fkind:   buf.Fkind,
 	}
+	// 旧版本无权益声明
+	if buf.Uid != "" || buf.Stake != "" {
+		a.Stake = &Stake{
+			UserID:  buf.Uid,
+			Address: buf.Stake,
+			Algor:   SignTag(buf.Salgor),
+			Pubkey:  buf.Spubkey,
+			Signd:   buf.Ssignd,
+		}
+	}
 	return NewBase(ver, id, int(buf.Hops), NatLevel(buf.Level)), &a, nil
 }
 
@@ -350,7 +470,8 @@ func DecodeContact(data []byte, ver int, id uint64) (*Base, *AidInfo, error) {
 //////////////////////////////////////////////////////////////////////////////
 
 // DataMessage 构建数据消息
-// 用于对目标数据的基本信息执行签名。
+// 用于对目标数据的基本信息执行签名（旧版本探测包）。
+// 新版本的探测包签名采用 ProbeMessage。
 // 串联：
 // - 数据类别：1字节
 // - 索引：n字节，原始顺序
@@ -370,3 +491,11 @@ func DataMessage(kind byte, index []byte, size uint32) []byte {
 	}
 	return buf
 }
+
+// ProbeID 探测包标识。
+// 探测包没有ID字段，由内容派生：SHA3:256(kind+index) 的前8字节（大端序）。
+// 数据大小不参与计算，同一数据的探测视为同一消息。
+func ProbeID(d *Data) uint64 {
+	sum := sha3.Sum256(DataMessage(byte(d.Kind), d.Index, 0))
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -137,6 +137,7 @@ func (x *Quest) GetSize() uint32 {
 // 探查数据的存在性，协助节点评估和补充。
 // 签名为可选，
 // 签名的数据为探测数据的相关信息：kind+index+size（大端序）。
+// 新版本（0x10+）的签名数据还包含时间戳和随机数，用于防范重放。
 type Probe struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -150,6 +151,8 @@ type Probe struct {
 	Kind   int32  `protobuf:"varint,6,opt,name=kind,proto3" json:"kind,omitempty"`    // 数据类别（<256）
 	Index  []byte `protobuf:"bytes,7,opt,name=index,proto3" json:"index,omitempty"`   // 数据索引（同询问包说明）
 	Size   uint32 `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`    // 数据大小，可选
+	Stamp  int64  `protobuf:"varint,9,opt,name=stamp,proto3" json:"stamp,omitempty"`  // 签名时间戳（Unix秒），新版本
+	Nonce  []byte `protobuf:"bytes,10,opt,name=nonce,proto3" json:"nonce,omitempty"`  // 签名随机数，新版本
 }
 
 func (x *Probe) Reset() {
@@ -240,35 +243,972 @@ func (x *Probe) GetSize() uint32 {
 	return 0
 }
 
+func (x *Probe) GetStamp() int64 {
+	if x != nil {
+		return x.Stamp
+	}
+	return 0
+}
+
+func (x *Probe) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
 // 回复包
 type Reply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Ver     int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`        // 消息包版本
-	Id      uint64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`          // 询问ID
-	Pubkey  []byte `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`   // 公钥数据，算法与询问包相同
-	Contact []byte `protobuf:"bytes,4,opt,name=contact,proto3" json:"contact,omitempty"` // 连系信息（密文）
+	Ver     int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`        // 消息包版本
+	Id      uint64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`          // 询问ID
+	Pubkey  []byte `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`   // 公钥数据，算法与询问包相同（KEM算法时为空）
+	Contact []byte `protobuf:"bytes,4,opt,name=contact,proto3" json:"contact,omitempty"` // 连系信息（密文）
+	Kemct   []byte `protobuf:"bytes,5,opt,name=kemct,proto3" json:"kemct,omitempty"`     // 密钥封装密文（仅KEM算法）
+}
+
+func (x *Reply) Reset() {
+	*x = Reply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Reply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reply) ProtoMessage() {}
+
+func (x *Reply) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reply.ProtoReflect.Descriptor instead.
+func (*Reply) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Reply) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *Reply) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Reply) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *Reply) GetContact() []byte {
+	if x != nil {
+		return x.Contact
+	}
+	return nil
+}
+
+func (x *Reply) GetKemct() []byte {
+	if x != nil {
+		return x.Kemct
+	}
+	return nil
+}
+
+// 回复包：连系信息
+// 此用于编解码，作为Reply字段传输时已加密。
+type Contact struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hops    int32  `protobuf:"varint,1,opt,name=hops,proto3" json:"hops,omitempty"`       // 跳数累计（<16）
+	Level   int32  `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`     // NAT层级（<16）
+	Xnet    string `protobuf:"bytes,3,opt,name=xnet,proto3" json:"xnet,omitempty"`        // 网络协议名（websocket|dtls|tcp|udp）
+	Ip      []byte `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`            // 数据节点IP
+	Port    int32  `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`       // 数据节点端口
+	Fip     []byte `protobuf:"bytes,6,opt,name=fip,proto3" json:"fip,omitempty"`          // Findings服务节点IP
+	Fport   int32  `protobuf:"varint,7,opt,name=fport,proto3" json:"fport,omitempty"`     // Findings服务节点端口
+	Fkind   string `protobuf:"bytes,8,opt,name=fkind,proto3" json:"fkind,omitempty"`      // 登录Findings节点的类别名
+	Uid     string `protobuf:"bytes,9,opt,name=uid,proto3" json:"uid,omitempty"`          // 身份ID（站群用），可选
+	Stake   string `protobuf:"bytes,10,opt,name=stake,proto3" json:"stake,omitempty"`     // 代币收益地址，可选
+	Salgor  int32  `protobuf:"varint,11,opt,name=salgor,proto3" json:"salgor,omitempty"`  // 签名算法（<16）
+	Spubkey []byte `protobuf:"bytes,12,opt,name=spubkey,proto3" json:"spubkey,omitempty"` // 节点签名公钥，可选
+	Ssignd  []byte `protobuf:"bytes,13,opt,name=ssignd,proto3" json:"ssignd,omitempty"`   // 签名数据（绑定身份ID和收益地址），可选
+}
+
+func (x *Contact) Reset() {
+	*x = Contact{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Contact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contact) ProtoMessage() {}
+
+func (x *Contact) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
+func (*Contact) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Contact) GetHops() int32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+func (x *Contact) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *Contact) GetXnet() string {
+	if x != nil {
+		return x.Xnet
+	}
+	return ""
+}
+
+func (x *Contact) GetIp() []byte {
+	if x != nil {
+		return x.Ip
+	}
+	return nil
+}
+
+func (x *Contact) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Contact) GetFip() []byte {
+	if x != nil {
+		return x.Fip
+	}
+	return nil
+}
+
+func (x *Contact) GetFport() int32 {
+	if x != nil {
+		return x.Fport
+	}
+	return 0
+}
+
+func (x *Contact) GetFkind() string {
+	if x != nil {
+		return x.Fkind
+	}
+	return ""
+}
+
+func (x *Contact) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *Contact) GetStake() string {
+	if x != nil {
+		return x.Stake
+	}
+	return ""
+}
+
+func (x *Contact) GetSalgor() int32 {
+	if x != nil {
+		return x.Salgor
+	}
+	return 0
+}
+
+func (x *Contact) GetSpubkey() []byte {
+	if x != nil {
+		return x.Spubkey
+	}
+	return nil
+}
+
+func (x *Contact) GetSsignd() []byte {
+	if x != nil {
+		return x.Ssignd
+	}
+	return nil
+}
+
+// 批量询问包
+// 多个数据索引共用一个询问ID和公钥，作为一个整体转播。
+// 适用于分片存储的大尺寸数据（每个分片为独立文档）。
+type BatchQuest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver    int32        `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`      // 消息包版本
+	Id     uint64       `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`        // 询问ID
+	Hops   int32        `protobuf:"varint,3,opt,name=hops,proto3" json:"hops,omitempty"`    // 询问包跳数累计（<16）
+	Algor  int32        `protobuf:"varint,4,opt,name=algor,proto3" json:"algor,omitempty"`  // 公钥算法（<16）
+	Pubkey []byte       `protobuf:"bytes,5,opt,name=pubkey,proto3" json:"pubkey,omitempty"` // 公钥字节序列
+	Level  int32        `protobuf:"varint,6,opt,name=level,proto3" json:"level,omitempty"`  // NAT 层级：Pub/FullC|RC|P-RC|Sym
+	Kind   int32        `protobuf:"varint,7,opt,name=kind,proto3" json:"kind,omitempty"`    // 数据类别（<256），各条目相同
+	Items  []*BatchItem `protobuf:"bytes,8,rep,name=items,proto3" json:"items,omitempty"`   // 询问条目集
+}
+
+func (x *BatchQuest) Reset() {
+	*x = BatchQuest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchQuest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchQuest) ProtoMessage() {}
+
+func (x *BatchQuest) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchQuest.ProtoReflect.Descriptor instead.
+func (*BatchQuest) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchQuest) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetHops() int32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetAlgor() int32 {
+	if x != nil {
+		return x.Algor
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *BatchQuest) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetKind() int32 {
+	if x != nil {
+		return x.Kind
+	}
+	return 0
+}
+
+func (x *BatchQuest) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// 批量询问条目
+type BatchItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Slot  uint32 `protobuf:"varint,1,opt,name=slot,proto3" json:"slot,omitempty"`  // 在原始批量中的序位
+	Index []byte `protobuf:"bytes,2,opt,name=index,proto3" json:"index,omitempty"` // 数据索引
+	Size  uint32 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`  // 数据大小，可选
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchItem) GetSlot() uint32 {
+	if x != nil {
+		return x.Slot
+	}
+	return 0
+}
+
+func (x *BatchItem) GetIndex() []byte {
+	if x != nil {
+		return x.Index
+	}
+	return nil
+}
+
+func (x *BatchItem) GetSize() uint32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// 批量回复包
+// 每个条目的连系信息独立加密，可能来自不同的数据源。
+type BatchReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver   int32         `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`    // 消息包版本
+	Id    uint64        `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`      // 询问ID
+	Found []*BatchFound `protobuf:"bytes,3,rep,name=found,proto3" json:"found,omitempty"` // 回复条目集
+}
+
+func (x *BatchReply) Reset() {
+	*x = BatchReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchReply) ProtoMessage() {}
+
+func (x *BatchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchReply.ProtoReflect.Descriptor instead.
+func (*BatchReply) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchReply) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *BatchReply) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BatchReply) GetFound() []*BatchFound {
+	if x != nil {
+		return x.Found
+	}
+	return nil
+}
+
+// 批量回复条目
+type BatchFound struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Slot    uint32 `protobuf:"varint,1,opt,name=slot,proto3" json:"slot,omitempty"`      // 对应询问条目的序位
+	Pubkey  []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`   // 数据源公钥，算法与询问包相同（KEM算法时为空）
+	Contact []byte `protobuf:"bytes,3,opt,name=contact,proto3" json:"contact,omitempty"` // 连系信息（密文）
+	Kemct   []byte `protobuf:"bytes,4,opt,name=kemct,proto3" json:"kemct,omitempty"`     // 密钥封装密文（仅KEM算法）
+}
+
+func (x *BatchFound) Reset() {
+	*x = BatchFound{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchFound) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchFound) ProtoMessage() {}
+
+func (x *BatchFound) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchFound.ProtoReflect.Descriptor instead.
+func (*BatchFound) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchFound) GetSlot() uint32 {
+	if x != nil {
+		return x.Slot
+	}
+	return 0
+}
+
+func (x *BatchFound) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *BatchFound) GetContact() []byte {
+	if x != nil {
+		return x.Contact
+	}
+	return nil
+}
+
+func (x *BatchFound) GetKemct() []byte {
+	if x != nil {
+		return x.Kemct
+	}
+	return nil
+}
+
+// 撤销询问包
+// 询问者获得数据后，撤销之前广播的询问。
+// 沿询问包的转播路径传递，中转节点据此清理待决状态。
+// 签名以询问包中的公钥验证（XEdDSA|ECDSA），只有询问者才能撤销。
+type Cancel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver   int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`    // 消息包版本
+	Id    uint64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`      // 询问ID
+	Signd []byte `protobuf:"bytes,3,opt,name=signd,proto3" json:"signd,omitempty"` // 签名数据
+}
+
+func (x *Cancel) Reset() {
+	*x = Cancel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cancel) ProtoMessage() {}
+
+func (x *Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cancel.ProtoReflect.Descriptor instead.
+func (*Cancel) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Cancel) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *Cancel) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Cancel) GetSignd() []byte {
+	if x != nil {
+		return x.Signd
+	}
+	return nil
+}
+
+// 握手：能力声明
+// 连接建立后由发起方发送，声明自身支持的版本范围和功能集。
+// 功能集为位图，第n位对应标识值为n的算法或协议。
+type Hello struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Vmin  int32  `protobuf:"varint,1,opt,name=vmin,proto3" json:"vmin,omitempty"`   // 支持的最低版本
+	Vmax  int32  `protobuf:"varint,2,opt,name=vmax,proto3" json:"vmax,omitempty"`   // 支持的最高版本
+	Signs uint32 `protobuf:"varint,3,opt,name=signs,proto3" json:"signs,omitempty"` // 签名算法集（SignTag位图）
+	Dhs   uint32 `protobuf:"varint,4,opt,name=dhs,proto3" json:"dhs,omitempty"`     // 密钥交换算法集（DHTag位图）
+	Xnets uint32 `protobuf:"varint,5,opt,name=xnets,proto3" json:"xnets,omitempty"` // 传输协议集（websocket|dtls|tcp|udp）
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Hello) GetVmin() int32 {
+	if x != nil {
+		return x.Vmin
+	}
+	return 0
+}
+
+func (x *Hello) GetVmax() int32 {
+	if x != nil {
+		return x.Vmax
+	}
+	return 0
+}
+
+func (x *Hello) GetSigns() uint32 {
+	if x != nil {
+		return x.Signs
+	}
+	return 0
+}
+
+func (x *Hello) GetDhs() uint32 {
+	if x != nil {
+		return x.Dhs
+	}
+	return 0
+}
+
+func (x *Hello) GetXnets() uint32 {
+	if x != nil {
+		return x.Xnets
+	}
+	return 0
+}
+
+// 握手：协商结果
+// 由接收方回应，给出双方共同的参数。
+// 若无法兼容，refuse为拒绝原因，其它字段无意义。
+type Accept struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver    int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`      // 选定的版本
+	Signs  uint32 `protobuf:"varint,2,opt,name=signs,proto3" json:"signs,omitempty"`  // 共同的签名算法集
+	Dhs    uint32 `protobuf:"varint,3,opt,name=dhs,proto3" json:"dhs,omitempty"`      // 共同的密钥交换算法集
+	Xnets  uint32 `protobuf:"varint,4,opt,name=xnets,proto3" json:"xnets,omitempty"`  // 共同的传输协议集
+	Refuse string `protobuf:"bytes,5,opt,name=refuse,proto3" json:"refuse,omitempty"` // 拒绝原因，可选
+}
+
+func (x *Accept) Reset() {
+	*x = Accept{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Accept) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Accept) ProtoMessage() {}
+
+func (x *Accept) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Accept.ProtoReflect.Descriptor instead.
+func (*Accept) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Accept) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *Accept) GetSigns() uint32 {
+	if x != nil {
+		return x.Signs
+	}
+	return 0
+}
+
+func (x *Accept) GetDhs() uint32 {
+	if x != nil {
+		return x.Dhs
+	}
+	return 0
+}
+
+func (x *Accept) GetXnets() uint32 {
+	if x != nil {
+		return x.Xnets
+	}
+	return 0
+}
+
+func (x *Accept) GetRefuse() string {
+	if x != nil {
+		return x.Refuse
+	}
+	return ""
+}
+
+// 核实报告包
+// 询问者核实数据源后，向本地驿站报告结果，沿回复的回传路径反向传递，
+// 各中转节点据此评定转回该回复的下级节点的信誉。
+// 签名以询问包中的公钥验证（XEdDSA|ECDSA），只有询问者才能报告。
+type Report struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver   int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`    // 消息包版本
+	Id    uint64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`      // 询问ID
+	Ok    bool   `protobuf:"varint,3,opt,name=ok,proto3" json:"ok,omitempty"`      // 数据源是否提供了所需数据
+	Signd []byte `protobuf:"bytes,4,opt,name=signd,proto3" json:"signd,omitempty"` // 签名数据
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Report) GetVer() int32 {
+	if x != nil {
+		return x.Ver
+	}
+	return 0
+}
+
+func (x *Report) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Report) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *Report) GetSignd() []byte {
+	if x != nil {
+		return x.Signd
+	}
+	return nil
+}
+
+// 节点交换：地址条目
+// 发送方近期核实过的驿站节点。
+type ExchangeItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ip    []byte `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`        // IP（4或16字节）
+	Port  uint32 `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`   // 服务端口
+	Level int32  `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"` // NAT 层级：Pub/FullC|RC|P-RC|Sym
+	Kinds uint32 `protobuf:"varint,4,opt,name=kinds,proto3" json:"kinds,omitempty"` // 支持的数据类别（位图）
+	Seen  int64  `protobuf:"varint,5,opt,name=seen,proto3" json:"seen,omitempty"`   // 最近核实时间（Unix秒）
+}
+
+func (x *ExchangeItem) Reset() {
+	*x = ExchangeItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExchangeItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExchangeItem) ProtoMessage() {}
+
+func (x *ExchangeItem) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExchangeItem.ProtoReflect.Descriptor instead.
+func (*ExchangeItem) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ExchangeItem) GetIp() []byte {
+	if x != nil {
+		return x.Ip
+	}
+	return nil
+}
+
+func (x *ExchangeItem) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ExchangeItem) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *ExchangeItem) GetKinds() uint32 {
+	if x != nil {
+		return x.Kinds
+	}
+	return 0
+}
+
+func (x *ExchangeItem) GetSeen() int64 {
+	if x != nil {
+		return x.Seen
+	}
+	return 0
+}
+
+// 节点交换包
+// 已连接的驿站之间分享近期核实过的节点地址样本。
+// 签名以发送方的身份密钥，签名数据含时间戳，接收者据此限定时效并拒绝重放。
+type Exchange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver    int32           `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`      // 消息包版本
+	Time   int64           `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`    // 签名时间（Unix秒）
+	Addrs  []*ExchangeItem `protobuf:"bytes,3,rep,name=addrs,proto3" json:"addrs,omitempty"`   // 地址样本
+	Algor  int32           `protobuf:"varint,4,opt,name=algor,proto3" json:"algor,omitempty"`  // 签名算法
+	Pubkey []byte          `protobuf:"bytes,5,opt,name=pubkey,proto3" json:"pubkey,omitempty"` // 签名公钥
+	Signd  []byte          `protobuf:"bytes,6,opt,name=signd,proto3" json:"signd,omitempty"`   // 签名数据
 }
 
-func (x *Reply) Reset() {
-	*x = Reply{}
+func (x *Exchange) Reset() {
+	*x = Exchange{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[2]
+		mi := &file_message_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Reply) String() string {
+func (x *Exchange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Reply) ProtoMessage() {}
+func (*Exchange) ProtoMessage() {}
 
-func (x *Reply) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[2]
+func (x *Exchange) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -279,73 +1219,83 @@ func (x *Reply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Reply.ProtoReflect.Descriptor instead.
-func (*Reply) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use Exchange.ProtoReflect.Descriptor instead.
+func (*Exchange) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *Reply) GetVer() int32 {
+func (x *Exchange) GetVer() int32 {
 	if x != nil {
 		return x.Ver
 	}
 	return 0
 }
 
-func (x *Reply) GetId() uint64 {
+func (x *Exchange) GetTime() int64 {
 	if x != nil {
-		return x.Id
+		return x.Time
 	}
 	return 0
 }
 
-func (x *Reply) GetPubkey() []byte {
+func (x *Exchange) GetAddrs() []*ExchangeItem {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *Exchange) GetAlgor() int32 {
+	if x != nil {
+		return x.Algor
+	}
+	return 0
+}
+
+func (x *Exchange) GetPubkey() []byte {
 	if x != nil {
 		return x.Pubkey
 	}
 	return nil
 }
 
-func (x *Reply) GetContact() []byte {
+func (x *Exchange) GetSignd() []byte {
 	if x != nil {
-		return x.Contact
+		return x.Signd
 	}
 	return nil
 }
 
-// 回复包：连系信息
-// 此用于编解码，作为Reply字段传输时已加密。
-type Contact struct {
+// 能力声明：紧凑过滤器
+// 布隆过滤器，元素为数据索引的前缀（如区块链名称、文档索引前缀）。
+type CapsFilter struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Hops  int32  `protobuf:"varint,1,opt,name=hops,proto3" json:"hops,omitempty"`   // 跳数累计（<16）
-	Level int32  `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"` // NAT层级（<16）
-	Xnet  string `protobuf:"bytes,3,opt,name=xnet,proto3" json:"xnet,omitempty"`    // 网络协议名（websocket|dtls|tcp|udp）
-	Ip    []byte `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`        // 数据节点IP
-	Port  int32  `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`   // 数据节点端口
-	Fip   []byte `protobuf:"bytes,6,opt,name=fip,proto3" json:"fip,omitempty"`      // Findings服务节点IP
-	Fport int32  `protobuf:"varint,7,opt,name=fport,proto3" json:"fport,omitempty"` // Findings服务节点端口
-	Fkind string `protobuf:"bytes,8,opt,name=fkind,proto3" json:"fkind,omitempty"`  // 登录Findings节点的类别名
+	Kind   int32  `protobuf:"varint,1,opt,name=kind,proto3" json:"kind,omitempty"`     // 数据类别
+	Prefix uint32 `protobuf:"varint,2,opt,name=prefix,proto3" json:"prefix,omitempty"` // 索引前缀长度（字节数）
+	Hashes uint32 `protobuf:"varint,3,opt,name=hashes,proto3" json:"hashes,omitempty"` // 哈希函数个数
+	Bits   []byte `protobuf:"bytes,4,opt,name=bits,proto3" json:"bits,omitempty"`      // 位图
 }
 
-func (x *Contact) Reset() {
-	*x = Contact{}
+func (x *CapsFilter) Reset() {
+	*x = CapsFilter{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[3]
+		mi := &file_message_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Contact) String() string {
+func (x *CapsFilter) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Contact) ProtoMessage() {}
+func (*CapsFilter) ProtoMessage() {}
 
-func (x *Contact) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[3]
+func (x *CapsFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -356,65 +1306,111 @@ func (x *Contact) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
-func (*Contact) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use CapsFilter.ProtoReflect.Descriptor instead.
+func (*CapsFilter) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *Contact) GetHops() int32 {
+func (x *CapsFilter) GetKind() int32 {
 	if x != nil {
-		return x.Hops
+		return x.Kind
 	}
 	return 0
 }
 
-func (x *Contact) GetLevel() int32 {
+func (x *CapsFilter) GetPrefix() uint32 {
 	if x != nil {
-		return x.Level
+		return x.Prefix
 	}
 	return 0
 }
 
-func (x *Contact) GetXnet() string {
+func (x *CapsFilter) GetHashes() uint32 {
 	if x != nil {
-		return x.Xnet
+		return x.Hashes
 	}
-	return ""
+	return 0
 }
 
-func (x *Contact) GetIp() []byte {
+func (x *CapsFilter) GetBits() []byte {
 	if x != nil {
-		return x.Ip
+		return x.Bits
 	}
 	return nil
 }
 
-func (x *Contact) GetPort() int32 {
+// 能力声明包
+// 已连接的驿站之间声明自身支持的数据类别和所服务数据的过滤器，
+// 可随时更新，序号较大者有效。
+type CapsAdvert struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ver     int32         `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`        // 消息包版本
+	Seq     uint64        `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`        // 更新序号（递增）
+	Kinds   uint32        `protobuf:"varint,3,opt,name=kinds,proto3" json:"kinds,omitempty"`    // 支持的数据类别（位图）
+	Filters []*CapsFilter `protobuf:"bytes,4,rep,name=filters,proto3" json:"filters,omitempty"` // 紧凑过滤器，可选
+}
+
+func (x *CapsAdvert) Reset() {
+	*x = CapsAdvert{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapsAdvert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapsAdvert) ProtoMessage() {}
+
+func (x *CapsAdvert) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapsAdvert.ProtoReflect.Descriptor instead.
+func (*CapsAdvert) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CapsAdvert) GetVer() int32 {
 	if x != nil {
-		return x.Port
+		return x.Ver
 	}
 	return 0
 }
 
-func (x *Contact) GetFip() []byte {
+func (x *CapsAdvert) GetSeq() uint64 {
 	if x != nil {
-		return x.Fip
+		return x.Seq
 	}
-	return nil
+	return 0
 }
 
-func (x *Contact) GetFport() int32 {
+func (x *CapsAdvert) GetKinds() uint32 {
 	if x != nil {
-		return x.Fport
+		return x.Kinds
 	}
 	return 0
 }
 
-func (x *Contact) GetFkind() string {
+func (x *CapsAdvert) GetFilters() []*CapsFilter {
 	if x != nil {
-		return x.Fkind
+		return x.Filters
 	}
-	return ""
+	return nil
 }
 
 var File_message_proto protoreflect.FileDescriptor
@@ -433,7 +1429,7 @@ var file_message_proto_rawDesc = []byte{
 	0x05, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78,
 	0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x12, 0x0a,
 	0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a,
-	0x65, 0x22, 0xaf, 0x01, 0x0a, 0x05, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x76,
+	0x65, 0x22, 0xdb, 0x01, 0x0a, 0x05, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x76,
 	0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12, 0x12, 0x0a,
 	0x04, 0x68, 0x6f, 0x70, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x68, 0x6f, 0x70,
 	0x73, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
@@ -444,25 +1440,118 @@ var file_message_proto_rawDesc = []byte{
 	0x01, 0x28, 0x05, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64,
 	0x65, 0x78, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12,
 	0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73,
-	0x69, 0x7a, 0x65, 0x22, 0x5b, 0x0a, 0x05, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x10, 0x0a, 0x03,
-	0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12, 0x0e,
-	0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16,
-	0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06,
-	0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63,
-	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74,
-	0x22, 0xa9, 0x01, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x12, 0x12, 0x0a, 0x04,
-	0x68, 0x6f, 0x70, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x68, 0x6f, 0x70, 0x73,
-	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x6e, 0x65, 0x74, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x78, 0x6e, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f,
-	0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x10,
-	0x0a, 0x03, 0x66, 0x69, 0x70, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x66, 0x69, 0x70,
-	0x12, 0x14, 0x0a, 0x05, 0x66, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x05, 0x66, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6b, 0x69, 0x6e, 0x64, 0x18,
-	0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x6b, 0x69, 0x6e, 0x64, 0x42, 0x0b, 0x5a, 0x09,
-	0x2e, 0x2e, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e,
+	0x63, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22,
+	0x71, 0x0a, 0x05, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75,
+	0x62, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b,
+	0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x6b, 0x65, 0x6d, 0x63, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6b, 0x65, 0x6d,
+	0x63, 0x74, 0x22, 0x9b, 0x02, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x6f, 0x70, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x68, 0x6f,
+	0x70, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x6e, 0x65, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x78, 0x6e, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x70, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x66, 0x69, 0x70, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x66,
+	0x69, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x66, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6b, 0x69, 0x6e,
+	0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x10,
+	0x0a, 0x03, 0x75, 0x69, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x69, 0x64,
+	0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x61, 0x6c, 0x67, 0x6f, 0x72,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x73, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x12, 0x18,
+	0x0a, 0x07, 0x73, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x73, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x73, 0x69, 0x67,
+	0x6e, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x73, 0x69, 0x67, 0x6e, 0x64,
+	0x22, 0xbc, 0x01, 0x0a, 0x0a, 0x42, 0x61, 0x74, 0x63, 0x68, 0x51, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65,
+	0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x70, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x04, 0x68, 0x6f, 0x70, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x70,
+	0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e,
+	0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x20, 0x0a,
+	0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x22,
+	0x49, 0x0a, 0x09, 0x42, 0x61, 0x74, 0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x6c, 0x6f, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x6c, 0x6f, 0x74,
+	0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x51, 0x0a, 0x0a, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x21, 0x0a, 0x05, 0x66, 0x6f,
+	0x75, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x46, 0x6f, 0x75, 0x6e, 0x64, 0x52, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x22, 0x68, 0x0a,
+	0x0a, 0x42, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x6c, 0x6f, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x6c, 0x6f, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61,
+	0x63, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x6b, 0x65, 0x6d, 0x63, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x05, 0x6b, 0x65, 0x6d, 0x63, 0x74, 0x22, 0x40, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03,
+	0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x22, 0x6d, 0x0a, 0x05, 0x48, 0x65, 0x6c,
+	0x6c, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x6d, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x76, 0x6d, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x6d, 0x61, 0x78, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x76, 0x6d, 0x61, 0x78, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69,
+	0x67, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x73,
+	0x12, 0x10, 0x0a, 0x03, 0x64, 0x68, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x64,
+	0x68, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x78, 0x6e, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x05, 0x78, 0x6e, 0x65, 0x74, 0x73, 0x22, 0x70, 0x0a, 0x06, 0x41, 0x63, 0x63, 0x65,
+	0x70, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x03, 0x76, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x68,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x64, 0x68, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x78, 0x6e, 0x65, 0x74, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x78, 0x6e, 0x65,
+	0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x66, 0x75, 0x73, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x66, 0x75, 0x73, 0x65, 0x22, 0x50, 0x0a, 0x06, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x22, 0x72, 0x0a, 0x0c,
+	0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x70, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x6b, 0x69, 0x6e, 0x64, 0x73, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6b, 0x69, 0x6e, 0x64, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x65, 0x65, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x65, 0x65, 0x6e,
+	0x22, 0x99, 0x01, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x10, 0x0a,
+	0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76, 0x65, 0x72, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74,
+	0x69, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x49, 0x74, 0x65,
+	0x6d, 0x52, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x67, 0x6f,
+	0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x12, 0x16,
+	0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06,
+	0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x69, 0x67, 0x6e, 0x64, 0x22, 0x64, 0x0a, 0x0a,
+	0x43, 0x61, 0x70, 0x73, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69,
+	0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x62, 0x69, 0x74, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x69,
+	0x74, 0x73, 0x22, 0x6d, 0x0a, 0x0a, 0x43, 0x61, 0x70, 0x73, 0x41, 0x64, 0x76, 0x65, 0x72, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x76,
+	0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x03, 0x73, 0x65, 0x71, 0x12, 0x14, 0x0a, 0x05, 0x6b, 0x69, 0x6e, 0x64, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x6b, 0x69, 0x6e, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x07, 0x66, 0x69,
+	0x6c, 0x74, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x43, 0x61,
+	0x70, 0x73, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x07, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x73, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2e, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -477,19 +1566,35 @@ func file_message_proto_rawDescGZIP() []byte {
 	return file_message_proto_rawDescData
 }
 
-var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_message_proto_goTypes = []interface{}{
-	(*Quest)(nil),   // 0: Quest
-	(*Probe)(nil),   // 1: Probe
-	(*Reply)(nil),   // 2: Reply
-	(*Contact)(nil), // 3: Contact
+	(*Quest)(nil),        // 0: Quest
+	(*Probe)(nil),        // 1: Probe
+	(*Reply)(nil),        // 2: Reply
+	(*Contact)(nil),      // 3: Contact
+	(*BatchQuest)(nil),   // 4: BatchQuest
+	(*BatchItem)(nil),    // 5: BatchItem
+	(*BatchReply)(nil),   // 6: BatchReply
+	(*BatchFound)(nil),   // 7: BatchFound
+	(*Cancel)(nil),       // 8: Cancel
+	(*Hello)(nil),        // 9: Hello
+	(*Accept)(nil),       // 10: Accept
+	(*Report)(nil),       // 11: Report
+	(*ExchangeItem)(nil), // 12: ExchangeItem
+	(*Exchange)(nil),     // 13: Exchange
+	(*CapsFilter)(nil),   // 14: CapsFilter
+	(*CapsAdvert)(nil),   // 15: CapsAdvert
 }
 var file_message_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: BatchQuest.items:type_name -> BatchItem
+	7,  // 1: BatchReply.found:type_name -> BatchFound
+	12, // 2: Exchange.addrs:type_name -> ExchangeItem
+	14, // 3: CapsAdvert.filters:type_name -> CapsFilter
+	4,  // [4:4] is the sub-list for method output_type
+	4,  // [4:4] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_message_proto_init() }
@@ -546,6 +1651,150 @@ func file_message_proto_init() {
 				return nil
 			}
 		}
+		file_message_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchQuest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchFound); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Cancel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Hello); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Accept); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Report); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExchangeItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Exchange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapsFilter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapsAdvert); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -553,7 +1802,7 @@ func file_message_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_message_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
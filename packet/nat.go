@@ -0,0 +1,30 @@
+package packet
+
+// 可达矩阵。
+// 按[询问者][数据源]的NAT层级索引（Pub/FullC、RC、P-RC、Sym）。
+// - 数据源为公网类时，询问者直接连接即可。
+// - 询问者为公网类时，受限的数据源可经打洞主动连通询问者。
+// - 双方均为圆锥型（RC|P-RC）时，可经打洞连通。
+// - 任何一方为对称型（Sym）时，另一方必须为公网类。
+var reachMatrix = [4][4]bool{
+	{true, true, true, true},
+	{true, true, true, false},
+	{true, true, true, false},
+	{true, false, false, false},
+}
+
+// Reachable 询问者能否与数据源建立连接。
+// 非法或未定义的层级视为Sym（最差）。
+// @asker  询问者NAT层级
+// @source 数据源NAT层级
+func Reachable(asker, source NatLevel) bool {
+	return reachMatrix[natIndex(asker)][natIndex(source)]
+}
+
+// 层级的矩阵索引。
+func natIndex(lev NatLevel) int {
+	if lev < NAT_LEVEL_NULL || lev > NAT_LEVEL_SYM {
+		return int(NAT_LEVEL_SYM)
+	}
+	return int(lev)
+}
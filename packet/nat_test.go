@@ -0,0 +1,49 @@
+package packet_test
+
+import (
+	"testing"
+
+	"github.com/cxio/depots/packet"
+)
+
+// 可达矩阵的期望值，按[询问者][数据源]。
+var reach = []struct {
+	asker, source packet.NatLevel
+	ok            bool
+}{
+	{packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_NULL, true},
+	{packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_RC, true},
+	{packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_PRC, true},
+	{packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_SYM, true},
+	{packet.NAT_LEVEL_RC, packet.NAT_LEVEL_NULL, true},
+	{packet.NAT_LEVEL_RC, packet.NAT_LEVEL_RC, true},
+	{packet.NAT_LEVEL_RC, packet.NAT_LEVEL_PRC, true},
+	{packet.NAT_LEVEL_RC, packet.NAT_LEVEL_SYM, false},
+	{packet.NAT_LEVEL_PRC, packet.NAT_LEVEL_NULL, true},
+	{packet.NAT_LEVEL_PRC, packet.NAT_LEVEL_RC, true},
+	{packet.NAT_LEVEL_PRC, packet.NAT_LEVEL_PRC, true},
+	{packet.NAT_LEVEL_PRC, packet.NAT_LEVEL_SYM, false},
+	{packet.NAT_LEVEL_SYM, packet.NAT_LEVEL_NULL, true},
+	{packet.NAT_LEVEL_SYM, packet.NAT_LEVEL_RC, false},
+	{packet.NAT_LEVEL_SYM, packet.NAT_LEVEL_PRC, false},
+	{packet.NAT_LEVEL_SYM, packet.NAT_LEVEL_SYM, false},
+}
+
+func TestReachable(t *testing.T) {
+	for _, r := range reach {
+		if got := packet.Reachable(r.asker, r.source); got != r.ok {
+			t.Errorf("asker %d, source %d: %v", r.asker, r.source, got)
+		}
+	}
+	// 非法或未定义的层级视为Sym
+	for _, bad := range []packet.NatLevel{packet.NAT_LEVEL_UNDEFINED, packet.NAT_LEVEL_SYM + 1, 99} {
+		for _, r := range reach {
+			if r.asker == packet.NAT_LEVEL_SYM && packet.Reachable(bad, r.source) != r.ok {
+				t.Errorf("asker %d, source %d: not treated as sym", bad, r.source)
+			}
+			if r.source == packet.NAT_LEVEL_SYM && packet.Reachable(r.asker, bad) != r.ok {
+				t.Errorf("asker %d, source %d: not treated as sym", r.asker, bad)
+			}
+		}
+	}
+}
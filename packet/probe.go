@@ -0,0 +1,54 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// 探测签名消息的上下文前缀
+const probeContext = "depots:probe"
+
+// 数据索引长度上限（2字节长度前缀）
+const indexMax = 0xffff
+
+// ErrIndex 数据索引过长
+var ErrIndex = errors.New("data index is too long")
+
+// ProbeMessage 构建探测包的签名消息（VersionStamp+）
+// 采用上下文前缀和协议版本实现域分离，各字段定长或有长度前缀，无歧义。
+// 串联（整数均为大端序）：
+// - 上下文前缀：depots:probe（12字节ASCII）
+// - 协议版本：4字节
+// - 签名算法：1字节
+// - 数据类别：4字节
+// - 索引长度：2字节
+// - 数据索引：n字节
+// - 数据大小：4字节（零值表示未知）
+// - 时间戳：8字节，Unix秒
+// - 随机数：16字节
+//
+// 详细规范和测试向量参见 docs/packet.md。
+// @ver   探测包版本
+// @algor 签名算法
+// @d     探测的数据信息
+// @st    签名时间戳信息
+func ProbeMessage(ver int, algor SignTag, d *Data, st *Stamp) ([]byte, error) {
+	if len(d.Index) > indexMax {
+		return nil, ErrIndex
+	}
+	if len(st.Nonce) != NonceSize {
+		return nil, ErrStamp
+	}
+	buf := make([]byte, 0, len(probeContext)+4+1+4+2+len(d.Index)+4+8+NonceSize)
+
+	buf = append(buf, probeContext...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(ver))
+	buf = append(buf, byte(algor))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(d.Kind))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(d.Index)))
+	buf = append(buf, d.Index...)
+	buf = binary.BigEndian.AppendUint32(buf, d.Size)
+	buf = append(buf, st.Bytes()...)
+
+	return buf, nil
+}
@@ -0,0 +1,251 @@
+package packet_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+	"github.com/cxio/depots/trust"
+	"google.golang.org/protobuf/proto"
+)
+
+// 测试向量的公共输入（docs/packet.md）
+var (
+	vecIndex = mustHex("deadbeef")
+	vecStamp = &packet.Stamp{
+		Time:  time.Unix(1767225600, 0),
+		Nonce: []byte("0123456789abcdef"),
+	}
+)
+
+// 文档中的签名测试向量
+var probeVectors = []struct {
+	algor msg.SignTag
+	key   string // 私钥（种子）
+	pub   string
+	msg   string
+	sig   string
+	fixed bool // 签名是否确定（可逐字节比对）
+}{
+	{
+		algor: msg.SIGN_Tradi,
+		key:   "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pub:   "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		msg:   "6465706f74733a70726f62650000001000000000000004deadbeef00000400000000006955b90030313233343536373839616263646566",
+		sig:   "b4b6476af7101b20437fe618dc7aee362d5a09d331de135a5b89d7df3b5370a17eed844f86234d3a265440c9b5c2e28b778cf0c89cf09e0f4a20f6f34357120f",
+		fixed: true,
+	},
+	{
+		algor: msg.SIGN_ED25519,
+		key:   "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pub:   "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		msg:   "6465706f74733a70726f62650000001001000000000004deadbeef00000400000000006955b90030313233343536373839616263646566",
+		sig:   "f54fc3dbdd9355ab800f113881a0ff49aafe39123eccf5497a63f1cf82c8a4821e337b761006771272fff2fe5ce672bdce71a340abb2a6b1aa35d9d65e8ae300",
+		fixed: true,
+	},
+	{
+		algor: msg.SIGN_ED448,
+		key:   "6c82a562cb808d10d632be89c8513ebf6c929f34ddfa8c9f63c9960ef6e348a3528c8a3fcc2f044e39a3fc5b94492f8f032e7549a20098f95b",
+		pub:   "5fd7449b59b461fd2ce787ec616ad46a1da1342485a70e1f8a0ea75d80e96778edf124769b46c7061bd6783df1e50f6cd1fa1abeafe8256180",
+		msg:   "6465706f74733a70726f62650000001002000000000004deadbeef00000400000000006955b90030313233343536373839616263646566",
+		sig:   "e34fe298fce94cfd425d3d564d5cf6db8b0d3f2a212fcd090595301df29fbf32980258847f20cac6a9ac52a82b7608eaab18ad5050359e06801e795fa3e9b3db3dfbce03650a2c8d7665879d229b7731f823d4bc4f30b793b378079cee48c4d424ae5e833891739c7be7946291d7b64d1c00",
+		fixed: true,
+	},
+	{
+		algor: msg.SIGN_SCHNORR,
+		key:   "0000000000000000000000000000000000000000000000000000000000000003",
+		pub:   "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		msg:   "6465706f74733a70726f62650000001003000000000004deadbeef00000400000000006955b90030313233343536373839616263646566",
+		sig:   "e6664c84f17836f213d80c85e51cace669aecb2123e6ca5c0793da84a8c34e2d6fb98bdd154386dd61634b9fdde87ad5fdecaaec27f05cda4b9e07470bdcc07f",
+	},
+}
+
+func TestProbeVectors(t *testing.T) {
+	d := packet.NewData(packet.KIND_ARCHIVE, vecIndex, 1024)
+
+	for _, v := range probeVectors {
+		m, err := packet.ProbeMessage(packet.VersionStamp, v.algor, d, vecStamp)
+		if err != nil {
+			t.Fatalf("algor %d: %v", v.algor, err)
+		}
+		if !bytes.Equal(m, mustHex(v.msg)) {
+			t.Errorf("algor %d: message\n got %x\nwant %s", v.algor, m, v.msg)
+		}
+		sp := vectorPack(t, v.algor, v.key)
+
+		if !bytes.Equal(sp.PublicBytes(), mustHex(v.pub)) {
+			t.Errorf("algor %d: public key\n got %x\nwant %s", v.algor, sp.PublicBytes(), v.pub)
+		}
+		if !sp.Verify(mustHex(v.pub), m, mustHex(v.sig)) {
+			t.Errorf("algor %d: documented signature rejected", v.algor)
+		}
+		sig, err := sp.Sign(m)
+		if err != nil {
+			t.Fatalf("algor %d: sign: %v", v.algor, err)
+		}
+		if v.fixed && !bytes.Equal(sig, mustHex(v.sig)) {
+			t.Errorf("algor %d: signature\n got %x\nwant %s", v.algor, sig, v.sig)
+		}
+		if !sp.Verify(sp.PublicBytes(), m, sig) {
+			t.Errorf("algor %d: own signature rejected", v.algor)
+		}
+	}
+}
+
+func TestProbeVectorLegacy(t *testing.T) {
+	const (
+		want = "00deadbeef00000400"
+		sig  = "a05aa76b482684677fa7b1caeba60e3dee8a10c165389237bbe9c436e1b92296daf1c57b001e524e683ad5ce231fb755403f767dcfe9ec177d4d50e63f7f740f"
+	)
+	m := packet.DataMessage(byte(packet.KIND_ARCHIVE), vecIndex, 1024)
+
+	if !bytes.Equal(m, mustHex(want)) {
+		t.Fatalf("message\n got %x\nwant %s", m, want)
+	}
+	sp := vectorPack(t, msg.SIGN_ED25519, probeVectors[1].key)
+
+	got, err := sp.Sign(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, mustHex(sig)) {
+		t.Errorf("signature\n got %x\nwant %s", got, sig)
+	}
+}
+
+func TestProbeRoundTrip(t *testing.T) {
+	d := packet.NewData(packet.KIND_BLOCKCHAIN, []byte("btc:block:1"), 4096)
+
+	for _, tag := range msg.SignTags {
+		sp := newPack(t, tag)
+		data, err := packet.EncodeProbe(packet.NewBase(packet.VersionStamp, 0, 0, 0), d, sp)
+		if err != nil {
+			t.Fatalf("algor %d: encode: %v", tag, err)
+		}
+		_, got, algor, pub, st, err := packet.DecodeProbe(data)
+		if err != nil {
+			t.Fatalf("algor %d: decode: %v", tag, err)
+		}
+		if algor != tag || !bytes.Equal(pub, sp.PublicBytes()) || st == nil ||
+			got.Kind != d.Kind || !bytes.Equal(got.Index, d.Index) || got.Size != d.Size {
+			t.Fatalf("algor %d: decoded probe mismatch", tag)
+		}
+		r := relay.NewReplay(trusted(sp))
+
+		if err := r.Check(algor, pub, st); err != nil {
+			t.Errorf("algor %d: first check: %v", tag, err)
+		}
+		if err := r.Check(algor, pub, st); !errors.Is(err, relay.ErrReplay) {
+			t.Errorf("algor %d: replay accepted: %v", tag, err)
+		}
+	}
+}
+
+func TestProbeRejects(t *testing.T) {
+	d := packet.NewData(packet.KIND_BLOCKCHAIN, []byte("btc:block:1"), 4096)
+
+	tampers := map[string]func(p *packet.Probe){
+		"wrong key": nil, // 由下面按算法替换公钥
+		"kind":      func(p *packet.Probe) { p.Kind++ },
+		"index":     func(p *packet.Probe) { p.Index = append(p.Index, 'x') },
+		"size":      func(p *packet.Probe) { p.Size++ },
+		"stamp":     func(p *packet.Probe) { p.Stamp++ },
+		"nonce":     func(p *packet.Probe) { p.Nonce[0] ^= 1 },
+		"version":   func(p *packet.Probe) { p.Ver++ },
+		"signature": func(p *packet.Probe) { p.Signd[len(p.Signd)-1] ^= 1 },
+	}
+	for _, tag := range msg.SignTags {
+		sp := newPack(t, tag)
+		other := newPack(t, tag)
+
+		data, err := packet.EncodeProbe(packet.NewBase(packet.VersionStamp, 0, 0, 0), d, sp)
+		if err != nil {
+			t.Fatalf("algor %d: encode: %v", tag, err)
+		}
+		for name, fn := range tampers {
+			p := &packet.Probe{}
+			if err := proto.Unmarshal(data, p); err != nil {
+				t.Fatal(err)
+			}
+			if fn == nil {
+				p.Pubkey = other.PublicBytes()
+			} else {
+				fn(p)
+			}
+			buf, err := proto.Marshal(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, _, _, _, err := packet.DecodeProbe(buf); !errors.Is(err, packet.ErrSign) {
+				t.Errorf("algor %d: %s: got %v, want ErrSign", tag, name, err)
+			}
+		}
+		// 签名有效但时间过旧
+		old := &packet.Stamp{Time: time.Now().Add(-time.Hour).Truncate(time.Second), Nonce: bytes.Repeat([]byte{7}, packet.NonceSize)}
+
+		m, err := packet.ProbeMessage(packet.VersionStamp, tag, d, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := sp.Sign(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := proto.Marshal(&packet.Probe{
+			Ver:    packet.VersionStamp,
+			Kind:   int32(d.Kind),
+			Index:  d.Index,
+			Size:   d.Size,
+			Algor:  int32(tag),
+			Pubkey: sp.PublicBytes(),
+			Signd:  sig,
+			Stamp:  old.Time.Unix(),
+			Nonce:  old.Nonce,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, pub, st, err := packet.DecodeProbe(buf)
+		if err != nil {
+			t.Fatalf("algor %d: stale probe decode: %v", tag, err)
+		}
+		if err := relay.NewReplay(trusted(sp)).Check(tag, pub, st); !errors.Is(err, relay.ErrExpired) {
+			t.Errorf("algor %d: stale probe: got %v, want ErrExpired", tag, err)
+		}
+	}
+}
+
+// 创建仅含一个签名者的公认登记簿。
+func trusted(sp *msg.SignPack) *trust.Registry {
+	r := trust.New(nil)
+	r.Add(&trust.Signer{Algor: sp.Algor, Pubkey: sp.PublicBytes()})
+	return r
+}
+
+// 从测试向量的私钥创建签名封包。
+func vectorPack(t *testing.T, tag msg.SignTag, key string) *msg.SignPack {
+	t.Helper()
+
+	priv, err := msg.ParseSignKey(tag, mustHex(key))
+	if err != nil {
+		t.Fatalf("algor %d: parse key: %v", tag, err)
+	}
+	sp := msg.NewSignPack(tag, priv)
+	if sp == nil {
+		t.Fatalf("algor %d: no sign pack", tag)
+	}
+	return sp
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrDowngrade 回复版本低于询问的加密模式（降级）
+var ErrDowngrade = errors.New("reply version downgraded below the quest")
+
+// 连系信息加密的上下文前缀
+const (
+	replyContext = "depots:reply" // 回复包
+	foundContext = "depots:found" // 批量回复条目
+)
+
+// ReplyContext 构建回复包连系信息的加密上下文（VersionBind+）
+// 用作密钥派生的信息和 AEAD 的附加数据，使密文绑定到该回复。
+// 串联（整数均为大端序）：
+// - 上下文前缀：depots:reply
+// - 协议版本：4字节
+// - 询问ID：8字节
+func ReplyContext(ver int, id uint64) []byte {
+	buf := make([]byte, 0, len(replyContext)+12)
+
+	buf = append(buf, replyContext...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(ver))
+	return binary.BigEndian.AppendUint64(buf, id)
+}
+
+// FoundContext 构建批量回复条目的加密上下文（VersionBind+）
+// 与回复包相同，前缀不同，并附加条目序位（4字节）。
+func FoundContext(ver int, id uint64, slot int) []byte {
+	buf := make([]byte, 0, len(foundContext)+16)
+
+	buf = append(buf, foundContext...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(ver))
+	buf = binary.BigEndian.AppendUint64(buf, id)
+	return binary.BigEndian.AppendUint32(buf, uint32(slot))
+}
+
+// ReplyID 提取回复包的询问ID。
+// 询问者据此找到对应的密钥交换包，再完整解码（DecodeReply）。
+// 批量回复包（BatchReply）的询问ID字段编号相同，也可适用。
+func ReplyID(data []byte) (uint64, error) {
+	buf := &Reply{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return 0, err
+	}
+	return buf.Id, nil
+}
+
+// 检查回复版本是否降级。
+// 回复的版本由发送者填写，询问以 VersionBind+ 发出时，
+// 低于 VersionBind 的回复会退回不绑定上下文的兼容路径，须拒绝。
+// @qver 询问包版本
+// @ver  回复包版本
+func checkDowngrade(qver, ver int) error {
+	if qver >= VersionBind && ver < VersionBind {
+		return ErrDowngrade
+	}
+	return nil
+}
+
+// 加密连系信息。
+// 新版本绑定上下文，旧版本为兼容路径。
+// @return1 封装密文（KEM），或nil
+// @return2 加密后的密文
+func sealContact(ver int, ctx []byte, dh *DHPack, pub, data []byte) ([]byte, []byte, error) {
+	if ver >= VersionBind {
+		return dh.SealBound(pub, ctx, data)
+	}
+	return dh.Seal(pub, data)
+}
+
+// 解密连系信息。
+func openContact(ver int, ctx []byte, dh *DHPack, pub, ct, data []byte) ([]byte, error) {
+	if ver >= VersionBind {
+		return dh.OpenBound(pub, ct, ctx, data)
+	}
+	return dh.Open(pub, ct, data)
+}
@@ -0,0 +1,61 @@
+package packet_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReplyKEM(t *testing.T) {
+	a := &packet.AidInfo{Network: "udp", IP: netip.MustParseAddr("10.0.0.1"), Port: 7790}
+
+	for _, tag := range []msg.DHTag{msg.DH_MLKEM768, msg.DH_X25519MLKEM768} {
+		for _, ver := range []int{packet.VersionStamp, packet.VersionBind} {
+			quest := newDH(t, tag)
+			b := packet.NewBase(ver, 0x1234, 3, packet.NAT_LEVEL_NULL)
+
+			// 回复者仅以算法标识封装
+			data, err := packet.EncodeReply(b, a, msg.NewDHPack(tag, nil), quest.PublicBytes())
+			if err != nil {
+				t.Fatalf("algor %d, ver %d: %v", tag, ver, err)
+			}
+			got, ga, err := packet.DecodeReply(data, ver, quest)
+			if err != nil {
+				t.Fatalf("algor %d, ver %d: %v", tag, ver, err)
+			}
+			if got.ID != b.ID || got.Hops != b.Hops || ga.IP != a.IP || ga.Port != a.Port {
+				t.Errorf("algor %d, ver %d: %+v, %+v", tag, ver, got, ga)
+			}
+			// 其它询问的密钥
+			if _, _, err = packet.DecodeReply(data, ver, newDH(t, tag)); err == nil {
+				t.Errorf("algor %d, ver %d: opened with a wrong key", tag, ver)
+			}
+			// 截短的封装密文
+			r := &packet.Reply{}
+			if err = proto.Unmarshal(data, r); err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Pubkey) != 0 || len(r.Kemct) == 0 {
+				t.Errorf("algor %d, ver %d: kem reply fields", tag, ver)
+			}
+			r.Kemct = r.Kemct[:len(r.Kemct)-1]
+			bad, _ := proto.Marshal(r)
+
+			if _, _, err = packet.DecodeReply(bad, ver, quest); !errors.Is(err, msg.ErrCiphertext) {
+				t.Errorf("algor %d, ver %d: truncated ciphertext: %v", tag, ver, err)
+			}
+			// 批量回复条目
+			f, err := packet.EncodeFound(b, a, msg.NewDHPack(tag, nil), quest.PublicBytes(), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ga, err = packet.OpenFound(f, ver, ver, b.ID, quest); err != nil || ga.Port != a.Port {
+				t.Errorf("algor %d, ver %d: found: %v", tag, ver, err)
+			}
+		}
+	}
+}
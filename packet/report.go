@@ -0,0 +1,74 @@
+package packet
+
+import (
+	"encoding/binary"
+
+	"github.com/cxio/depots/crypto/msg"
+	"google.golang.org/protobuf/proto"
+)
+
+// 报告消息的上下文前缀
+const reportContext = "depots:report"
+
+// EncodeReport 编码核实报告包
+// 用询问时的密钥交换包签名，证明报告者即询问者。
+// @b  基础信息（版本和询问ID）
+// @ok 数据源是否提供了所需数据
+// @dh 发出询问时的密钥交换包
+func EncodeReport(b *Base, ok bool, dh *DHPack) ([]byte, error) {
+	sig, err := dh.Sign(ReportMessage(b.Ver, b.ID, ok))
+	if err != nil {
+		return nil, err
+	}
+	buf := &Report{
+		Ver:   int32(b.Ver),
+		Id:    b.ID,
+		Ok:    ok,
+		Signd: sig,
+	}
+	return proto.Marshal(buf)
+}
+
+// DecodeReport 解码核实报告包
+// 签名需要由持有询问公钥的节点验证（VerifyReport）。
+// @return1 基础信息（版本和询问ID）
+// @return2 数据源是否提供了所需数据
+// @return3 签名数据
+func DecodeReport(data []byte) (*Base, bool, []byte, error) {
+	buf := &Report{}
+
+	if err := proto.Unmarshal(data, buf); err != nil {
+		return nil, false, nil, err
+	}
+	return &Base{Ver: int(buf.Ver), ID: buf.Id}, buf.Ok, buf.Signd, nil
+}
+
+// VerifyReport 验证核实报告的签名
+// @b   报告包基础信息
+// @ok  报告的核实结果
+// @tag 询问包的公钥算法
+// @pub 询问包的公钥
+// @sig 签名数据
+func VerifyReport(b *Base, ok bool, tag DHTag, pub, sig []byte) bool {
+	return msg.VerifyDH(tag, pub, ReportMessage(b.Ver, b.ID, ok), sig)
+}
+
+// ReportMessage 构建报告消息
+// 串联：
+// - 上下文前缀：depots:report
+// - 版本：4字节，大端序
+// - 询问ID：8字节，大端序
+// - 核实结果：1字节，有效为1，无效为0
+func ReportMessage(ver int, id uint64, ok bool) []byte {
+	n := len(reportContext)
+	buf := make([]byte, n+13)
+
+	copy(buf, reportContext)
+	binary.BigEndian.PutUint32(buf[n:], uint32(ver))
+	binary.BigEndian.PutUint64(buf[n+4:], id)
+
+	if ok {
+		buf[n+12] = 1
+	}
+	return buf
+}
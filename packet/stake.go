@@ -0,0 +1,109 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+
+	"github.com/cxio/depots/crypto/msg"
+)
+
+// 权益声明消息的上下文前缀
+const stakeContext = "depots:stake"
+
+// 身份ID和收益地址的长度上限
+const stakeMaxLen = 0xff
+
+// ErrStakeLen 身份ID或收益地址过长
+var ErrStakeLen = errors.New("user id or benefit address is too long")
+
+// Stake 权益声明。
+// 数据源向询问者公布的代币收益地址，以及在数据站群中的身份ID。
+// 签名为可选，用于将两者绑定到节点的签名公钥，
+// 站群管理者据此确认成员身份，支付奖励的应用据此确认收益地址的归属。
+// 签名同时绑定询问ID和数据源的连系端点，中转节点无法将其移植到其它回复中。
+type Stake struct {
+	UserID  string  // 身份ID（站群用），可选
+	Address string  // 收益地址
+	Algor   SignTag // 签名算法
+	Pubkey  []byte  // 节点签名公钥，可选
+	Signd   []byte  // 签名数据，可选
+}
+
+// NewStake 创建权益声明。
+// 如果签名封包为nil，则不签名。
+// @uid  身份ID
+// @addr 收益地址
+// @id   询问ID
+// @xnet 连系协议（websocket|dtls|tcp|udp）
+// @ep   数据源的连系端点
+// @sp   节点签名封包，可选
+func NewStake(uid, addr string, id uint64, xnet string, ep netip.AddrPort, sp *SignPack) (*Stake, error) {
+	if len(uid) > stakeMaxLen || len(addr) > stakeMaxLen || len(xnet) > stakeMaxLen {
+		return nil, ErrStakeLen
+	}
+	s := &Stake{UserID: uid, Address: addr}
+
+	if sp != nil {
+		sig, err := sp.Sign(StakeMessage(uid, addr, id, xnet, ep))
+		if err != nil {
+			return nil, err
+		}
+		s.Algor = sp.Algor
+		s.Pubkey = sp.PublicBytes()
+		s.Signd = sig
+	}
+	return s, nil
+}
+
+// Signed 是否已签名。
+func (s *Stake) Signed() bool {
+	return len(s.Pubkey) > 0
+}
+
+// Verify 验证签名。
+// 询问ID和连系端点取自声明所在的回复，与签名时不同则验证失败。
+// 未签名或内容超长的声明返回假。
+// @id   询问ID
+// @xnet 连系协议
+// @ep   数据源的连系端点
+func (s *Stake) Verify(id uint64, xnet string, ep netip.AddrPort) bool {
+	if !s.Signed() ||
+		len(s.UserID) > stakeMaxLen ||
+		len(s.Address) > stakeMaxLen ||
+		len(xnet) > stakeMaxLen {
+		return false
+	}
+	sp := msg.NewSignPack(s.Algor, nil)
+	if sp == nil || len(s.Pubkey) != s.Algor.PublicSize() {
+		return false
+	}
+	return sp.Verify(s.Pubkey, StakeMessage(s.UserID, s.Address, id, xnet, ep), s.Signd)
+}
+
+// StakeMessage 构建权益声明消息
+// 串联（整数均为大端序）：
+// - 上下文前缀：depots:stake
+// - 询问ID：8字节
+// - 连系协议：1字节长度 + 内容
+// - 端点IP：16字节，IPv4为映射形式
+// - 端点端口：2字节
+// - 身份ID：1字节长度 + 内容
+// - 收益地址：1字节长度 + 内容
+func StakeMessage(uid, addr string, id uint64, xnet string, ep netip.AddrPort) []byte {
+	buf := make([]byte, 0, len(stakeContext)+29+len(xnet)+len(uid)+len(addr))
+
+	buf = append(buf, stakeContext...)
+	buf = binary.BigEndian.AppendUint64(buf, id)
+	buf = append(buf, byte(len(xnet)))
+	buf = append(buf, xnet...)
+	ip := ep.Addr().As16()
+	buf = append(buf, ip[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, ep.Port())
+	buf = append(buf, byte(len(uid)))
+	buf = append(buf, uid...)
+	buf = append(buf, byte(len(addr)))
+	buf = append(buf, addr...)
+
+	return buf
+}
@@ -0,0 +1,77 @@
+package packet_test
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 创建一个随机密钥的签名封包。
+func newPack(t testing.TB, tag msg.SignTag) *msg.SignPack {
+	t.Helper()
+
+	priv, err := msg.GenerateSignKey(tag)
+	if err != nil {
+		t.Fatalf("algor %d: generate key: %v", tag, err)
+	}
+	sp := msg.NewSignPack(tag, priv)
+	if sp == nil {
+		t.Fatalf("algor %d: no sign pack", tag)
+	}
+	return sp
+}
+
+func TestStake(t *testing.T) {
+	const id = 0x5a5a
+	ep := netip.MustParseAddrPort("10.0.0.1:7790")
+
+	for _, tag := range msg.SignTags {
+		sp := newPack(t, tag)
+
+		s, err := packet.NewStake("station-1", "bc1qexample", id, "udp", ep, sp)
+		if err != nil {
+			t.Fatalf("algor %d: %v", tag, err)
+		}
+		if !s.Signed() || !s.Verify(id, "udp", ep) {
+			t.Fatalf("algor %d: valid stake rejected", tag)
+		}
+		// 移植到其它回复
+		if s.Verify(id+1, "udp", ep) {
+			t.Errorf("algor %d: stake accepted for another quest", tag)
+		}
+		if s.Verify(id, "tcp", ep) {
+			t.Errorf("algor %d: stake accepted for another network", tag)
+		}
+		for _, other := range []string{"10.0.0.2:7790", "10.0.0.1:7791", "[::ffff:10.0.0.2]:7790"} {
+			if s.Verify(id, "udp", netip.MustParseAddrPort(other)) {
+				t.Errorf("algor %d: stake accepted for endpoint %s", tag, other)
+			}
+		}
+		// 替换公钥或内容
+		forged := *s
+		forged.Pubkey = newPack(t, tag).PublicBytes()
+		if forged.Verify(id, "udp", ep) {
+			t.Errorf("algor %d: stake accepted under another key", tag)
+		}
+		forged = *s
+		forged.Address = "bc1qattacker"
+		if forged.Verify(id, "udp", ep) {
+			t.Errorf("algor %d: stake accepted with another address", tag)
+		}
+	}
+	// 未签名
+	s, err := packet.NewStake("", "bc1qexample", id, "udp", ep, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Signed() || s.Verify(id, "udp", ep) {
+		t.Error("unsigned stake verified")
+	}
+	if _, err = packet.NewStake(strings.Repeat("x", 256), "", id, "udp", ep, nil); !errors.Is(err, packet.ErrStakeLen) {
+		t.Errorf("long user id: %v", err)
+	}
+}
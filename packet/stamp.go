@@ -0,0 +1,45 @@
+package packet
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// NonceSize 探测签名随机数长度
+const NonceSize = 16
+
+// ErrStamp 签名时间戳信息缺失或无效
+var ErrStamp = errors.New("probe stamp is missing or invalid")
+
+// Stamp 探测包签名的时间戳信息。
+// 时间戳和随机数包含在签名消息内，
+// 接收者据此限定探测包的有效时间窗口，并在窗口内拒绝重复的随机数。
+type Stamp struct {
+	Time  time.Time // 签名时间（秒精度）
+	Nonce []byte    // 随机数
+}
+
+// NewStamp 创建一个当前时间的时间戳信息。
+func NewStamp() (*Stamp, error) {
+	nonce := make([]byte, NonceSize)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Stamp{Time: time.Unix(time.Now().Unix(), 0), Nonce: nonce}, nil
+}
+
+// Bytes 时间戳信息的字节序列。
+// 串联：
+// - 时间戳：8字节，Unix秒，大端序
+// - 随机数：NonceSize 字节
+func (st *Stamp) Bytes() []byte {
+	buf := make([]byte, 8+len(st.Nonce))
+
+	binary.BigEndian.PutUint64(buf, uint64(st.Time.Unix()))
+	copy(buf[8:], st.Nonce)
+
+	return buf
+}
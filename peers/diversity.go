@@ -0,0 +1,86 @@
+package peers
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// 多样性分组的网段长度
+const (
+	bucketBits4 = 16 // IPv4 /16
+	bucketBits6 = 32 // IPv6 /32
+)
+
+// 多样性分组。
+// 同一自治域的节点为一组，对照表中没有的按网段分组。
+// 非公网地址（回环、内网等）各自为一组，便于本地测试和内网组网。
+type bucket struct {
+	asn uint32
+	net netip.Prefix
+}
+
+// 网段到自治域编号的对照表。
+// 按前缀长度分组，查询时最长前缀优先匹配。
+type asnTable struct {
+	lens []int // 前缀长度，从长到短
+	sets map[int]map[netip.Prefix]uint32
+}
+
+// 创建对照表。
+// 空表返回nil。
+func newASN(list map[netip.Prefix]uint32) *asnTable {
+	if len(list) == 0 {
+		return nil
+	}
+	t := &asnTable{sets: make(map[int]map[netip.Prefix]uint32)}
+
+	for p, asn := range list {
+		set := t.sets[p.Bits()]
+		if set == nil {
+			set = make(map[netip.Prefix]uint32)
+			t.sets[p.Bits()] = set
+			t.lens = append(t.lens, p.Bits())
+		}
+		set[p] = asn
+	}
+	slices.Sort(t.lens)
+	slices.Reverse(t.lens)
+
+	return t
+}
+
+// 查询IP所属的自治域。
+func (t *asnTable) lookup(ip netip.Addr) (uint32, bool) {
+	if t == nil {
+		return 0, false
+	}
+	for _, n := range t.lens {
+		p, err := ip.Prefix(n)
+		if err != nil {
+			continue
+		}
+		if asn, ok := t.sets[n][p]; ok {
+			return asn, true
+		}
+	}
+	return 0, false
+}
+
+// 获取IP的多样性分组。
+func (t *asnTable) bucket(ip netip.Addr) bucket {
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return bucket{net: netip.PrefixFrom(ip, ip.BitLen())}
+	}
+	if asn, ok := t.lookup(ip); ok {
+		return bucket{asn: asn}
+	}
+	bits := bucketBits6
+	if ip.Is4() {
+		bits = bucketBits4
+	}
+	p, _ := ip.Prefix(bits)
+
+	return bucket{net: p}
+}
@@ -0,0 +1,674 @@
+// Package peers 本类节点（depots）连接管理。
+// 维持目标数量的节点连接，定期以新的候选节点替换评分最差的连接，
+// 退出时将良好的节点写回 peers.json，以便下次启动时快速组网。
+// 连接的节点按网段或自治域分散，以防范日蚀攻击（eclipse）。
+package peers
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/ban"
+	"github.com/cxio/depots/base"
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/repute"
+)
+
+// Dialer 节点连接函数。
+// 通常为TCP连接后在其上完成加密握手（link.Client）并协商通讯参数（link.Greet）。
+type Dialer func(ctx context.Context, addr netip.AddrPort) (net.Conn, error)
+
+// 候选节点
+type candidate struct {
+	peer  *config.Peer // 节点配置（含钉扎身份）
+	fails int          // 连续连接失败次数
+	retry time.Time    // 下次可尝试连接的时间
+	seen  time.Time    // 最近被添加或连接成功的时间
+}
+
+// 是否失败过或已过时。
+func (c *candidate) terrible(now time.Time) bool {
+	return c.fails > 0 || now.Sub(c.seen) >= config.DepotStale
+}
+
+// 已连接节点
+type active struct {
+	conn  net.Conn  // 连接
+	score int       // 评分
+	since time.Time // 连接时间
+}
+
+// Manager 节点连接管理器。
+// - 启动时从用户配置（peers.json）载入候选节点，连接至目标数量。
+// - 连接断开后即时补充，巡查时替换评分最差的节点。
+// - 连续连接失败的候选节点会被移除。
+// - 候选池有总量（config.DepotPool）和分组（config.DepotPoolBucket）上限，超出时淘汰失败过或久未见的候选，以免外部来源无限添加。
+// - 同一网段（IPv4 /16、IPv6 /32）或同一自治域的连接数不超过 config.DepotBucket。
+// - 本机协议栈无法连接的地址族被排除，仅有IPv6的节点也可组网。
+// 节点的连接、断开和替换历史记录在 base.LogPeer 中。
+type Manager struct {
+	mu      sync.Mutex
+	target  int
+	dial    Dialer
+	pins    []*config.Peer // 配置中钉扎了身份的节点，保存时保留
+	pool    map[netip.AddrPort]*candidate
+	actives map[netip.AddrPort]*active
+	bans    *ban.Manager // 禁闭管理，可选
+	book    *repute.Book // 节点信誉，可选
+	asn     *asnTable    // 网段自治域对照表，可选
+	stack   base.Stack   // 本机IP协议栈
+	kick    chan struct{}
+}
+
+// New 创建节点连接管理器。
+// @target 目标连接数，零值取默认值（config.Depots）
+// @dial   节点连接函数
+func New(target int, dial Dialer) *Manager {
+	if target <= 0 {
+		target = config.Depots
+	}
+	return &Manager{
+		target:  target,
+		dial:    dial,
+		pool:    make(map[netip.AddrPort]*candidate),
+		actives: make(map[netip.AddrPort]*active),
+		stack:   base.LocalStack(),
+		kick:    make(chan struct{}, 1),
+	}
+}
+
+// Load 从用户配置载入候选节点。
+// 配置文件 ~/.depots/peers.json，未配置端口的节点使用默认服务端口。
+// 同时载入可选的网段自治域对照表（~/.depots/asn.txt），用于连接的分散。
+func (m *Manager) Load() error {
+	peers, err := config.Peers()
+	if err != nil {
+		return err
+	}
+	prefixes, err := config.Prefixes()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.asn = newASN(prefixes)
+
+	for _, p := range peers {
+		if !p.IP.IsValid() {
+			continue
+		}
+		p.IP = p.IP.Unmap()
+
+		if p.Port == 0 {
+			p.Port = config.ServerTCP
+		}
+		if p.Pubkey != "" {
+			m.pins = append(m.pins, p)
+		}
+		m.add(p)
+	}
+	return nil
+}
+
+// Add 添加一个候选节点。
+// 已存在的节点仅更新其最近所见时间。
+// 候选池或所在分组已满，且没有可淘汰的候选时，新节点被忽略。
+// @addr 节点地址
+// @return 是否为新添加
+func (m *Manager) Add(addr netip.AddrPort) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bans != nil && m.bans.Banned(addr.Addr()) {
+		return false
+	}
+	return m.add(&config.Peer{IP: addr.Addr().Unmap(), Port: addr.Port()})
+}
+
+// Guard 设置禁闭管理器。
+// 禁闭中的节点不会被添加为候选，也不会被连接。
+// 巡查时断开已被禁闭的节点，即时断开可在禁闭通知中调用 DropNet。
+// @b 禁闭管理器
+func (m *Manager) Guard(b *ban.Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bans = b
+}
+
+// Weigh 设置节点信誉簿。
+// 巡查替换和保存时，节点的评分附加其信誉分（依回复质量）。
+// @r 信誉簿
+func (m *Manager) Weigh(r *repute.Book) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.book = r
+}
+
+// Score 调整已连接节点的评分。
+// 外部根据节点的表现（如有效回复、恶意数据）调用，未连接的节点被忽略。
+// @addr  节点地址
+// @delta 评分增量，可为负
+func (m *Manager) Score(addr netip.AddrPort, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a := m.actives[addr]; a != nil {
+		a.score += delta
+	}
+}
+
+// Drop 移除一个已断开的节点。
+// 应当在节点连接的读写出错后调用，管理器会尽快补充新的连接。
+// 该节点仍保留为候选，但需在重试间隔之后才会再次连接。
+// @addr 节点地址
+func (m *Manager) Drop(addr netip.AddrPort) {
+	m.mu.Lock()
+	a := m.remove(addr, "dropped")
+	m.mu.Unlock()
+
+	if a == nil {
+		return
+	}
+	a.conn.Close()
+
+	select {
+	case m.kick <- struct{}{}:
+	default:
+	}
+}
+
+// DropNet 断开某网段内的所有已连接节点。
+// 通常用于节点被禁闭时，这些节点的重试也会被禁闭阻止。
+// @p 网段
+func (m *Manager) DropNet(p netip.Prefix) {
+	for _, ap := range m.Peers() {
+		if p.Contains(ap.Addr()) {
+			m.Drop(ap)
+		}
+	}
+}
+
+// Peers 返回已连接节点的地址清单。
+func (m *Manager) Peers() []netip.AddrPort {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]netip.AddrPort, 0, len(m.actives))
+
+	for ap := range m.actives {
+		list = append(list, ap)
+	}
+	return list
+}
+
+// Conn 获取已连接节点的连接。
+// 未连接时返回nil。
+// @addr 节点地址
+func (m *Manager) Conn(addr netip.AddrPort) net.Conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a := m.actives[addr]; a != nil {
+		return a.conn
+	}
+	return nil
+}
+
+// Len 返回已连接节点数。
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.actives)
+}
+
+// Pool 返回候选节点数。
+func (m *Manager) Pool() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pool)
+}
+
+// Run 运行节点连接管理。
+// 阻塞直到上下文退出，退出时保存良好的节点（Save）并关闭所有连接。
+// @ctx 执行上下文
+// @return 保存节点清单的错误
+func (m *Manager) Run(ctx context.Context) error {
+	tick := time.NewTicker(config.DepotPatrol)
+	defer tick.Stop()
+
+	m.fill(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := m.Save()
+			m.closeAll()
+			return err
+		case <-tick.C:
+			m.patrol(ctx)
+		case <-m.kick:
+			m.fill(ctx)
+		}
+	}
+}
+
+// Save 保存良好的节点到 peers.json。
+// 良好的节点为评分（含信誉分）非负的已连接节点，按评分从高到低排列，
+// 配置中钉扎了身份的节点总是保留。
+// 没有良好的节点时不覆盖原文件，以免丢失启动种子。
+func (m *Manager) Save() error {
+	m.mu.Lock()
+	good := make([]netip.AddrPort, 0, len(m.actives))
+
+	ranks := make(map[netip.AddrPort]float64, len(m.actives))
+
+	for ap := range m.actives {
+		if ranks[ap] = m.rank(ap); ranks[ap] >= 0 {
+			good = append(good, ap)
+		}
+	}
+	if len(good) == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	slices.SortFunc(good, func(a, b netip.AddrPort) int {
+		return cmp.Compare(ranks[b], ranks[a])
+	})
+	list := make([]*config.Peer, 0, len(good)+len(m.pins))
+	seen := make(map[netip.Addr]bool)
+
+	for _, ap := range good {
+		if c := m.pool[ap]; c != nil {
+			list = append(list, c.peer)
+			seen[ap.Addr()] = true
+		}
+	}
+	for _, p := range m.pins {
+		if !seen[p.IP] {
+			list = append(list, p)
+		}
+	}
+	m.mu.Unlock()
+
+	return config.SavePeers(list)
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 添加候选节点。
+// 所在分组已满时，只能淘汰同组中失败过或久未见的候选。
+// 候选池已满时，先淘汰失败过或久未见的候选，
+// 否则若新节点的分组明显小于最大的分组，淘汰最大分组中最久未见的候选（增进多样性）。
+// 调用者需持有锁。
+func (m *Manager) add(p *config.Peer) bool {
+	ap := netip.AddrPortFrom(p.IP, p.Port)
+	now := time.Now()
+
+	if c, ok := m.pool[ap]; ok {
+		c.seen = now
+		return false
+	}
+	b := m.asn.bucket(p.IP)
+	counts := make(map[bucket]int)
+
+	for x := range m.pool {
+		counts[m.asn.bucket(x.Addr())]++
+	}
+	switch {
+	case counts[b] >= config.DepotPoolBucket:
+		if !m.evict(now, func(x bucket) bool { return x == b }, false) {
+			return false
+		}
+	case len(m.pool) >= config.DepotPool:
+		if m.evict(now, nil, false) {
+			break
+		}
+		top, n := b, counts[b]
+
+		for x, c := range counts {
+			if c > n {
+				top, n = x, c
+			}
+		}
+		// 淘汰后最大的分组须仍大于新节点的分组
+		if n <= counts[b]+1 || !m.evict(now, func(x bucket) bool { return x == top }, true) {
+			return false
+		}
+	}
+	m.pool[ap] = &candidate{peer: p, seen: now}
+	return true
+}
+
+// 淘汰一个候选节点。
+// 已连接和钉扎了身份的节点不淘汰。
+// 优先淘汰连续失败次数多的，其次为最久未见的。
+// 调用者需持有锁。
+// @in  分组限定，nil表示不限
+// @force 是否可淘汰良好的候选（否则仅淘汰失败过或久未见的）
+// @return 是否淘汰了一个
+func (m *Manager) evict(now time.Time, in func(bucket) bool, force bool) bool {
+	var victim netip.AddrPort
+	var worst *candidate
+
+	for ap, c := range m.pool {
+		if _, ok := m.actives[ap]; ok || c.peer.Pubkey != "" {
+			continue
+		}
+		if !force && !c.terrible(now) {
+			continue
+		}
+		if in != nil && !in(m.asn.bucket(ap.Addr())) {
+			continue
+		}
+		if worst == nil || c.fails > worst.fails ||
+			(c.fails == worst.fails && c.seen.Before(worst.seen)) {
+			victim, worst = ap, c
+		}
+	}
+	if worst == nil {
+		return false
+	}
+	delete(m.pool, victim)
+	return true
+}
+
+// 移除久未见的候选节点。
+// 已连接和钉扎了身份的节点保留。
+// 调用者需持有锁。
+func (m *Manager) prune(now time.Time) {
+	for ap, c := range m.pool {
+		if _, ok := m.actives[ap]; ok || c.peer.Pubkey != "" {
+			continue
+		}
+		if now.Sub(c.seen) >= config.DepotStale {
+			delete(m.pool, ap)
+			logf("removed %s: not seen since %s", ap, c.seen.Format(time.DateTime))
+		}
+	}
+}
+
+// 移除已连接节点并记录。
+// 返回被移除的节点，未连接时返回nil。
+// 调用者需持有锁，连接的关闭由调用者在锁外执行。
+func (m *Manager) remove(addr netip.AddrPort, reason string) *active {
+	a := m.actives[addr]
+	if a == nil {
+		return nil
+	}
+	delete(m.actives, addr)
+
+	if c := m.pool[addr]; c != nil {
+		c.retry = time.Now().Add(config.DepotRetry)
+	}
+	logf("%s %s score=%d after %s", reason, addr, a.score, time.Since(a.since).Round(time.Second))
+
+	return a
+}
+
+// 补充连接至目标数量。
+func (m *Manager) fill(ctx context.Context) {
+	m.mu.Lock()
+	n := m.target - len(m.actives)
+	m.mu.Unlock()
+
+	if n > 0 {
+		m.connect(ctx, m.fresh(n))
+	}
+}
+
+// 巡查。
+// 先断开已被禁闭的节点，移除久未见的候选并补充连接，然后以新的候选节点替换评分最差的节点。
+// 仅在连接数已达目标时替换，新节点连接成功才移除旧节点。
+// 移除的旧节点须仍在连接，移除后连接数不低于目标。
+func (m *Manager) patrol(ctx context.Context) {
+	m.expel()
+
+	m.mu.Lock()
+	m.prune(time.Now())
+	m.mu.Unlock()
+
+	m.fill(ctx)
+
+	m.mu.Lock()
+	if len(m.actives) < m.target {
+		m.mu.Unlock()
+		return
+	}
+	worst := m.worst(config.DepotRotate)
+	olds := make([]*active, len(worst))
+
+	for i, ap := range worst {
+		olds[i] = m.actives[ap]
+	}
+	m.mu.Unlock()
+
+	got := m.connect(ctx, m.fresh(len(worst)))
+	if len(got) == 0 {
+		return
+	}
+	closes := make([]net.Conn, 0, len(got))
+
+	m.mu.Lock()
+	// 期间断开的节点已让出位置，仅移除超出目标的部分
+	n := min(len(got), len(m.actives)-m.target)
+
+	for i, ap := range worst {
+		if len(closes) >= n {
+			break
+		}
+		// 已断开，或断开后又重新连接的节点不再移除
+		if m.actives[ap] != olds[i] {
+			continue
+		}
+		closes = append(closes, m.remove(ap, "rotated").conn)
+	}
+	m.mu.Unlock()
+
+	for _, conn := range closes {
+		conn.Close()
+	}
+}
+
+// 断开已被禁闭的节点。
+func (m *Manager) expel() {
+	m.mu.Lock()
+	b := m.bans
+	m.mu.Unlock()
+
+	if b == nil {
+		return
+	}
+	for _, ap := range m.Peers() {
+		if b.Banned(ap.Addr()) {
+			m.Drop(ap)
+		}
+	}
+}
+
+// 节点的综合评分。
+// 连接评分附加信誉分（如果设置了信誉簿）。
+// 调用者需持有锁。
+func (m *Manager) rank(ap netip.AddrPort) float64 {
+	v := float64(m.actives[ap].score)
+
+	if m.book != nil {
+		v += m.book.Score(ap)
+	}
+	return v
+}
+
+// 选取评分最差的已连接节点。
+// 所在分组超额的节点优先被替换（如多样性规则生效前的连接）。
+// 评分相同时，连接时间较早的优先被替换，以使连接集逐渐更新。
+// 调用者需持有锁。
+// @n 选取数量上限
+func (m *Manager) worst(n int) []netip.AddrPort {
+	list := make([]netip.AddrPort, 0, len(m.actives))
+	ranks := make(map[netip.AddrPort]float64, len(m.actives))
+	counts := m.buckets()
+
+	for ap := range m.actives {
+		list = append(list, ap)
+		ranks[ap] = m.rank(ap)
+	}
+	crowded := func(ap netip.AddrPort) bool {
+		return counts[m.asn.bucket(ap.Addr())] > config.DepotBucket
+	}
+	slices.SortFunc(list, func(a, b netip.AddrPort) int {
+		if x, y := crowded(a), crowded(b); x != y {
+			if x {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(ranks[a], ranks[b]); c != 0 {
+			return c
+		}
+		return m.actives[a].since.Compare(m.actives[b].since)
+	})
+	return list[:min(n, len(list))]
+}
+
+// 随机选取可连接的候选节点。
+// 排除已连接、尚在重试间隔内、禁闭中和本机协议栈无法连接的节点。
+// 选取时分散于各分组：先选取尚无连接的分组，再选取未满额的分组，
+// 满额（config.DepotBucket）的分组不再选取。
+// @n 选取数量上限
+func (m *Manager) fresh(n int) []netip.AddrPort {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	list := make([]netip.AddrPort, 0, len(m.pool))
+
+	for ap, c := range m.pool {
+		if _, ok := m.actives[ap]; ok || now.Before(c.retry) {
+			continue
+		}
+		if m.bans != nil && m.bans.Banned(ap.Addr()) {
+			continue
+		}
+		if !m.stack.Reach(ap.Addr()) {
+			continue
+		}
+		list = append(list, ap)
+	}
+	rand.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	counts := m.buckets()
+	out := make([]netip.AddrPort, 0, n)
+	used := make(map[netip.AddrPort]bool)
+
+	for _, limit := range []int{1, config.DepotBucket} {
+		for _, ap := range list {
+			if len(out) >= n {
+				return out
+			}
+			b := m.asn.bucket(ap.Addr())
+
+			if used[ap] || counts[b] >= limit {
+				continue
+			}
+			counts[b]++
+			used[ap] = true
+			out = append(out, ap)
+		}
+	}
+	return out
+}
+
+// 统计已连接节点的分组计数。
+// 调用者需持有锁。
+func (m *Manager) buckets() map[bucket]int {
+	counts := make(map[bucket]int)
+
+	for ap := range m.actives {
+		counts[m.asn.bucket(ap.Addr())]++
+	}
+	return counts
+}
+
+// 并发连接节点。
+// 连接失败的候选节点延后重试，连续失败达到上限时移除。
+// @list 节点地址清单
+// @return 连接成功的节点
+func (m *Manager) connect(ctx context.Context, list []netip.AddrPort) []netip.AddrPort {
+	var wg sync.WaitGroup
+	var out []netip.AddrPort
+
+	for _, ap := range list {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			conn, err := m.dial(ctx, ap)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			if err != nil {
+				m.failed(ap, err)
+				return
+			}
+			if c := m.pool[ap]; c != nil {
+				c.fails, c.seen = 0, time.Now()
+			}
+			m.actives[ap] = &active{conn: conn, since: time.Now()}
+			out = append(out, ap)
+
+			logf("connected %s", ap)
+		}()
+	}
+	wg.Wait()
+
+	return out
+}
+
+// 记录连接失败。
+// 调用者需持有锁。
+func (m *Manager) failed(ap netip.AddrPort, err error) {
+	c := m.pool[ap]
+	if c == nil {
+		return
+	}
+	c.fails++
+
+	if c.fails >= config.DepotFails {
+		delete(m.pool, ap)
+		logf("removed %s after %d failures: %v", ap, c.fails, err)
+		return
+	}
+	c.retry = time.Now().Add(config.DepotRetry * time.Duration(c.fails))
+}
+
+// 关闭所有连接。
+func (m *Manager) closeAll() {
+	m.mu.Lock()
+	list := make([]net.Conn, 0, len(m.actives))
+
+	for ap := range m.actives {
+		list = append(list, m.remove(ap, "closed").conn)
+	}
+	m.mu.Unlock()
+
+	for _, conn := range list {
+		conn.Close()
+	}
+}
+
+// 记录节点历史。
+func logf(format string, v ...any) {
+	if base.LogPeer != nil {
+		base.LogPeer.Printf(format, v...)
+	}
+}
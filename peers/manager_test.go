@@ -0,0 +1,208 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+)
+
+// 不可用的连接函数。
+func noDial(context.Context, netip.AddrPort) (net.Conn, error) {
+	return nil, errors.New("unreachable")
+}
+
+// 第n个公网地址，每个地址一个 /16 分组。
+func spread(n int) netip.AddrPort {
+	return netip.AddrPortFrom(netip.AddrFrom4([4]byte{20 + byte(n>>8), byte(n), 1, 1}), config.ServerTCP)
+}
+
+// 同一 /16 分组内的第n个地址。
+func crowd(n int) netip.AddrPort {
+	return netip.AddrPortFrom(netip.AddrFrom4([4]byte{8, 8, byte(n >> 8), byte(n)}), config.ServerTCP)
+}
+
+func TestPoolBucketCap(t *testing.T) {
+	m := New(0, noDial)
+
+	for i := range config.DepotPoolBucket {
+		if !m.Add(crowd(i)) {
+			t.Fatalf("add %d rejected", i)
+		}
+	}
+	if m.Add(crowd(config.DepotPoolBucket)) {
+		t.Error("crowded bucket grew beyond the cap")
+	}
+	// 失败过的候选可被同组的新节点替换
+	m.mu.Lock()
+	m.failed(crowd(3), errors.New("refused"))
+	m.mu.Unlock()
+
+	if !m.Add(crowd(config.DepotPoolBucket)) {
+		t.Fatal("failed candidate not evicted")
+	}
+	if m.Pool() != config.DepotPoolBucket {
+		t.Errorf("pool: %d", m.Pool())
+	}
+	m.mu.Lock()
+	_, ok := m.pool[crowd(3)]
+	m.mu.Unlock()
+
+	if ok {
+		t.Error("failed candidate kept")
+	}
+}
+
+func TestPoolCap(t *testing.T) {
+	m := New(0, noDial)
+
+	for i := range config.DepotPool {
+		m.Add(spread(i))
+	}
+	if m.Pool() != config.DepotPool {
+		t.Fatalf("pool: %d", m.Pool())
+	}
+	// 各分组均只有一个候选，新分组不能挤出良好的候选
+	if m.Add(spread(config.DepotPool)) {
+		t.Error("pool grew beyond the cap")
+	}
+	// 久未见的候选被淘汰
+	stale := spread(10)
+	m.mu.Lock()
+	m.pool[stale].seen = time.Now().Add(-config.DepotStale)
+	m.mu.Unlock()
+
+	if !m.Add(spread(config.DepotPool)) {
+		t.Fatal("stale candidate not evicted")
+	}
+	if m.Pool() != config.DepotPool {
+		t.Errorf("pool: %d", m.Pool())
+	}
+	// 重复添加刷新所见时间
+	m.mu.Lock()
+	old := time.Now().Add(-config.DepotStale)
+	m.pool[spread(11)].seen = old
+	m.mu.Unlock()
+
+	if m.Add(spread(11)) {
+		t.Error("existing candidate added again")
+	}
+	m.mu.Lock()
+	refreshed := m.pool[spread(11)].seen.After(old)
+	m.mu.Unlock()
+
+	if !refreshed {
+		t.Error("seen time not refreshed")
+	}
+}
+
+func TestPoolDiversity(t *testing.T) {
+	m := New(0, noDial)
+
+	// 一个分组占据大量候选，其余各一个
+	for i := range config.DepotPoolBucket {
+		m.Add(crowd(i))
+	}
+	for i := 0; m.Pool() < config.DepotPool; i++ {
+		m.Add(spread(i))
+	}
+	// 新分组的节点挤出最大分组中的一个
+	ap := netip.MustParseAddrPort("99.1.1.1:7799")
+
+	if !m.Add(ap) {
+		t.Fatal("new bucket rejected while another is crowded")
+	}
+	n := 0
+	m.mu.Lock()
+	for x := range m.pool {
+		if x.Addr().As4()[0] == 8 {
+			n++
+		}
+	}
+	m.mu.Unlock()
+
+	if n != config.DepotPoolBucket-1 {
+		t.Errorf("crowded bucket: %d", n)
+	}
+}
+
+func TestPoolPinned(t *testing.T) {
+	m := New(0, noDial)
+	pin := crowd(0)
+
+	m.mu.Lock()
+	m.add(&config.Peer{IP: pin.Addr(), Port: pin.Port(), Pubkey: "00"})
+	m.pool[pin].seen = time.Now().Add(-config.DepotStale)
+	m.prune(time.Now())
+	_, ok := m.pool[pin]
+	m.mu.Unlock()
+
+	if !ok {
+		t.Error("pinned candidate pruned")
+	}
+}
+
+// 创建目标为3的管理器：已连接a、b、c（评分依次递增），候选两个新节点。
+// 连接新节点时调用一次hook（可为nil）。
+func rotating(a, b, c netip.AddrPort, hook func(m *Manager)) *Manager {
+	var once sync.Once
+	var m *Manager
+
+	m = New(3, func(context.Context, netip.AddrPort) (net.Conn, error) {
+		if hook != nil {
+			once.Do(func() { hook(m) })
+		}
+		conn, _ := net.Pipe()
+		return conn, nil
+	})
+	connected(m, a, b, c)
+	m.Add(spread(10))
+	m.Add(spread(11))
+
+	return m
+}
+
+// 添加已连接节点，评分依次递增。
+func connected(m *Manager, addrs ...netip.AddrPort) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, ap := range addrs {
+		conn, _ := net.Pipe()
+		m.actives[ap] = &active{conn: conn, score: i * 10, since: time.Now()}
+	}
+}
+
+func TestPatrolRotate(t *testing.T) {
+	a, b, c := spread(1), spread(2), spread(3)
+
+	// 评分最差的两个被新节点替换
+	m := rotating(a, b, c, nil)
+	m.patrol(context.Background())
+
+	if m.Conn(a) != nil || m.Conn(b) != nil || m.Conn(c) == nil || len(m.Peers()) != 3 {
+		t.Fatalf("rotated: %v", m.Peers())
+	}
+	// 替换期间另一节点断开：只移除超出目标的部分
+	m = rotating(a, b, c, func(m *Manager) { m.Drop(c) })
+	m.patrol(context.Background())
+
+	if m.Conn(a) != nil || m.Conn(b) == nil || len(m.Peers()) != 3 {
+		t.Fatalf("rotated with a disconnect: %v", m.Peers())
+	}
+	// 期间断开后又重新连接的节点不被移除
+	m = rotating(a, b, c, func(m *Manager) {
+		m.Drop(a)
+		connected(m, a)
+	})
+	m.patrol(context.Background())
+
+	if m.Conn(a) == nil || m.Conn(b) != nil || len(m.Peers()) != 4 {
+		t.Fatalf("reconnected peer removed: %v", m.Peers())
+	}
+}
@@ -0,0 +1,256 @@
+// Package pex 驿站之间的节点交换（peer exchange）。
+// 已连接的驿站定期分享一个有限的地址样本：发送方近期核实过（成功连接）的节点，
+// 附带其NAT层级和支持的数据类别。样本以发送方的身份密钥签名并带时间戳，
+// 接收方核实签名和时效，拒绝重放，收到的地址按连接限量交由候选池（通常为 peers.Manager）。
+// 这使得在Findings节点不可用时，本类节点的组网仍可自我修复。
+package pex
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/base"
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+var (
+	// ErrStale 签名时间超出窗口，或不晚于该签名者已接受的时间（重放）
+	ErrStale = errors.New("peer exchange is stale or replayed")
+
+	// ErrKey 缺少身份签名密钥
+	ErrKey = errors.New("peer exchange requires a signing key")
+)
+
+// Sender 节点交换包发送函数。
+// 应当仅发送给协商版本不低于 packet.VersionPex 的节点。
+type Sender func(to netip.AddrPort, data []byte)
+
+// 核实过的节点
+type known struct {
+	level packet.NatLevel
+	kinds uint32
+	seen  time.Time
+}
+
+// Gossip 节点交换。
+// 并发安全。
+type Gossip struct {
+	mu      sync.Mutex
+	sp      *packet.SignPack
+	add     func(netip.AddrPort) bool
+	known   map[netip.AddrPort]*known
+	signers map[string]time.Time   // 各签名者最近接受的签名时间
+	intake  map[netip.AddrPort]int // 本周期各连接已接收的新候选数
+	period  time.Time              // 本周期的起始时间
+}
+
+// New 创建节点交换。
+// @sp  本节点的身份签名封包
+// @add 候选接收函数（如 peers.Manager.Add），返回是否为新候选
+func New(sp *packet.SignPack, add func(netip.AddrPort) bool) *Gossip {
+	return &Gossip{
+		sp:      sp,
+		add:     add,
+		known:   make(map[netip.AddrPort]*known),
+		signers: make(map[string]time.Time),
+		intake:  make(map[netip.AddrPort]int),
+		period:  time.Now(),
+	}
+}
+
+// Verified 记录一个核实过的节点。
+// 应当在成功连接（完成握手）后调用，重复调用更新其信息和核实时间。
+// @addr  节点地址
+// @level 节点的NAT层级
+// @kinds 节点支持的数据类别（位图，可取自其能力声明 packet.Caps.Kinds）
+func (g *Gossip) Verified(addr netip.AddrPort, level packet.NatLevel, kinds uint32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.known[unmap(addr)] = &known{level: level, kinds: kinds, seen: time.Now()}
+}
+
+// Sample 随机选取近期核实过的节点。
+// 核实时间超过 config.PexFresh 的节点被清理。
+// @n 数量上限
+func (g *Gossip) Sample(n int) []*packet.PeerAddr {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	list := make([]*packet.PeerAddr, 0, len(g.known))
+
+	for ap, k := range g.known {
+		if now.Sub(k.seen) >= config.PexFresh {
+			delete(g.known, ap)
+			continue
+		}
+		list = append(list, &packet.PeerAddr{
+			Addr:  ap,
+			Level: k.level,
+			Kinds: k.kinds,
+			Seen:  k.seen,
+		})
+	}
+	rand.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	return list[:min(n, len(list), packet.ExchangeMax)]
+}
+
+// Encode 编码一个签名的节点交换包。
+// 地址样本不超过 config.PexSample 条。
+func (g *Gossip) Encode() ([]byte, error) {
+	if g.sp == nil {
+		return nil, ErrKey
+	}
+	return packet.EncodeExchange(packet.VersionPex, g.Sample(config.PexSample), g.sp)
+}
+
+// Receive 接收一个节点交换包。
+// 签名无效时返回解码错误（可交由禁闭管理器惩罚），
+// 签名时间超出窗口或重放时返回 ErrStale。
+// 有效的地址交由候选接收函数，每个分享周期内每个连接不超过 config.PexIntake 个新候选。
+// 限额按发来该包的连接计算，而非签名者自选的签名密钥，以免一个对端以多个密钥占满候选。
+// 签名者记录不超过 config.PexSigners 个，满时淘汰签名时间最早者。
+// 以下地址被忽略：非公网地址、端口为零、核实时间过旧或在未来。
+// @from 发来该包的连接的对端地址
+// @data 节点交换包数据
+// @return1 被接收的新候选数
+// @return2 签名公钥，可用于与连接握手时的身份比对
+func (g *Gossip) Receive(from netip.AddrPort, data []byte) (int, []byte, error) {
+	addrs, t, pub, err := packet.DecodeExchange(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	now := time.Now()
+
+	if t.Before(now.Add(-config.PexWindow)) || t.After(now.Add(config.PexWindow)) {
+		return 0, pub, ErrStale
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(now)
+
+	last, ok := g.signers[string(pub)]
+	if ok && !t.After(last) {
+		return 0, pub, ErrStale
+	}
+	from = unmap(from)
+
+	// 限额已满的连接不再记录签名者，以免以新的密钥不断扩充记录
+	if g.intake[from] >= config.PexIntake {
+		return 0, pub, nil
+	}
+	if !ok && len(g.signers) >= config.PexSigners {
+		g.evict()
+	}
+	g.signers[string(pub)] = t
+	n := 0
+
+	for _, a := range addrs {
+		if g.intake[from] >= config.PexIntake {
+			break
+		}
+		if !usable(a, now) {
+			continue
+		}
+		if g.add(unmap(a.Addr)) {
+			g.intake[from]++
+			n++
+		}
+	}
+	return n, pub, nil
+}
+
+// Run 定期向已连接的节点分享地址样本。
+// 阻塞直到上下文退出。
+// @ctx   执行上下文
+// @peers 已连接节点的地址清单（如 peers.Manager.Peers）
+// @send  发送函数
+func (g *Gossip) Run(ctx context.Context, peers func() []netip.AddrPort, send Sender) {
+	tick := time.NewTicker(config.PexInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			data, err := g.Encode()
+			if err != nil {
+				logf("pex encode: %v", err)
+				continue
+			}
+			for _, ap := range peers() {
+				send(ap, data)
+			}
+		}
+	}
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 开始新的分享周期，并清理过期的签名者记录。
+// 调用者需持有锁。
+func (g *Gossip) prune(now time.Time) {
+	if now.Sub(g.period) < config.PexInterval {
+		return
+	}
+	g.period = now
+	clear(g.intake)
+
+	for k, t := range g.signers {
+		if now.Sub(t) > config.PexWindow {
+			delete(g.signers, k)
+		}
+	}
+}
+
+// 淘汰签名时间最早的签名者记录。
+// 调用者需持有锁。
+func (g *Gossip) evict() {
+	var old string
+	var last time.Time
+
+	for k, t := range g.signers {
+		if last.IsZero() || t.Before(last) {
+			old, last = k, t
+		}
+	}
+	delete(g.signers, old)
+}
+
+// 地址条目是否可用。
+// 仅接受公网地址，以免诱使本节点连接内网。
+func usable(a *packet.PeerAddr, now time.Time) bool {
+	ip := a.Addr.Addr().Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || a.Addr.Port() == 0 {
+		return false
+	}
+	if a.Seen.After(now.Add(config.PexWindow)) {
+		return false
+	}
+	return now.Sub(a.Seen) < config.PexFresh
+}
+
+// 还原IPv4映射地址。
+func unmap(ap netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+}
+
+// 记录节点历史。
+func logf(format string, v ...any) {
+	if base.LogPeer != nil {
+		base.LogPeer.Printf(format, v...)
+	}
+}
@@ -0,0 +1,206 @@
+package pex
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// 创建一个随机的身份签名封包。
+func newSigner(t *testing.T) *packet.SignPack {
+	t.Helper()
+
+	key, err := msg.GenerateSignKey(msg.SIGN_ED25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg.NewSignPack(msg.SIGN_ED25519, key)
+}
+
+// 自第from个起的n个公网地址样本，核实时间为当前。
+func sample(from, n int) []*packet.PeerAddr {
+	now := time.Unix(time.Now().Unix(), 0)
+	list := make([]*packet.PeerAddr, 0, n)
+
+	for i := from; i < from+n; i++ {
+		ip := netip.AddrFrom4([4]byte{20, byte(i >> 8), byte(i), 1})
+		list = append(list, &packet.PeerAddr{Addr: netip.AddrPortFrom(ip, config.ServerTCP), Seen: now})
+	}
+	return list
+}
+
+// 编码一个指定签名时间的节点交换包。
+func encode(t *testing.T, sp *packet.SignPack, at time.Time, addrs []*packet.PeerAddr) []byte {
+	t.Helper()
+
+	at = time.Unix(at.Unix(), 0)
+	sig, err := sp.Sign(packet.ExchangeMessage(packet.VersionPex, sp.Algor, at, addrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &packet.Exchange{
+		Ver:    packet.VersionPex,
+		Time:   at.Unix(),
+		Algor:  int32(sp.Algor),
+		Pubkey: sp.PublicBytes(),
+		Signd:  sig,
+	}
+	for _, a := range addrs {
+		buf.Addrs = append(buf.Addrs, &packet.ExchangeItem{
+			Ip:    a.Addr.Addr().AsSlice(),
+			Port:  uint32(a.Addr.Port()),
+			Level: int32(a.Level),
+			Kinds: a.Kinds,
+			Seen:  a.Seen.Unix(),
+		})
+	}
+	data, err := proto.Marshal(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// 接收所有地址的节点交换。
+func newGossip() (*Gossip, map[netip.AddrPort]bool) {
+	pool := make(map[netip.AddrPort]bool)
+
+	return New(nil, func(ap netip.AddrPort) bool {
+		if pool[ap] {
+			return false
+		}
+		pool[ap] = true
+		return true
+	}), pool
+}
+
+var link = netip.MustParseAddrPort("30.0.0.1:7788")
+
+func TestReceiveWindow(t *testing.T) {
+	g, _ := newGossip()
+	sp := newSigner(t)
+	now := time.Now()
+
+	for _, at := range []time.Time{now.Add(-config.PexWindow - time.Second), now.Add(config.PexWindow + time.Second)} {
+		if _, _, err := g.Receive(link, encode(t, sp, at, sample(0, 1))); !errors.Is(err, ErrStale) {
+			t.Errorf("signed at %s: %v", at.Sub(now), err)
+		}
+	}
+	if n, pub, err := g.Receive(link, encode(t, sp, now, sample(0, 1))); err != nil || n != 1 || len(pub) == 0 {
+		t.Errorf("fresh exchange: %d, %v", n, err)
+	}
+	// 签名无效
+	data := encode(t, sp, now.Add(time.Second), sample(1, 1))
+	data[len(data)-1] ^= 1
+
+	if _, _, err := g.Receive(link, data); err == nil {
+		t.Error("forged exchange accepted")
+	}
+}
+
+func TestReceiveReplay(t *testing.T) {
+	g, _ := newGossip()
+	sp := newSigner(t)
+	now := time.Now()
+	data := encode(t, sp, now, sample(0, 2))
+
+	if _, _, err := g.Receive(link, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.Receive(link, data); !errors.Is(err, ErrStale) {
+		t.Errorf("replayed: %v", err)
+	}
+	// 不晚于上次接受的签名时间
+	if _, _, err := g.Receive(link, encode(t, sp, now.Add(-time.Second), sample(2, 1))); !errors.Is(err, ErrStale) {
+		t.Errorf("older exchange: %v", err)
+	}
+	if _, _, err := g.Receive(link, encode(t, sp, now.Add(time.Second), sample(2, 1))); err != nil {
+		t.Errorf("newer exchange: %v", err)
+	}
+	// 其它签名者不受影响
+	if _, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(3, 1))); err != nil {
+		t.Errorf("other signer: %v", err)
+	}
+}
+
+func TestReceiveQuota(t *testing.T) {
+	g, pool := newGossip()
+	now := time.Now()
+
+	// 同一连接上轮换签名密钥，共享限额
+	n1, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(0, 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(6, 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n1+n2 != config.PexIntake {
+		t.Errorf("intake on one link: %d", n1+n2)
+	}
+	// 其它连接有各自的限额
+	other := netip.MustParseAddrPort("30.0.0.2:7788")
+
+	if n, _, err := g.Receive(other, encode(t, newSigner(t), now, sample(20, 6))); err != nil || n != 6 {
+		t.Errorf("intake on another link: %d, %v", n, err)
+	}
+	// 非公网地址被忽略
+	private := []*packet.PeerAddr{{Addr: netip.MustParseAddrPort("10.0.0.1:7788"), Seen: now}}
+
+	if n, _, _ := g.Receive(other, encode(t, newSigner(t), now, private)); n != 0 || len(pool) != config.PexIntake+6 {
+		t.Errorf("private address accepted: %d", n)
+	}
+	// 新的周期恢复限额
+	g.mu.Lock()
+	g.period = now.Add(-config.PexInterval)
+	g.mu.Unlock()
+
+	if n, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(40, 2))); err != nil || n != 2 {
+		t.Errorf("intake in a new period: %d, %v", n, err)
+	}
+}
+
+func TestReceiveSigners(t *testing.T) {
+	g, _ := newGossip()
+	now := time.Now()
+
+	// 限额已满的连接以新的密钥发送，不再记录签名者
+	if n, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(0, config.PexIntake))); err != nil || n != config.PexIntake {
+		t.Fatalf("intake: %d, %v", n, err)
+	}
+	for i := range 4 {
+		if n, _, err := g.Receive(link, encode(t, newSigner(t), now, sample(20+i, 1))); err != nil || n != 0 {
+			t.Errorf("over quota: %d, %v", n, err)
+		}
+	}
+	if len(g.signers) != 1 {
+		t.Errorf("signers recorded over quota: %d", len(g.signers))
+	}
+	// 记录数有上限，淘汰签名时间最早者
+	g.signers = make(map[string]time.Time)
+	oldest := newSigner(t)
+	other := netip.MustParseAddrPort("30.0.0.2:7788")
+
+	if _, _, err := g.Receive(other, encode(t, oldest, now.Add(-time.Minute), nil)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range config.PexSigners - 1 {
+		g.signers[string(rune(i))] = now
+	}
+	if _, _, err := g.Receive(other, encode(t, newSigner(t), now, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.signers) != config.PexSigners {
+		t.Errorf("signers: %d", len(g.signers))
+	}
+	if _, ok := g.signers[string(oldest.PublicBytes())]; ok {
+		t.Error("oldest signer kept")
+	}
+}
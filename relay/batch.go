@@ -0,0 +1,108 @@
+package relay
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+	"golang.org/x/crypto/sha3"
+)
+
+// SplitBatch 按本地存在性拆分批量条目。
+// 本地拥有的条目直接回复，其余的条目继续转播（懒原则）。
+// 询问者无法与数据源连通时，视同全部没有数据。
+// @its    询问条目集
+// @asker  询问者NAT层级
+// @source 数据源NAT层级
+// @have   本地存在性检查
+// @return1 本地拥有的条目
+// @return2 需要转播的条目
+func SplitBatch(its []*packet.Item, asker, source packet.NatLevel, have func(index []byte) bool) (hit, miss []*packet.Item) {
+	if !packet.Reachable(asker, source) {
+		return nil, its
+	}
+	for _, it := range its {
+		if have(it.Index) {
+			hit = append(hit, it)
+			continue
+		}
+		miss = append(miss, it)
+	}
+	return
+}
+
+// Chunk 切分后的一个批量。
+type Chunk struct {
+	ID    uint64         // 本批的询问ID
+	Items []*packet.Item // 本批的条目
+}
+
+// ChunkBatch 将条目集切分为多个批量。
+// 每批不超过 config.BatchMax 条，序位保持不变（仍为原始批量中的位置）。
+// 各批有独立的询问ID（见 ChunkID），否则后续批量会被转播的去重和待决询问集丢弃。
+// 询问者据此将回复的询问ID映射回原询问，再按序位对应条目。
+// @id  原询问ID
+// @its 条目集
+func ChunkBatch(id uint64, its []*packet.Item) []*Chunk {
+	var list []*Chunk
+
+	for len(its) > 0 {
+		n := min(len(its), config.BatchMax)
+		list = append(list, &Chunk{
+			ID:    ChunkID(id, len(list)),
+			Items: its[:n:n],
+		})
+		its = its[n:]
+	}
+	return list
+}
+
+// ChunkID 切分批量的询问ID。
+// 首批沿用原询问ID，其余为 SHA3:256(原ID+批次) 的前8字节（大端序）。
+// @id 原询问ID
+// @n  批次（从0开始）
+func ChunkID(id uint64, n int) uint64 {
+	if n == 0 {
+		return id
+	}
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:8], id)
+	binary.BigEndian.PutUint32(buf[8:], uint32(n))
+
+	sum := sha3.Sum256(buf[:])
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// MergeFound 合并批量回复条目。
+// 同一序位可能有多个数据源回复，只保留其中随机的一个向上回传，
+// 避免单纯采用最先到达的回复（参考回复确认策略）。
+// 返回的集合按序位分批，每批不超过 config.BatchMax 条。
+// @fs 收到的全部回复条目
+func MergeFound(fs []*packet.Found) [][]*packet.Found {
+	pool := make(map[int][]*packet.Found)
+	var slots []int
+
+	for _, f := range fs {
+		if _, ok := pool[f.Slot]; !ok {
+			slots = append(slots, f.Slot)
+		}
+		pool[f.Slot] = append(pool[f.Slot], f)
+	}
+	var list [][]*packet.Found
+	var buf []*packet.Found
+
+	for _, slot := range slots {
+		cands := pool[slot]
+		buf = append(buf, cands[rand.IntN(len(cands))])
+
+		if len(buf) == config.BatchMax {
+			list = append(list, buf)
+			buf = nil
+		}
+	}
+	if len(buf) > 0 {
+		list = append(list, buf)
+	}
+	return list
+}
@@ -0,0 +1,237 @@
+package relay_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+)
+
+// 创建n个条目的批量（索引为序位字节）。
+func testItems(n int) []*packet.Item {
+	index := make([][]byte, n)
+
+	for i := range index {
+		index[i] = []byte{byte(i >> 8), byte(i)}
+	}
+	return packet.NewItems(index, nil)
+}
+
+// 创建回复条目（公钥标识来源）。
+func testFound(src byte, slots ...int) []*packet.Found {
+	fs := make([]*packet.Found, len(slots))
+
+	for i, slot := range slots {
+		fs[i] = &packet.Found{Slot: slot, Pubkey: []byte{src}, Contact: []byte{src, byte(slot)}}
+	}
+	return fs
+}
+
+// 编码批量回复包。
+func testBatchReply(t *testing.T, id uint64, fs []*packet.Found) []byte {
+	t.Helper()
+
+	data, err := packet.EncodeBatchReply(&packet.Base{Ver: packet.VersionBind, ID: id}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSplitBatch(t *testing.T) {
+	its := testItems(6)
+	have := func(index []byte) bool { return index[1]%2 == 0 }
+
+	hit, miss := relay.SplitBatch(its, packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_RC, have)
+	if len(hit) != 3 || len(miss) != 3 {
+		t.Fatalf("split: hit %d, miss %d", len(hit), len(miss))
+	}
+	for i, it := range hit {
+		if it.Slot != i*2 {
+			t.Errorf("hit[%d]: slot %d", i, it.Slot)
+		}
+	}
+	for i, it := range miss {
+		if it.Slot != i*2+1 {
+			t.Errorf("miss[%d]: slot %d", i, it.Slot)
+		}
+	}
+	// 不可达时全部转播
+	hit, miss = relay.SplitBatch(its, packet.NAT_LEVEL_SYM, packet.NAT_LEVEL_SYM, have)
+	if len(hit) != 0 || len(miss) != len(its) {
+		t.Errorf("unreachable: hit %d, miss %d", len(hit), len(miss))
+	}
+}
+
+func TestChunkBatch(t *testing.T) {
+	const id = 0x0102030405060708
+	n := config.BatchMax*2 + 10
+	list := relay.ChunkBatch(id, testItems(n))
+
+	if len(list) != 3 {
+		t.Fatalf("chunks: %d", len(list))
+	}
+	if list[0].ID != id {
+		t.Errorf("first chunk id: %x", list[0].ID)
+	}
+	ids := make(map[uint64]bool)
+	slot := 0
+
+	for i, c := range list {
+		if c.ID != relay.ChunkID(id, i) {
+			t.Errorf("chunk %d: id %x", i, c.ID)
+		}
+		ids[c.ID] = true
+
+		if len(c.Items) > config.BatchMax {
+			t.Errorf("chunk %d: %d items", i, len(c.Items))
+		}
+		for _, it := range c.Items {
+			if it.Slot != slot {
+				t.Fatalf("chunk %d: slot %d, want %d", i, it.Slot, slot)
+			}
+			slot++
+		}
+	}
+	if len(ids) != len(list) {
+		t.Errorf("chunk ids not distinct: %v", ids)
+	}
+	if slot != n {
+		t.Errorf("items: %d, want %d", slot, n)
+	}
+	if relay.ChunkBatch(id, nil) != nil {
+		t.Error("empty items should give no chunk")
+	}
+}
+
+func TestMergeFound(t *testing.T) {
+	fs := append(testFound(1, 0, 1, 2), testFound(2, 1, 2, 3)...)
+	list := relay.MergeFound(fs)
+
+	if len(list) != 1 {
+		t.Fatalf("batches: %d", len(list))
+	}
+	slots := make(map[int]int)
+
+	for _, f := range list[0] {
+		slots[f.Slot]++
+	}
+	if len(slots) != 4 {
+		t.Errorf("slots: %v", slots)
+	}
+	for slot, n := range slots {
+		if n != 1 {
+			t.Errorf("slot %d: %d entries", slot, n)
+		}
+	}
+	// 超过上限时分批
+	many := make([]int, config.BatchMax+1)
+	for i := range many {
+		many[i] = i
+	}
+	list = relay.MergeFound(testFound(1, many...))
+
+	if len(list) != 2 || len(list[0]) != config.BatchMax || len(list[1]) != 1 {
+		t.Errorf("split merge: %d batches", len(list))
+	}
+}
+
+// 收集回传的批量回复条目。
+type sink chan []*packet.Found
+
+func (s sink) send(t *testing.T) relay.Sender {
+	return func(_ netip.AddrPort, data []byte) {
+		_, fs, err := packet.DecodeBatchReply(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s <- fs
+	}
+}
+
+// 等待一次回传。
+func (s sink) wait(t *testing.T, d time.Duration) []*packet.Found {
+	t.Helper()
+
+	select {
+	case fs := <-s:
+		return fs
+	case <-time.After(d):
+		t.Fatal("no reply sent")
+	}
+	return nil
+}
+
+func TestPendingBatch(t *testing.T) {
+	const id = 77
+	out := make(sink, 8)
+	ps := relay.NewPending(out.send(t))
+	peer := func(n byte) netip.AddrPort { return netip.AddrPortFrom(netip.AddrFrom4([4]byte{10, 0, 0, n}), 7790) }
+
+	if !ps.AddBatch(id, peer(1), 0, nil, nil, testItems(3)) {
+		t.Fatal("add batch failed")
+	}
+	// 各下级回复不同的序位
+	if !ps.Reply(id, peer(2), testBatchReply(t, id, testFound(2, 0))) {
+		t.Fatal("first reply rejected")
+	}
+	if !ps.Reply(id, peer(3), testBatchReply(t, id, testFound(3, 1, 9))) {
+		t.Fatal("reply with a new slot rejected")
+	}
+	fs := out.wait(t, config.ReplyWait*3)
+	got := make(map[int]bool)
+
+	for _, f := range fs {
+		got[f.Slot] = true
+	}
+	if len(got) != 2 || !got[0] || !got[1] {
+		t.Fatalf("merged slots: %v", got)
+	}
+	// 已回传的序位被忽略，新序位仍被接收
+	if ps.Reply(id, peer(4), testBatchReply(t, id, testFound(4, 0, 1))) {
+		t.Error("reply of answered slots accepted")
+	}
+	for src := byte(5); src < 5+config.ReplyPool; src++ {
+		if !ps.Reply(id, peer(src), testBatchReply(t, id, testFound(src, 2))) {
+			t.Fatalf("reply from %d rejected", src)
+		}
+	}
+	// 候选满额即时回传
+	fs = out.wait(t, config.ReplyWait/2)
+
+	if len(fs) != 1 || fs[0].Slot != 2 {
+		t.Fatalf("last slot: %v", fs)
+	}
+	if ps.Reply(id, peer(9), testBatchReply(t, id, testFound(9, 2))) {
+		t.Error("reply after all slots answered accepted")
+	}
+}
+
+func TestPendingBatchReject(t *testing.T) {
+	const id = 78
+	ps := relay.NewPending(func(netip.AddrPort, []byte) {})
+	from := netip.MustParseAddrPort("10.0.0.1:7790")
+
+	ps.AddBatch(id, from, 0, nil, nil, testItems(2))
+
+	if ps.Reply(id, from, []byte("garbage")) {
+		t.Error("malformed reply accepted")
+	}
+	if ps.Reply(id, from, testBatchReply(t, id+1, testFound(1, 0))) {
+		t.Error("reply of another quest accepted")
+	}
+	if !ps.Reply(id, from, testBatchReply(t, id, testFound(1, 0))) {
+		t.Fatal("valid reply rejected")
+	}
+	old, err := packet.EncodeBatchReply(&packet.Base{Ver: packet.VersionStamp, ID: id}, testFound(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Reply(id, from, old) {
+		t.Error("reply of another version accepted")
+	}
+}
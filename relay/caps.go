@@ -0,0 +1,119 @@
+package relay
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+// 转播目标的优先层级（小者优先）
+const (
+	tierMatch   = iota // 支持该类别且过滤器匹配（或无过滤器）
+	tierUnknown        // 未声明能力
+	tierOther          // 不支持该类别或过滤器不匹配（仍可路由）
+)
+
+// Caps 对端节点的能力声明集。
+// 对端节点声明其支持的数据类别和所服务数据的紧凑过滤器，可随时更新。
+// 转播询问和探测时，据此优先选取可能拥有目标数据的节点。
+type Caps struct {
+	mu    sync.Mutex
+	peers map[netip.AddrPort]*packet.Caps
+}
+
+// NewCaps 创建一个空的能力声明集。
+func NewCaps() *Caps {
+	return &Caps{peers: make(map[netip.AddrPort]*packet.Caps)}
+}
+
+// Update 更新对端节点的能力声明。
+// 序号不大于已有声明的被忽略（乱序或重放）。
+// @peer 对端节点
+// @c    能力声明
+// @return 是否更新
+func (cs *Caps) Update(peer netip.AddrPort, c *packet.Caps) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if old := cs.peers[peer]; old != nil && c.Seq <= old.Seq {
+		return false
+	}
+	cs.peers[peer] = c
+	return true
+}
+
+// Get 获取对端节点的能力声明。
+// 未声明时返回nil。
+// @peer 对端节点
+func (cs *Caps) Get(peer netip.AddrPort) *packet.Caps {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.peers[peer]
+}
+
+// Forget 移除对端节点的能力声明。
+// 应当在连接断开时调用。
+// @peer 对端节点
+func (cs *Caps) Forget(peer netip.AddrPort) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	delete(cs.peers, peer)
+}
+
+// Targets 选取转播的目标节点。
+// 先从全部节点中随机选取 config.ForwardRandom 个，使紧缺性感知不受能力声明的影响，
+// 其余按优先层级选取：能力匹配的节点、未声明能力的节点、其它节点，同层级内随机。
+// @peers 候选节点（通常为已连接节点，已排除询问来源）
+// @d     目标数据信息
+// @n     选取数量上限
+func (cs *Caps) Targets(peers []netip.AddrPort, d *packet.Data, n int) []netip.AddrPort {
+	list := make([]netip.AddrPort, len(peers))
+	copy(list, peers)
+
+	rand.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	if n >= len(list) {
+		return list
+	}
+	k := min(config.ForwardRandom, n)
+	out := list[:k:k]
+	rest := list[k:]
+
+	var tiers [3][]netip.AddrPort
+
+	cs.mu.Lock()
+	for _, ap := range rest {
+		t := cs.tier(ap, d)
+		tiers[t] = append(tiers[t], ap)
+	}
+	cs.mu.Unlock()
+
+	for _, t := range tiers {
+		for _, ap := range t {
+			if len(out) >= n {
+				return out
+			}
+			out = append(out, ap)
+		}
+	}
+	return out
+}
+
+// 获取节点对于目标数据的优先层级。
+// 调用者需持有锁。
+func (cs *Caps) tier(peer netip.AddrPort, d *packet.Data) int {
+	c := cs.peers[peer]
+
+	switch {
+	case c == nil:
+		return tierUnknown
+	case c.Match(d):
+		return tierMatch
+	}
+	return tierOther
+}
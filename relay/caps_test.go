@@ -0,0 +1,114 @@
+package relay_test
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+)
+
+// 创建n个节点地址，第i个为 10.0.0.i:7799。
+func testPeers(n int) []netip.AddrPort {
+	list := make([]netip.AddrPort, n)
+	for i := range list {
+		list[i] = netip.AddrPortFrom(netip.AddrFrom4([4]byte{10, 0, 0, byte(i + 1)}), 7799)
+	}
+	return list
+}
+
+func TestCapsUpdate(t *testing.T) {
+	cs := relay.NewCaps()
+	peer := testPeers(1)[0]
+
+	if !cs.Update(peer, &packet.Caps{Seq: 5}) {
+		t.Fatal("first update ignored")
+	}
+	// 乱序或重放
+	for _, seq := range []uint64{5, 4} {
+		if cs.Update(peer, &packet.Caps{Seq: seq, Kinds: 1}) {
+			t.Errorf("stale seq %d applied", seq)
+		}
+	}
+	if c := cs.Get(peer); c == nil || c.Seq != 5 || c.Kinds != 0 {
+		t.Errorf("caps: %+v", c)
+	}
+	if !cs.Update(peer, &packet.Caps{Seq: 6}) {
+		t.Error("newer update ignored")
+	}
+	cs.Forget(peer)
+	if cs.Get(peer) != nil {
+		t.Error("forgotten caps kept")
+	}
+}
+
+func TestTargets(t *testing.T) {
+	cs := relay.NewCaps()
+	peers := testPeers(20)
+	d := packet.NewData(packet.KIND_BLOCKCHAIN, []byte("btc:0001"), 0)
+
+	f := packet.NewFilter(packet.KIND_BLOCKCHAIN, 3, 10, 0.01)
+	f.Add([]byte("btc"))
+	match := packet.NewCaps([]packet.Kind{packet.KIND_BLOCKCHAIN}, f)
+	other := packet.NewCaps([]packet.Kind{packet.KIND_ARCHIVE})
+
+	// 前2个匹配，其后2个未声明，余者不匹配
+	tier := func(ap netip.AddrPort) int {
+		i := slices.Index(peers, ap)
+		switch {
+		case i < 2:
+			return 0
+		case i < 4:
+			return 1
+		}
+		return 2
+	}
+	for _, ap := range peers {
+		switch tier(ap) {
+		case 0:
+			cs.Update(ap, match)
+		case 2:
+			cs.Update(ap, other)
+		}
+	}
+	n := config.ForwardRandom + 4
+	randomOther := false
+
+	for range 50 {
+		out := cs.Targets(peers, d, n)
+		if len(out) != n {
+			t.Fatalf("targets: %d", len(out))
+		}
+		// 随机选取的节点之后，按层级填充
+		rest := out[config.ForwardRandom:]
+		for i := 1; i < len(rest); i++ {
+			if tier(rest[i]) < tier(rest[i-1]) {
+				t.Fatalf("tier order: %v", out)
+			}
+		}
+		// 匹配和未声明的节点（共4个）全部选中
+		for _, ap := range peers[:4] {
+			if !slices.Contains(out, ap) {
+				t.Fatalf("preferred peer %v missing: %v", ap, out)
+			}
+		}
+		for _, ap := range out[:config.ForwardRandom] {
+			if tier(ap) == 2 {
+				randomOther = true
+			}
+		}
+	}
+	// 随机选取不受能力声明的影响
+	if !randomOther {
+		t.Error("random picks always preferred")
+	}
+	// 数量不足时全部返回
+	if out := cs.Targets(peers[:3], d, 5); len(out) != 3 {
+		t.Errorf("short list: %d", len(out))
+	}
+	if out := cs.Targets(peers, d, 1); len(out) != 1 {
+		t.Errorf("one target: %d", len(out))
+	}
+}
@@ -0,0 +1,45 @@
+package relay
+
+import (
+	"fmt"
+
+	"github.com/cxio/depots/packet"
+)
+
+// Action 询问的处理方式
+type Action int
+
+// 三种处理方式
+const (
+	Drop    Action = iota // 忽略（跳数已达上限）
+	Forward               // 转播
+	Reply                 // 回复
+)
+
+func (a Action) String() string {
+	switch a {
+	case Drop:
+		return "drop"
+	case Forward:
+		return "forward"
+	case Reply:
+		return "reply"
+	}
+	return fmt.Sprintf("action(%d)", int(a))
+}
+
+// Decide 决定询问的处理方式。
+// 拥有目标数据，且询问者能与数据源连通时回复。
+// 否则视同没有数据而转播（懒原则），跳数已达上限时忽略。
+// @b      询问的基础信息（含询问者NAT层级）
+// @have   本地是否拥有目标数据
+// @source 数据源的NAT层级（驿站的数据服务可能在另一台主机上）
+func Decide(b *packet.Base, have bool, source packet.NatLevel) Action {
+	if have && packet.Reachable(b.Level, source) {
+		return Reply
+	}
+	if b.Hops >= packet.HopsMax {
+		return Drop
+	}
+	return Forward
+}
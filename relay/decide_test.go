@@ -0,0 +1,51 @@
+package relay_test
+
+import (
+	"testing"
+
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+)
+
+// NAT层级
+var levels = []packet.NatLevel{packet.NAT_LEVEL_NULL, packet.NAT_LEVEL_RC, packet.NAT_LEVEL_PRC, packet.NAT_LEVEL_SYM}
+
+func TestDecide(t *testing.T) {
+	// 按[询问者][数据源]，有数据时是否回复
+	reply := [4][4]bool{
+		{true, true, true, true},
+		{true, true, true, false},
+		{true, true, true, false},
+		{true, false, false, false},
+	}
+	for i, asker := range levels {
+		for j, source := range levels {
+			b := packet.NewBase(packet.Version, 1, 1, asker)
+
+			want := relay.Forward
+			if reply[i][j] {
+				want = relay.Reply
+			}
+			if got := relay.Decide(b, true, source); got != want {
+				t.Errorf("asker %d, source %d: %v", asker, source, got)
+			}
+			// 没有数据时转播
+			if got := relay.Decide(b, false, source); got != relay.Forward {
+				t.Errorf("asker %d, source %d, no data: %v", asker, source, got)
+			}
+			// 跳数已达上限：可连通时仍回复，否则忽略
+			b.Hops = packet.HopsMax
+			if reply[i][j] {
+				want = relay.Reply
+			} else {
+				want = relay.Drop
+			}
+			if got := relay.Decide(b, true, source); got != want {
+				t.Errorf("asker %d, source %d, max hops: %v", asker, source, got)
+			}
+			if got := relay.Decide(b, false, source); got != relay.Drop {
+				t.Errorf("asker %d, source %d, max hops, no data: %v", asker, source, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/repute"
+)
+
+var (
+	// ErrUnknown 没有对应的待决询问
+	ErrUnknown = errors.New("no pending quest for the id")
+
+	// ErrCancel 撤销签名验证失败
+	ErrCancel = errors.New("cancel signature verification failed")
+)
+
+// Sender 回复回传函数。
+// 向询问的来源节点发送回复数据。
+// 来源为零值地址时，表示询问由本节点自己发出。
+type Sender func(to netip.AddrPort, data []byte)
+
+// 候选回复
+type candidate struct {
+	from netip.AddrPort // 转回回复的下级节点
+	data []byte         // 回复包数据
+}
+
+// 待决询问
+type pending struct {
+	from    netip.AddrPort   // 询问来源（回复回传目标）
+	algor   packet.DHTag     // 询问公钥算法
+	pubkey  []byte           // 询问公钥（撤销验证用）
+	to      []netip.AddrPort // 已转播的目标节点
+	created time.Time        // 创建时间
+	pool    []candidate      // 候选回复
+	wait    *time.Timer      // 第二个回复起的计时器
+	total   *time.Timer      // 总超时计时器
+	done    bool             // 已回传或已撤销
+	cancel  bool             // 已撤销
+	slots   map[int]bool     // 批量：转播的序位集（true为已回传）
+	found   []*packet.Found  // 批量：候选回复条目
+	ver     int              // 批量：回复版本（首个接收的回复，-1为尚无）
+}
+
+// 是否为批量询问。
+func (p *pending) batch() bool {
+	return p.slots != nil
+}
+
+// 停止计时器。
+func (p *pending) stop() {
+	if p.wait != nil {
+		p.wait.Stop()
+	}
+	if p.total != nil {
+		p.total.Stop()
+	}
+}
+
+// Pending 待决询问集。
+// 记录转播出去的询问，汇集下级节点的回复，按策略选取一个向上回传：
+// 1. 候选池满额（3个）时，随机选取一个即时回传。
+// 2. 从第二个回复起计时，到时随机选取一个回传。
+// 3. 总超时到达时，即便只有一个回复也回传。
+// 回传之后，同一询问的后续回复被丢弃。
+// 设置了信誉簿时，随机选取按下级节点的信誉加权，并记录所选回复的来源。
+//
+// 批量询问按序位汇集：各下级回复的条目逐一合并，同一序位随机保留一个（MergeFound）。
+// 回传之后，填充了尚未回传序位的回复仍被接收，全部序位回传后才结束。
+type Pending struct {
+	mu    sync.Mutex
+	items map[uint64]*pending
+	send  Sender
+	book  *repute.Book
+}
+
+// NewPending 创建待决询问集。
+// @send 回复回传函数
+func NewPending(send Sender) *Pending {
+	return &Pending{
+		items: make(map[uint64]*pending),
+		send:  send,
+	}
+}
+
+// Weigh 设置节点信誉簿。
+// @r 信誉簿
+func (ps *Pending) Weigh(r *repute.Book) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.book = r
+}
+
+// Add 添加一个待决询问。
+// 应当在询问包转播之后调用，重复的询问ID会被忽略。
+// @id     询问ID
+// @from   询问来源
+// @algor  询问公钥算法
+// @pubkey 询问公钥
+// @to     已转播的目标节点
+// @return 是否为新添加
+func (ps *Pending) Add(id uint64, from netip.AddrPort, algor packet.DHTag, pubkey []byte, to []netip.AddrPort) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.items[id]; ok {
+		return false
+	}
+	p := &pending{
+		from:    from,
+		algor:   algor,
+		pubkey:  bytes.Clone(pubkey),
+		to:      to,
+		created: time.Now(),
+	}
+	p.total = time.AfterFunc(config.ReplyTimeout, func() { ps.flush(id) })
+	ps.items[id] = p
+
+	return true
+}
+
+// AddBatch 添加一个待决的批量询问。
+// 应当在批量询问包转播之后调用，重复的询问ID会被忽略。
+// 回复中不属于转播条目的序位会被忽略。
+// @id     询问ID
+// @from   询问来源
+// @algor  询问公钥算法
+// @pubkey 询问公钥
+// @to     已转播的目标节点
+// @its    已转播的条目（通常为 SplitBatch 的转播部分）
+// @return 是否为新添加
+func (ps *Pending) AddBatch(id uint64, from netip.AddrPort, algor packet.DHTag, pubkey []byte, to []netip.AddrPort, its []*packet.Item) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.items[id]; ok {
+		return false
+	}
+	p := &pending{
+		from:    from,
+		algor:   algor,
+		pubkey:  bytes.Clone(pubkey),
+		to:      to,
+		created: time.Now(),
+		slots:   make(map[int]bool, len(its)),
+		ver:     -1,
+	}
+	for _, it := range its {
+		p.slots[it.Slot] = false
+	}
+	p.total = time.AfterFunc(config.ReplyTimeout, func() { ps.flush(id) })
+	ps.items[id] = p
+
+	return true
+}
+
+// Reply 收到一个下级回复。
+// 如果询问未知、已回传或已撤销，回复被丢弃。
+// 批量询问的回复为批量回复包，只要含有尚未回传的序位即被接收。
+// @id   询问ID
+// @from 转回回复的下级节点
+// @data 回复包数据（单个回复原样回传）
+// @return 是否被接收为候选
+func (ps *Pending) Reply(id uint64, from netip.AddrPort, data []byte) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.items[id]
+	if !ok || p.done {
+		return false
+	}
+	if p.batch() {
+		return ps.collect(id, p, data)
+	}
+	p.pool = append(p.pool, candidate{from: from, data: data})
+
+	switch len(p.pool) {
+	case 2:
+		p.wait = time.AfterFunc(config.ReplyWait, func() { ps.flush(id) })
+	case config.ReplyPool:
+		ps.reply(id, p)
+	}
+	return true
+}
+
+// Cancel 撤销一个待决询问。
+// 签名以询问公钥验证，只接受询问者本人的撤销。
+// 撤销后停止回复汇集，清空候选，后续回复不再回传。
+// 返回的节点集用于继续转播撤销包，重复的撤销返回空集。
+// @b   撤销包基础信息
+// @sig 撤销签名
+// @return 需要继续转播的目标节点
+func (ps *Pending) Cancel(b *packet.Base, sig []byte) ([]netip.AddrPort, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.items[b.ID]
+	if !ok {
+		return nil, ErrUnknown
+	}
+	if !packet.VerifyCancel(b, p.algor, p.pubkey, sig) {
+		return nil, ErrCancel
+	}
+	if p.cancel {
+		return nil, nil
+	}
+	p.stop()
+	p.pool = nil
+	p.found = nil
+	p.done = true
+	p.cancel = true
+
+	return p.to, nil
+}
+
+// Clean 清理过期的待决询问。
+// 应当由外部定时调用。
+func (ps *Pending) Clean() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	now := time.Now()
+
+	for id, p := range ps.items {
+		if now.Sub(p.created) >= config.PendingExpired {
+			p.stop()
+			delete(ps.items, id)
+		}
+	}
+}
+
+// Len 返回待决询问数。
+func (ps *Pending) Len() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.items)
+}
+
+// 计时器到期回传。
+func (ps *Pending) flush(id uint64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.items[id]
+	if !ok || p.done {
+		return
+	}
+	if p.batch() {
+		if len(p.found) > 0 {
+			ps.merge(id, p)
+		}
+		return
+	}
+	if len(p.pool) > 0 {
+		ps.reply(id, p)
+	}
+}
+
+// 汇集批量回复的条目。
+// 只接收尚未回传的序位，版本须与首个接收的回复相同。
+// 从首个接收的条目起计时，到时合并回传；各序位的候选都满额时即时回传。
+// 调用者需持有锁。
+func (ps *Pending) collect(id uint64, p *pending, data []byte) bool {
+	b, fs, err := packet.DecodeBatchReply(data)
+	if err != nil || b.ID != id {
+		return false
+	}
+	if p.ver >= 0 && b.Ver != p.ver {
+		return false
+	}
+	got := make(map[int]bool)
+
+	for _, f := range fs {
+		// 同一回复中的重复序位只计一次
+		if sent, ok := p.slots[f.Slot]; !ok || sent || got[f.Slot] {
+			continue
+		}
+		got[f.Slot] = true
+		p.found = append(p.found, f)
+	}
+	if len(got) == 0 {
+		return false
+	}
+	p.ver = b.Ver
+
+	if p.full() {
+		ps.merge(id, p)
+		return true
+	}
+	if p.wait == nil {
+		p.wait = time.AfterFunc(config.ReplyWait, func() { ps.flush(id) })
+	}
+	return true
+}
+
+// 尚未回传的序位是否都已有满额的候选。
+// 调用者需持有锁。
+func (p *pending) full() bool {
+	count := make(map[int]int)
+
+	for _, f := range p.found {
+		count[f.Slot]++
+	}
+	for slot, sent := range p.slots {
+		if !sent && count[slot] < config.ReplyPool {
+			return false
+		}
+	}
+	return true
+}
+
+// 合并批量条目回传。
+// 每个序位随机保留一个，按上限分批编码为批量回复包。
+// 全部序位回传之后，询问结束。
+// 调用者需持有锁。
+func (ps *Pending) merge(id uint64, p *pending) {
+	if p.wait != nil {
+		p.wait.Stop()
+		p.wait = nil
+	}
+	b := &packet.Base{Ver: p.ver, ID: id}
+
+	for _, fs := range MergeFound(p.found) {
+		data, err := packet.EncodeBatchReply(b, fs)
+		if err != nil {
+			continue
+		}
+		for _, f := range fs {
+			p.slots[f.Slot] = true
+		}
+		go ps.send(p.from, data)
+	}
+	p.found = nil
+
+	for _, sent := range p.slots {
+		if !sent {
+			return
+		}
+	}
+	p.stop()
+	p.done = true
+}
+
+// 随机选取一个候选回传。
+// 设置了信誉簿时按信誉加权选取，并记录所选回复的来源以待核实。
+// 调用者需持有锁。
+func (ps *Pending) reply(id uint64, p *pending) {
+	c := ps.pick(p.pool)
+	p.stop()
+	p.pool = nil
+	p.done = true
+
+	if ps.book != nil {
+		ps.book.Link(id, c.from, p.algor, p.pubkey)
+	}
+	go ps.send(p.from, c.data)
+}
+
+// 加权随机选取一个候选。
+// 调用者需持有锁。
+func (ps *Pending) pick(pool []candidate) candidate {
+	if ps.book == nil {
+		return pool[rand.IntN(len(pool))]
+	}
+	ws := make([]float64, len(pool))
+	var sum float64
+
+	for i, c := range pool {
+		ws[i] = ps.book.Weight(c.from)
+		sum += ws[i]
+	}
+	x := rand.Float64() * sum
+
+	for i, w := range ws {
+		if x < w {
+			return pool[i]
+		}
+		x -= w
+	}
+	return pool[len(pool)-1]
+}
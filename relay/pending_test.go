@@ -0,0 +1,65 @@
+package relay_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+)
+
+func TestPendingCancel(t *testing.T) {
+	const id = 88
+	ps := relay.NewPending(func(netip.AddrPort, []byte) {})
+	from := netip.MustParseAddrPort("10.0.0.1:7790")
+	to := []netip.AddrPort{netip.MustParseAddrPort("10.0.0.2:7790"), netip.MustParseAddrPort("10.0.0.3:7790")}
+
+	key, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dh := msg.NewDHPack(msg.DH_X25519, key)
+	ps.Add(id, from, msg.DH_X25519, dh.PublicBytes(), to)
+
+	b := &packet.Base{Ver: packet.Version, ID: id}
+	sig, err := dh.Sign(packet.CancelMessage(b.Ver, b.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 他人的签名或篡改的签名，询问保持待决
+	other, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := msg.NewDHPack(msg.DH_X25519, other).Sign(packet.CancelMessage(b.Ver, b.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := append([]byte(nil), sig...)
+	bad[0] ^= 1
+
+	for _, s := range [][]byte{forged, bad, nil} {
+		if _, err = ps.Cancel(b, s); !errors.Is(err, relay.ErrCancel) {
+			t.Errorf("bad cancel: %v", err)
+		}
+	}
+	if ps.Len() != 1 || !ps.Reply(id, to[0], []byte("reply")) {
+		t.Fatal("quest dropped by a bad cancel")
+	}
+	if _, err = ps.Cancel(&packet.Base{Ver: b.Ver, ID: id + 1}, sig); !errors.Is(err, relay.ErrUnknown) {
+		t.Errorf("unknown quest: %v", err)
+	}
+	// 有效的撤销，继续转播给原目标
+	next, err := ps.Cancel(b, sig)
+	if err != nil || len(next) != len(to) {
+		t.Fatalf("cancel: %v, %v", next, err)
+	}
+	if ps.Reply(id, to[1], []byte("reply")) {
+		t.Error("reply accepted after cancel")
+	}
+	if next, err = ps.Cancel(b, sig); err != nil || next != nil {
+		t.Errorf("repeated cancel: %v, %v", next, err)
+	}
+}
@@ -0,0 +1,135 @@
+package relay
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/trust"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrExpired 探测包时间戳超出接受窗口
+	ErrExpired = errors.New("probe stamp out of the window")
+
+	// ErrReplay 探测包为重放
+	ErrReplay = errors.New("probe replayed")
+
+	// ErrReplayFull 重放缓存已满，且各签名者仍有未过期的随机数
+	ErrReplayFull = errors.New("probe replay cache is full")
+
+	// ErrUntrusted 签名者不在公认清单中，不登记随机数
+	ErrUntrusted = errors.New("probe signer is not trusted")
+)
+
+// 签名者的随机数缓存
+type nonces struct {
+	key  string    // 签名者索引（算法+公钥）
+	seen *Seen     // 已见随机数
+	last time.Time // 最近活动时间
+}
+
+// Replay 签名探测包的重放缓存。
+// 只为公认签名者（trust.Registry）按算法和公钥分别记录窗口内已见的随机数，
+// 其他签名者的探测包只按未签名计算权重（trust.Registry.Rate），无需登记。
+// 签名者数量有上限，超出时仅淘汰随机数已全部过期的签名者（最久未活动者），否则拒绝新的签名者。
+// 每个签名者的随机数缓存满时拒绝新的随机数而非淘汰旧的，被淘汰的随机数在窗口内即可重放。
+type Replay struct {
+	mu      sync.Mutex
+	window  time.Duration
+	trust   *trust.Registry
+	queue   *list.List               // 按活动时间排序，前端最久
+	signers map[string]*list.Element // 签名者索引
+}
+
+// NewReplay 创建重放缓存。
+// 时间窗口和容量采用系统默认配置。
+// @r 公认签名者登记簿
+func NewReplay(r *trust.Registry) *Replay {
+	return &Replay{
+		window:  config.ProbeWindow,
+		trust:   r,
+		queue:   list.New(),
+		signers: make(map[string]*list.Element),
+	}
+}
+
+// Check 检查签名探测包是否可接受。
+// 时间戳须在当前时间前后的窗口内，且同一签名者的随机数未曾出现。
+// 通过检查的随机数会被登记。
+// 签名者不被信任时返回 ErrUntrusted，缓存已满无法登记时返回 ErrReplayFull，
+// 调用者可将这两种情况视同未签名的探测包。
+// @algor 签名算法
+// @pub   签名公钥
+// @st    签名时间戳信息
+func (r *Replay) Check(algor msg.SignTag, pub []byte, st *packet.Stamp) error {
+	d := time.Since(st.Time)
+	if d > r.window || d < -r.window {
+		return ErrExpired
+	}
+	if r.trust == nil || r.trust.Lookup(algor, pub) == nil {
+		return ErrUntrusted
+	}
+	// 时间戳参与摘要，避免随机数的跨窗口碰撞
+	sum := sha3.Sum256(st.Bytes())
+	id := binary.BigEndian.Uint64(sum[:8])
+
+	seen := r.nonces(append([]byte{byte(algor)}, pub...))
+	if seen == nil {
+		return ErrReplayFull
+	}
+	first, full := seen.Hold(id)
+	if full {
+		return ErrReplayFull
+	}
+	if !first {
+		return ErrReplay
+	}
+	return nil
+}
+
+// Len 返回缓存的签名者数量。
+func (r *Replay) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queue.Len()
+}
+
+// 获取签名者的随机数缓存。
+// 不存在时创建，必要时淘汰最久未活动的签名者。
+// 最久未活动者仍有未过期的随机数时不淘汰，返回nil。
+func (r *Replay) nonces(k []byte) *Seen {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := string(k)
+	now := time.Now()
+
+	if e, ok := r.signers[key]; ok {
+		its := e.Value.(*nonces)
+		its.last = now
+		r.queue.MoveToBack(e)
+		return its.seen
+	}
+	// 窗口为前后两段，随机数需保留两倍时长
+	ttl := r.window * 2
+
+	if r.queue.Len() >= config.ReplaySigners {
+		e := r.queue.Front()
+		if now.Sub(e.Value.(*nonces).last) < ttl {
+			return nil
+		}
+		delete(r.signers, e.Value.(*nonces).key)
+		r.queue.Remove(e)
+	}
+	its := &nonces{key: key, seen: NewSeen(ttl, config.ReplayNonces), last: now}
+	r.signers[key] = r.queue.PushBack(its)
+
+	return its.seen
+}
@@ -0,0 +1,85 @@
+package relay_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+	"github.com/cxio/depots/trust"
+)
+
+// 测试用的签名时间，各时间戳相同，同一序号的摘要才一致。
+var testTime = time.Now().Truncate(time.Second)
+
+// 以序号创建一个签名时间戳。
+func testStamp(n int) *packet.Stamp {
+	nonce := make([]byte, packet.NonceSize)
+	binary.BigEndian.PutUint64(nonce, uint64(n))
+
+	return &packet.Stamp{Time: testTime, Nonce: nonce}
+}
+
+func TestReplayUntrusted(t *testing.T) {
+	pub := bytes.Repeat([]byte{1}, 32)
+	reg := trust.New(nil)
+	reg.Add(&trust.Signer{Algor: msg.SIGN_ED25519, Pubkey: pub})
+	r := relay.NewReplay(reg)
+
+	if err := r.Check(msg.SIGN_ED25519, bytes.Repeat([]byte{2}, 32), testStamp(0)); !errors.Is(err, relay.ErrUntrusted) {
+		t.Errorf("unknown key: %v", err)
+	}
+	if err := r.Check(msg.SIGN_Tradi, pub, testStamp(0)); !errors.Is(err, relay.ErrUntrusted) {
+		t.Errorf("other algor: %v", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("untrusted signers cached: %d", r.Len())
+	}
+	if err := r.Check(msg.SIGN_ED25519, pub, testStamp(0)); err != nil {
+		t.Errorf("trusted: %v", err)
+	}
+	if err := relay.NewReplay(nil).Check(msg.SIGN_ED25519, pub, testStamp(0)); !errors.Is(err, relay.ErrUntrusted) {
+		t.Errorf("no registry: %v", err)
+	}
+}
+
+func TestReplayNoncesFull(t *testing.T) {
+	pub := bytes.Repeat([]byte{1}, 32)
+	reg := trust.New(nil)
+	reg.Add(&trust.Signer{Algor: msg.SIGN_ED25519, Pubkey: pub})
+	r := relay.NewReplay(reg)
+
+	for i := range config.ReplayNonces {
+		if err := r.Check(msg.SIGN_ED25519, pub, testStamp(i)); err != nil {
+			t.Fatalf("nonce %d: %v", i, err)
+		}
+	}
+	if err := r.Check(msg.SIGN_ED25519, pub, testStamp(config.ReplayNonces)); !errors.Is(err, relay.ErrReplayFull) {
+		t.Errorf("nonce over the limit: %v", err)
+	}
+	// 最早的随机数未被淘汰，仍可识别重放
+	if err := r.Check(msg.SIGN_ED25519, pub, testStamp(0)); !errors.Is(err, relay.ErrReplay) {
+		t.Errorf("first nonce replayed: %v", err)
+	}
+}
+
+func TestSeenHold(t *testing.T) {
+	s := relay.NewSeen(time.Minute, 2)
+
+	for id := range uint64(2) {
+		if first, full := s.Hold(id); !first || full {
+			t.Fatalf("hold %d: %v, %v", id, first, full)
+		}
+	}
+	if first, full := s.Hold(9); !first || !full {
+		t.Errorf("hold over capacity: %v, %v", first, full)
+	}
+	if first, _ := s.Hold(0); first {
+		t.Error("held entry evicted")
+	}
+}
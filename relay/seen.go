@@ -0,0 +1,196 @@
+// Package relay 实现询问和探测包的转播逻辑。
+// 包括消息去重、回复路径记录等。
+package relay
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+// 已见条目
+type seenItem struct {
+	id    uint64    // 消息标识
+	first time.Time // 首次到达时间
+	count int       // 到达次数（含首次）
+}
+
+// Seen 已见消息集。
+// 一个有时限、有容量上限的去重集合，按首次到达时间顺序淘汰：
+// - 超过时间窗口的条目自动移除。
+// - 容量满时移除最早的条目。
+// 重复到达的次数会被记录，可作为紧缺性感知的一个辅助信号：
+// 同一消息经由越多路径到达，说明其广播扩散越广。
+type Seen struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	max   int
+	queue *list.List               // 按首次到达排序，前端最早
+	items map[uint64]*list.Element // 标识索引
+	dups  uint64                   // 重复累计（统计）
+}
+
+// NewSeen 创建一个已见消息集。
+// @ttl 时间窗口
+// @max 容量上限，不足1时按1计
+func NewSeen(ttl time.Duration, max int) *Seen {
+	if max < 1 {
+		max = 1
+	}
+	return &Seen{
+		ttl:   ttl,
+		max:   max,
+		queue: list.New(),
+		items: make(map[uint64]*list.Element),
+	}
+}
+
+// Check 检查并登记消息。
+// 首次到达时返回真，重复时返回假。
+// @id 消息标识
+// @return1 是否首次到达
+// @return2 到达次数（含本次）
+func (s *Seen) Check(id uint64) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	if e, ok := s.items[id]; ok {
+		its := e.Value.(*seenItem)
+		its.count++
+		s.dups++
+		return false, its.count
+	}
+	if s.queue.Len() >= s.max {
+		s.remove(s.queue.Front())
+	}
+	s.items[id] = s.queue.PushBack(&seenItem{id: id, first: now, count: 1})
+
+	return true, 1
+}
+
+// Hold 检查并登记消息，容量满时不淘汰已有条目。
+// 用于不能遗忘的场合（如重放缓存）：被淘汰的条目再次到达时会被当作首次。
+// 条目只在超出时间窗口后移除，容量满时新的消息不被登记。
+// @id 消息标识
+// @return1 是否首次到达
+// @return2 是否因容量已满而未登记
+func (s *Seen) Hold(id uint64) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	if e, ok := s.items[id]; ok {
+		e.Value.(*seenItem).count++
+		s.dups++
+		return false, false
+	}
+	if s.queue.Len() >= s.max {
+		return true, true
+	}
+	s.items[id] = s.queue.PushBack(&seenItem{id: id, first: now, count: 1})
+
+	return true, false
+}
+
+// Count 获取消息的到达次数。
+// 不存在或已过期的消息返回0。
+func (s *Seen) Count(id uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	if e, ok := s.items[id]; ok {
+		return e.Value.(*seenItem).count
+	}
+	return 0
+}
+
+// Dups 返回累计的重复次数。
+func (s *Seen) Dups() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dups
+}
+
+// Len 返回当前条目数。
+func (s *Seen) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len()
+}
+
+// 移除过期条目。
+// 队列按首次到达排序，从前端逐一检查即可。
+func (s *Seen) expire(now time.Time) {
+	for e := s.queue.Front(); e != nil; e = s.queue.Front() {
+		if now.Sub(e.Value.(*seenItem).first) < s.ttl {
+			break
+		}
+		s.remove(e)
+	}
+}
+
+// 移除一个条目。
+func (s *Seen) remove(e *list.Element) {
+	delete(s.items, e.Value.(*seenItem).id)
+	s.queue.Remove(e)
+}
+
+// Filter 消息过滤器。
+// 分别管理询问ID和探测摘要两个去重集。
+type Filter struct {
+	quests *Seen
+	probes *Seen
+}
+
+// NewFilter 创建消息过滤器。
+// 时间窗口和容量采用系统默认配置。
+func NewFilter() *Filter {
+	return &Filter{
+		quests: NewSeen(config.SeenQuestExpired, config.SeenCapacity),
+		probes: NewSeen(config.SeenProbeExpired, config.SeenCapacity),
+	}
+}
+
+// Quest 检查询问包是否首次到达。
+// 重复的询问包应当丢弃，不再转播，也不覆盖已有的回复路径。
+// @b 询问包基础信息
+// @return1 是否首次到达
+// @return2 到达次数
+func (f *Filter) Quest(b *packet.Base) (bool, int) {
+	return f.quests.Check(b.ID)
+}
+
+// Probe 检查探测包是否首次到达。
+// 探测包无ID，以内容派生的摘要为标识。
+// @d 探测的数据信息
+// @return1 是否首次到达
+// @return2 到达次数
+func (f *Filter) Probe(d *packet.Data) (bool, int) {
+	return f.probes.Check(packet.ProbeID(d))
+}
+
+// ProbeCount 获取目标数据的探测到达次数。
+// 可作为紧缺性评估的参考。
+func (f *Filter) ProbeCount(d *packet.Data) int {
+	return f.probes.Count(packet.ProbeID(d))
+}
+
+// QuestCount 获取询问的到达次数。
+func (f *Filter) QuestCount(id uint64) int {
+	return f.quests.Count(id)
+}
+
+// Dups 返回询问和探测的累计重复次数。
+func (f *Filter) Dups() (quests, probes uint64) {
+	return f.quests.Dups(), f.probes.Dups()
+}
@@ -0,0 +1,108 @@
+package relay_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/relay"
+)
+
+func TestSeenCheck(t *testing.T) {
+	s := relay.NewSeen(time.Minute, 8)
+
+	if first, n := s.Check(1); !first || n != 1 {
+		t.Fatalf("first arrival: %v, %d", first, n)
+	}
+	for i := 2; i <= 3; i++ {
+		if first, n := s.Check(1); first || n != i {
+			t.Errorf("duplicate %d: %v, %d", i, first, n)
+		}
+	}
+	if s.Count(1) != 3 || s.Dups() != 2 || s.Len() != 1 {
+		t.Errorf("count %d, dups %d, len %d", s.Count(1), s.Dups(), s.Len())
+	}
+	if s.Count(2) != 0 {
+		t.Error("unseen id counted")
+	}
+}
+
+func TestSeenCapacity(t *testing.T) {
+	s := relay.NewSeen(time.Minute, 3)
+
+	for id := range uint64(4) {
+		s.Check(id)
+	}
+	// 最早的条目被淘汰
+	if s.Len() != 3 || s.Count(0) != 0 || s.Count(1) != 1 {
+		t.Fatalf("eviction: len %d", s.Len())
+	}
+	if first, _ := s.Check(0); !first {
+		t.Error("evicted id still seen")
+	}
+	// 容量不足1时按1计
+	for _, max := range []int{0, -1} {
+		s = relay.NewSeen(time.Minute, max)
+		s.Check(1)
+		s.Check(2)
+
+		if s.Len() != 1 || s.Count(1) != 0 || s.Count(2) != 1 {
+			t.Errorf("capacity %d: len %d", max, s.Len())
+		}
+		if first, _ := s.Check(2); first {
+			t.Errorf("capacity %d: duplicate accepted", max)
+		}
+	}
+}
+
+func TestSeenExpire(t *testing.T) {
+	s := relay.NewSeen(50*time.Millisecond, 8)
+	s.Check(1)
+	s.Check(1)
+
+	time.Sleep(60 * time.Millisecond)
+
+	if s.Count(1) != 0 || s.Len() != 0 {
+		t.Fatal("expired id kept")
+	}
+	if first, n := s.Check(1); !first || n != 1 {
+		t.Errorf("after expiry: %v, %d", first, n)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := relay.NewFilter()
+	b := &packet.Base{ID: 7}
+
+	if first, _ := f.Quest(b); !first {
+		t.Fatal("first quest rejected")
+	}
+	if first, n := f.Quest(b); first || n != 2 {
+		t.Errorf("duplicate quest: %v, %d", first, n)
+	}
+	// 探测以类别和索引为标识，与大小无关
+	d := packet.NewData(packet.Kind(1), []byte("index"), 100)
+
+	if first, _ := f.Probe(d); !first {
+		t.Fatal("first probe rejected")
+	}
+	if first, _ := f.Probe(packet.NewData(d.Kind, d.Index, 200)); first {
+		t.Error("probe with another size accepted")
+	}
+	if first, _ := f.Probe(packet.NewData(d.Kind, []byte("other"), 100)); !first {
+		t.Error("probe for other data rejected")
+	}
+	if first, _ := f.Probe(packet.NewData(d.Kind+1, d.Index, 100)); !first {
+		t.Error("probe for another kind rejected")
+	}
+	if f.ProbeCount(d) != 2 || f.QuestCount(7) != 2 {
+		t.Errorf("counts: %d, %d", f.ProbeCount(d), f.QuestCount(7))
+	}
+	if q, p := f.Dups(); q != 1 || p != 1 {
+		t.Errorf("dups: %d, %d", q, p)
+	}
+	// 询问ID与探测标识分开记录
+	if f.QuestCount(packet.ProbeID(d)) != 0 {
+		t.Error("probe counted as quest")
+	}
+}
@@ -0,0 +1,201 @@
+// Package repute 依回复质量评定的节点信誉。
+// 中转节点若回传了并不提供数据的连系信息，即构成“分布式数据阻塞”攻击。
+// 为此，本节点将经由某个对端节点转回的回复与询问者随后的核实结果关联：
+// 结果由客户端库以核实报告包报告（client.Keys.Report），或来自本节点补充存储时的获取。
+// 核实有效的节点加分，无效的减分，信誉分随时间衰减，
+// 用于回复汇集时的选取权重（relay.Pending）和巡查时的节点替换（peers.Manager）。
+// 核实结果仅作为信誉的依据，不据以惩罚或禁闭：数据源暂时离线等情况同样表现为无效。
+package repute
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/packet"
+)
+
+var (
+	// ErrUnknown 询问未记录回复来源或已过期
+	ErrUnknown = errors.New("unknown or expired reply link")
+
+	// ErrReport 报告签名验证失败
+	ErrReport = errors.New("report signature verification failed")
+)
+
+// 信誉分
+type score struct {
+	value float64   // 最近一次更新时的分值
+	at    time.Time // 最近一次更新时间
+}
+
+// 当前分值（已衰减）。
+func (s *score) now(now time.Time) float64 {
+	return s.value * math.Exp2(-float64(now.Sub(s.at))/float64(config.ReputeHalfLife))
+}
+
+// 回复来源记录
+type link struct {
+	peer   netip.AddrPort // 转回回复的对端节点
+	algor  packet.DHTag   // 询问公钥算法
+	pubkey []byte         // 询问公钥，验证核实报告
+	at     time.Time      // 记录时间
+}
+
+// Book 节点信誉簿。
+// 并发安全。
+type Book struct {
+	mu     sync.Mutex
+	scores map[netip.AddrPort]*score
+	links  map[uint64]*link
+}
+
+// New 创建一个空的信誉簿。
+func New() *Book {
+	return &Book{
+		scores: make(map[netip.AddrPort]*score),
+		links:  make(map[uint64]*link),
+	}
+}
+
+// Link 记录一个询问的回复来源。
+// 在回复被选取回传时调用，同一询问的记录会被覆盖。
+// 询问公钥用于验证询问者的核实报告（Confirm）。
+// @id     询问ID
+// @peer   转回该回复的对端节点
+// @algor  询问公钥算法
+// @pubkey 询问公钥
+func (r *Book) Link(id uint64, peer netip.AddrPort, algor packet.DHTag, pubkey []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.links[id] = &link{peer: peer, algor: algor, pubkey: bytes.Clone(pubkey), at: time.Now()}
+}
+
+// Report 报告一个询问的核实结果。
+// 供本节点补充存储时的获取使用，来自客户端的报告经由 Confirm 验证。
+// 数据源提供了所需的数据为有效，否则为无效。
+// 未记录或已过期的询问被忽略，每个询问只计一次。
+// @id 询问ID
+// @ok 是否有效
+// @return 是否有对应的回复来源
+func (r *Book) Report(id uint64, ok bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.link(id, time.Now())
+	if l == nil {
+		return false
+	}
+	r.settle(id, l, ok)
+	return true
+}
+
+// Confirm 处理一个核实报告包。
+// 签名以记录的询问公钥验证，只接受询问者本人的报告。
+// 返回的节点为转回该回复的下级节点，核实报告包应继续转发给它
+// （协商版本不低于 packet.VersionReport 时），使回传路径上的各中转节点都能评定其下级。
+// @b   报告包基础信息
+// @ok  报告的核实结果
+// @sig 报告签名
+// @return 继续转发的目标节点
+func (r *Book) Confirm(b *packet.Base, ok bool, sig []byte) (netip.AddrPort, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.link(b.ID, time.Now())
+	if l == nil {
+		return netip.AddrPort{}, ErrUnknown
+	}
+	if !packet.VerifyReport(b, ok, l.algor, l.pubkey, sig) {
+		return netip.AddrPort{}, ErrReport
+	}
+	r.settle(b.ID, l, ok)
+	return l.peer, nil
+}
+
+// Score 返回节点当前的信誉分（已衰减）。
+// 未知节点为0。
+// @peer 节点地址
+func (r *Book) Score(peer netip.AddrPort) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.scores[peer]; s != nil {
+		return s.now(time.Now())
+	}
+	return 0
+}
+
+// Weight 返回节点回复的选取权重。
+// 未知节点为1，信誉分每增减一半上限，权重加倍或减半（0.25 ~ 4）。
+// @peer 节点地址
+func (r *Book) Weight(peer netip.AddrPort) float64 {
+	return math.Exp2(r.Score(peer) / (config.ReputeMax / 2))
+}
+
+// Clean 清理过期的回复来源记录和衰减殆尽的信誉分。
+// 应当由外部定时调用。
+func (r *Book) Clean() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	for id, l := range r.links {
+		if now.Sub(l.at) >= config.ReputeLinkExpired {
+			delete(r.links, id)
+		}
+	}
+	for peer, s := range r.scores {
+		if math.Abs(s.now(now)) < 0.01 {
+			delete(r.scores, peer)
+		}
+	}
+}
+
+//
+// 私有辅助
+//////////////////////////////////////////////////////////////////////////////
+
+// 获取有效的回复来源记录。
+// 已过期的记录被移除，返回nil。
+// 调用者需持有锁。
+func (r *Book) link(id uint64, now time.Time) *link {
+	l := r.links[id]
+
+	if l != nil && now.Sub(l.at) >= config.ReputeLinkExpired {
+		delete(r.links, id)
+		return nil
+	}
+	return l
+}
+
+// 依核实结果评定回复来源，移除记录。
+// 调用者需持有锁。
+func (r *Book) settle(id uint64, l *link, ok bool) {
+	delete(r.links, id)
+
+	delta := config.ReputeGood
+	if !ok {
+		delta = config.ReputeBad
+	}
+	r.add(l.peer, delta, time.Now())
+}
+
+// 累计信誉分，限制在上限内。
+// 调用者需持有锁。
+func (r *Book) add(peer netip.AddrPort, delta float64, now time.Time) {
+	s := r.scores[peer]
+	if s == nil {
+		s = &score{}
+		r.scores[peer] = s
+	}
+	v := s.now(now) + delta
+	s.value = max(-config.ReputeMax, min(config.ReputeMax, v))
+	s.at = now
+}
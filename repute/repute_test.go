@@ -0,0 +1,105 @@
+package repute_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/repute"
+)
+
+var peer = netip.MustParseAddrPort("10.0.0.1:7788")
+
+// 创建一个随机密钥的密钥交换包。
+func newDH(t *testing.T) *msg.DHPack {
+	t.Helper()
+
+	key, err := msg.GenerateKey(msg.DH_X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg.NewDHPack(msg.DH_X25519, key)
+}
+
+// 编码并解码一个核实报告包。
+func report(t *testing.T, id uint64, ok bool, dh *msg.DHPack) (*packet.Base, bool, []byte) {
+	t.Helper()
+
+	data, err := packet.EncodeReport(&packet.Base{Ver: packet.VersionBind, ID: id}, ok, dh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok, sig, err := packet.DecodeReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, ok, sig
+}
+
+func TestReport(t *testing.T) {
+	r := repute.New()
+	dh := newDH(t)
+
+	if r.Report(1, true) {
+		t.Error("unlinked quest reported")
+	}
+	r.Link(1, peer, msg.DH_X25519, dh.PublicBytes())
+
+	if !r.Report(1, false) {
+		t.Fatal("linked quest not reported")
+	}
+	if r.Report(1, false) {
+		t.Error("quest reported twice")
+	}
+	if s := r.Score(peer); s > config.ReputeBad+0.01 || s < config.ReputeBad-0.01 {
+		t.Errorf("score: %f", s)
+	}
+	if r.Weight(peer) >= 1 {
+		t.Errorf("weight: %f", r.Weight(peer))
+	}
+	// 分值限制在上限内
+	for i := range 20 {
+		id := uint64(100 + i)
+		r.Link(id, peer, msg.DH_X25519, dh.PublicBytes())
+		r.Report(id, true)
+	}
+	if s := r.Score(peer); s > config.ReputeMax {
+		t.Errorf("score beyond the cap: %f", s)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	r := repute.New()
+	dh := newDH(t)
+	r.Link(7, peer, msg.DH_X25519, dh.PublicBytes())
+
+	// 他人的签名
+	if _, err := r.Confirm(report(t, 7, false, newDH(t))); !errors.Is(err, repute.ErrReport) {
+		t.Errorf("forged report: %v", err)
+	}
+	// 篡改的结果
+	b, _, sig := report(t, 7, true, dh)
+
+	if _, err := r.Confirm(b, false, sig); !errors.Is(err, repute.ErrReport) {
+		t.Errorf("altered report: %v", err)
+	}
+	if r.Score(peer) != 0 {
+		t.Fatal("rejected report scored")
+	}
+	to, err := r.Confirm(b, true, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to != peer {
+		t.Errorf("forward to %s", to)
+	}
+	if r.Score(peer) <= 0 {
+		t.Errorf("score: %f", r.Score(peer))
+	}
+	if _, err = r.Confirm(b, true, sig); !errors.Is(err, repute.ErrUnknown) {
+		t.Errorf("repeated report: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+// Package trust 公认心跳签名者登记。
+// 探测包的签名可用于识别公认的心跳节点，
+// 这些节点约定从零跳数开始探测，其紧缺性信号更为可信。
+// 未签名或签名者未知的探测包，其信号会被打折或忽略，
+// 以防范攻击者以高初始跳数激发过度冗余。
+package trust
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+)
+
+// 签名者清单更新消息的上下文前缀
+const updateContext = "depots:signers"
+
+var (
+	// ErrMaster 未配置主控公钥，不接受在线更新
+	ErrMaster = errors.New("no master key for signed updates")
+
+	// ErrUpdate 更新签名验证失败
+	ErrUpdate = errors.New("signers update verification failed")
+
+	// ErrStale 更新的序号不高于当前序号
+	ErrStale = errors.New("signers update is stale")
+)
+
+// Signer 公认心跳签名者。
+type Signer struct {
+	Name    string      // 名称（备注）
+	Algor   msg.SignTag // 签名算法
+	Pubkey  []byte      // 签名公钥
+	Weight  float64     // 信号权重
+	Expires time.Time   // 过期时间，零值表示不过期
+}
+
+// Expired 是否已过期。
+func (s *Signer) Expired(now time.Time) bool {
+	return !s.Expires.IsZero() && now.After(s.Expires)
+}
+
+// Registry 签名者登记簿。
+type Registry struct {
+	mu      sync.RWMutex
+	master  ed25519.PublicKey  // 主控公钥（验证在线更新）
+	seq     uint64             // 当前更新序号
+	signers map[string]*Signer // 以算法+公钥为键
+	save    bool               // 在线更新是否写回用户配置
+}
+
+// New 创建一个签名者登记簿。
+// @master 主控公钥，可选。为nil时不接受在线更新
+func New(master ed25519.PublicKey) *Registry {
+	return &Registry{
+		master:  master,
+		signers: make(map[string]*Signer),
+	}
+}
+
+// Load 从用户配置创建签名者登记簿。
+// 配置文件 ~/.depots/signers.json，格式错误的条目会被忽略。
+// 配置中的更新序号一并载入，在线更新成功后连同清单写回，
+// 因此重启后旧的更新不会被再次接受（回滚签名者的撤销）。
+func Load() (*Registry, error) {
+	conf, err := config.Signers()
+	if err != nil {
+		return nil, err
+	}
+	var master ed25519.PublicKey
+
+	if conf.Master != "" {
+		buf, err := hex.DecodeString(conf.Master)
+		if err != nil || len(buf) != ed25519.PublicKeySize {
+			return nil, ErrMaster
+		}
+		master = buf
+	}
+	r := New(master)
+	r.seq = conf.Seq
+	r.save = true
+
+	for _, s := range fromConfig(conf.Signers) {
+		r.Add(s)
+	}
+	return r, nil
+}
+
+// Add 添加一个签名者。
+// 零权重视为1，同一算法和公钥的签名者会被覆盖。
+func (r *Registry) Add(s *Signer) {
+	if s.Weight <= 0 {
+		s.Weight = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.signers[signerKey(s.Algor, s.Pubkey)] = s
+}
+
+// Lookup 查找签名者。
+// 算法和公钥都须匹配，未知或已过期的签名者返回nil。
+// @algor 签名算法
+// @pub   签名公钥
+func (r *Registry) Lookup(algor msg.SignTag, pub []byte) *Signer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := r.signers[signerKey(algor, pub)]
+	if s == nil || s.Expired(time.Now()) {
+		return nil
+	}
+	return s
+}
+
+// Rate 评估探测包的紧缺性信号权重。
+// - 公认签名者的新版本探测包（带时间戳）：取其配置的权重。
+// - 未签名、签名者未知，或旧版本的签名（无时间戳，可被无限重放）：
+//   - 直接来自客户端（可知初始跳数），非零起跳的视为违约，返回0（忽略）。
+//   - 经由驿站转播：按未签名权重打折。
+//
+// 注：签名的有效性应当已经在解码时验证（packet.DecodeProbe），
+// 时间戳应当已经通过重放检查（relay.Replay）。
+// @algor  签名算法
+// @pub    探测包的签名公钥，未签名为nil
+// @st     签名时间戳，旧版本或未签名为nil
+// @hops   到达时的跳数
+// @direct 是否直接来自客户端
+// @return 信号权重，0表示忽略
+func (r *Registry) Rate(algor msg.SignTag, pub []byte, st *packet.Stamp, hops int, direct bool) float64 {
+	if len(pub) > 0 && st != nil {
+		if s := r.Lookup(algor, pub); s != nil {
+			return s.Weight
+		}
+	}
+	if direct && hops > 0 {
+		return 0
+	}
+	return config.ProbeUnsignedWeight
+}
+
+// Len 返回有效签名者数量。
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	now := time.Now()
+
+	for _, s := range r.signers {
+		if !s.Expired(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Clean 移除已过期的签名者。
+func (r *Registry) Clean() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	for k, s := range r.signers {
+		if s.Expired(now) {
+			delete(r.signers, k)
+		}
+	}
+}
+
+//
+// 在线更新
+// 签名者清单可由主控密钥签名后发布，节点验证后整体替换当前清单。
+//////////////////////////////////////////////////////////////////////////////
+
+// Update 签名的清单更新。
+// 签名针对载荷的原始字节，其它实现无需复现本地的JSON编码即可签名。
+type Update struct {
+	Payload []byte `json:"payload"` // 载荷（Body 的JSON编码）
+	Signd   []byte `json:"signd"`   // 主控密钥的签名
+}
+
+// Body 清单更新的载荷。
+type Body struct {
+	Seq     uint64           `json:"seq"`     // 更新序号，递增
+	Signers []*config.Signer `json:"signers"` // 新的签名者清单
+}
+
+// UpdateMessage 构建更新的签名消息。
+// 串联：上下文前缀 + 载荷原始字节。
+// @payload 载荷
+func UpdateMessage(payload []byte) []byte {
+	return append([]byte(updateContext), payload...)
+}
+
+// NewUpdate 创建一个签名的清单更新。
+// 由持有主控私钥的管理者使用。
+// @seq     更新序号
+// @signers 签名者清单
+// @master  主控私钥
+func NewUpdate(seq uint64, signers []*config.Signer, master ed25519.PrivateKey) ([]byte, error) {
+	payload, err := json.Marshal(&Body{Seq: seq, Signers: signers})
+	if err != nil {
+		return nil, err
+	}
+	up := &Update{
+		Payload: payload,
+		Signd:   ed25519.Sign(master, UpdateMessage(payload)),
+	}
+	return json.Marshal(up)
+}
+
+// Apply 应用一个签名的清单更新。
+// 验证主控签名，且序号必须高于当前序号，然后整体替换签名者清单。
+// 由用户配置载入（Load）的登记簿，新的序号和清单先写回配置文件，写入失败时不应用。
+// @data 更新数据（JSON）
+func (r *Registry) Apply(data []byte) error {
+	if r.master == nil {
+		return ErrMaster
+	}
+	up := &Update{}
+
+	if err := json.Unmarshal(data, up); err != nil {
+		return err
+	}
+	if !ed25519.Verify(r.master, UpdateMessage(up.Payload), up.Signd) {
+		return ErrUpdate
+	}
+	body := &Body{}
+
+	if err := json.Unmarshal(up.Payload, body); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if body.Seq <= r.seq {
+		return ErrStale
+	}
+	if r.save {
+		conf := &config.SignerList{
+			Master:  hex.EncodeToString(r.master),
+			Seq:     body.Seq,
+			Signers: body.Signers,
+		}
+		if err := config.SaveSigners(conf); err != nil {
+			return err
+		}
+	}
+	pool := make(map[string]*Signer)
+
+	for _, s := range fromConfig(body.Signers) {
+		if s.Weight <= 0 {
+			s.Weight = 1
+		}
+		pool[signerKey(s.Algor, s.Pubkey)] = s
+	}
+	r.seq, r.signers = body.Seq, pool
+
+	return nil
+}
+
+// Seq 返回当前更新序号。
+func (r *Registry) Seq() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.seq
+}
+
+// 签名者索引键。
+// 同一公钥在不同算法下视为不同的签名者。
+func signerKey(algor msg.SignTag, pub []byte) string {
+	return string(append([]byte{byte(algor)}, pub...))
+}
+
+// 转换配置条目。
+// 公钥格式错误或长度与算法不符的条目被忽略。
+func fromConfig(list []*config.Signer) []*Signer {
+	var buf []*Signer
+
+	for _, c := range list {
+		pub, err := hex.DecodeString(c.Pubkey)
+		if err != nil || len(pub) == 0 || len(pub) != msg.SignTag(c.Algor).PublicSize() {
+			continue
+		}
+		buf = append(buf, &Signer{
+			Name:    c.Name,
+			Algor:   msg.SignTag(c.Algor),
+			Pubkey:  pub,
+			Weight:  c.Weight,
+			Expires: c.Expires,
+		})
+	}
+	return buf
+}
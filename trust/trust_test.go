@@ -0,0 +1,180 @@
+package trust_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cxio/depots/config"
+	"github.com/cxio/depots/crypto/msg"
+	"github.com/cxio/depots/packet"
+	"github.com/cxio/depots/trust"
+)
+
+func TestRate(t *testing.T) {
+	pub := bytes.Repeat([]byte{7}, 32)
+	r := trust.New(nil)
+	r.Add(&trust.Signer{Name: "heart", Algor: msg.SIGN_ED25519, Pubkey: pub, Weight: 2})
+
+	st, err := packet.NewStamp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		algor  msg.SignTag
+		pub    []byte
+		st     *packet.Stamp
+		hops   int
+		direct bool
+		want   float64
+	}{
+		{"trusted", msg.SIGN_ED25519, pub, st, 3, true, 2},
+		{"legacy relayed", msg.SIGN_ED25519, pub, nil, 3, false, config.ProbeUnsignedWeight},
+		{"legacy direct", msg.SIGN_ED25519, pub, nil, 3, true, 0},
+		{"other algor", msg.SIGN_Tradi, pub, st, 0, true, config.ProbeUnsignedWeight},
+		{"unknown", msg.SIGN_ED25519, bytes.Repeat([]byte{8}, 32), st, 0, false, config.ProbeUnsignedWeight},
+		{"unsigned direct", -1, nil, nil, 1, true, 0},
+	}
+	for _, c := range cases {
+		if got := r.Rate(c.algor, c.pub, c.st, c.hops, c.direct); got != c.want {
+			t.Errorf("%s: rate %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLookupExpired(t *testing.T) {
+	pub := bytes.Repeat([]byte{7}, 32)
+	r := trust.New(nil)
+	r.Add(&trust.Signer{Algor: msg.SIGN_ED25519, Pubkey: pub, Expires: time.Now().Add(-time.Second)})
+
+	if r.Lookup(msg.SIGN_ED25519, pub) != nil {
+		t.Error("expired signer found")
+	}
+	if r.Len() != 0 {
+		t.Errorf("len: %d", r.Len())
+	}
+}
+
+func TestSignersMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	list, err := config.Signers()
+	if err != nil || list == nil || len(list.Signers) != 0 {
+		t.Errorf("missing signers.json: %v, %v", list, err)
+	}
+}
+
+// 创建签名者清单（单个ed25519签名者）。
+func testSigners(t *testing.T) ([]*config.Signer, []byte) {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*config.Signer{{Name: "heart", Algor: int(msg.SIGN_ED25519), Pubkey: hex.EncodeToString(pub), Weight: 3}}, pub
+}
+
+func TestApply(t *testing.T) {
+	mpub, mpriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := trust.New(mpub)
+	old := bytes.Repeat([]byte{7}, 32)
+	r.Add(&trust.Signer{Algor: msg.SIGN_ED25519, Pubkey: old})
+
+	list, pub := testSigners(t)
+	up, err := trust.NewUpdate(5, list, mpriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Apply(up); err != nil {
+		t.Fatal(err)
+	}
+	// 整体替换
+	if s := r.Lookup(msg.SIGN_ED25519, pub); s == nil || s.Weight != 3 || r.Lookup(msg.SIGN_ED25519, old) != nil || r.Seq() != 5 {
+		t.Fatal("update not applied")
+	}
+	// 重放或更旧的序号
+	if err = r.Apply(up); !errors.Is(err, trust.ErrStale) {
+		t.Errorf("replayed update: %v", err)
+	}
+	older, _ := trust.NewUpdate(4, nil, mpriv)
+	if err = r.Apply(older); !errors.Is(err, trust.ErrStale) {
+		t.Errorf("older update: %v", err)
+	}
+	// 其它密钥的签名，或篡改的载荷
+	_, other, _ := ed25519.GenerateKey(nil)
+	forged, _ := trust.NewUpdate(6, nil, other)
+	if err = r.Apply(forged); !errors.Is(err, trust.ErrUpdate) {
+		t.Errorf("foreign update: %v", err)
+	}
+	u := &trust.Update{}
+	if err = json.Unmarshal(up, u); err != nil {
+		t.Fatal(err)
+	}
+	u.Payload = bytes.Replace(u.Payload, []byte(`"seq":5`), []byte(`"seq":9`), 1)
+	tampered, _ := json.Marshal(u)
+
+	if err = r.Apply(tampered); !errors.Is(err, trust.ErrUpdate) {
+		t.Errorf("tampered update: %v", err)
+	}
+	if r.Seq() != 5 || r.Lookup(msg.SIGN_ED25519, pub) == nil {
+		t.Error("rejected update changed the registry")
+	}
+	// 无主控公钥
+	if err = trust.New(nil).Apply(up); !errors.Is(err, trust.ErrMaster) {
+		t.Errorf("no master: %v", err)
+	}
+}
+
+func TestApplyPersist(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	mpub, mpriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(home, ".depots"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = config.SaveSigners(&config.SignerList{Master: hex.EncodeToString(mpub)}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := trust.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, pub := testSigners(t)
+	up, err := trust.NewUpdate(7, list, mpriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Apply(up); err != nil {
+		t.Fatal(err)
+	}
+	// 重启后序号和清单保留，旧的更新不再被接受
+	r, err = trust.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Seq() != 7 || r.Lookup(msg.SIGN_ED25519, pub) == nil {
+		t.Fatalf("reloaded: seq %d", r.Seq())
+	}
+	if err = r.Apply(up); !errors.Is(err, trust.ErrStale) {
+		t.Errorf("replayed after reload: %v", err)
+	}
+	prev, _ := trust.NewUpdate(6, nil, mpriv)
+	if err = r.Apply(prev); !errors.Is(err, trust.ErrStale) {
+		t.Errorf("rollback after reload: %v", err)
+	}
+}